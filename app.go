@@ -1,20 +1,55 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/aldoramirezmartinez/fiber-api/audit"
 	"github.com/aldoramirezmartinez/fiber-api/config"
 	"github.com/aldoramirezmartinez/fiber-api/controllers"
+	"github.com/aldoramirezmartinez/fiber-api/docs"
+	"github.com/aldoramirezmartinez/fiber-api/events"
+	"github.com/aldoramirezmartinez/fiber-api/jobs"
+	"github.com/aldoramirezmartinez/fiber-api/middlewares"
+	"github.com/aldoramirezmartinez/fiber-api/prices"
+	"github.com/aldoramirezmartinez/fiber-api/queue"
 	"github.com/aldoramirezmartinez/fiber-api/routes"
+	"github.com/aldoramirezmartinez/fiber-api/webhooks"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type App struct {
-	fiberApp             *fiber.App
-	UserController       *controllers.UserController
-	ProviderController   *controllers.ProviderController
-	ItemController       *controllers.ItemController
-	PurchaseV2Controller *controllers.PurchaseV2Controller
+	fiberApp                   *fiber.App
+	db                         *mongo.Database
+	UserController             *controllers.UserController
+	ProviderController         *controllers.ProviderController
+	ItemController             *controllers.ItemController
+	PurchaseV2Controller       *controllers.PurchaseV2Controller
+	DocsController             *controllers.DocsController
+	IntegrityController        *controllers.IntegrityController
+	AuditController            *controllers.AuditController
+	WebhookController          *controllers.WebhookController
+	LiveController             *controllers.LiveController
+	JobController              *controllers.JobController
+	ExchangeRateController     *controllers.ExchangeRateController
+	ReportController           *controllers.ReportController
+	TaxRuleController          *controllers.TaxRuleController
+	ProviderPriceController    *controllers.ProviderPriceController
+	RFQController              *controllers.RFQController
+	RequisitionController      *controllers.RequisitionController
+	PurchaseRevisionController *controllers.PurchaseRevisionController
+	// EventBus receives every domain event relayed from the outbox;
+	// subscribe to it to react to changes in-process.
+	EventBus *events.Bus
+	// Worker runs queued background jobs; register job handlers with
+	// queue.Register before calling Run.
+	Worker *queue.Worker
 }
 
 func NewApp() *App {
@@ -28,36 +63,166 @@ func NewApp() *App {
 	itemController := controllers.NewItemController(db)
 
 	purchasev2Controller := controllers.NewPurchaseV2Controller(db)
+	docsController := controllers.NewDocsController()
+	integrityController := controllers.NewIntegrityController(db)
+	auditController := controllers.NewAuditController(db)
+	webhookController := controllers.NewWebhookController(db)
+	liveController := controllers.NewLiveController(db, purchasev2Controller)
+	jobController := controllers.NewJobController(db)
+	exchangeRateController := controllers.NewExchangeRateController(db)
+	reportController := controllers.NewReportController(db)
+	taxRuleController := controllers.NewTaxRuleController(db)
+	providerPriceController := controllers.NewProviderPriceController(db)
+	rfqController := controllers.NewRFQController(db, purchasev2Controller)
+	requisitionController := controllers.NewRequisitionController(db, purchasev2Controller)
+	purchaseRevisionController := controllers.NewPurchaseRevisionController(db)
+
+	worker := queue.NewWorker(db)
+	queue.Register(worker, prices.ApplyJobType, queue.HandlerOptions{MaxAttempts: 10, Timeout: time.Minute},
+		func(ctx context.Context, job prices.ApplyJob) error {
+			return prices.Apply(ctx, db, job.PriceID)
+		})
 
 	fiberApp := fiber.New()
+	fiberApp.Use(requestid.New())
+	fiberApp.Use(middlewares.NewTracing())
+	// The actor is resolved first because idempotency keys are per caller.
+	fiberApp.Use(middlewares.NewActor(db))
+	fiberApp.Use(middlewares.NewIdempotency(db))
+	fiberApp.Use(middlewares.NewIncludeDeletedGuard())
 
 	return &App{
-		fiberApp:             fiberApp,
-		UserController:       userController,
-		ProviderController:   providerController,
-		ItemController:       itemController,
-		PurchaseV2Controller: purchasev2Controller,
+		fiberApp:                   fiberApp,
+		db:                         db,
+		UserController:             userController,
+		ProviderController:         providerController,
+		ItemController:             itemController,
+		PurchaseV2Controller:       purchasev2Controller,
+		DocsController:             docsController,
+		IntegrityController:        integrityController,
+		AuditController:            auditController,
+		WebhookController:          webhookController,
+		LiveController:             liveController,
+		JobController:              jobController,
+		ExchangeRateController:     exchangeRateController,
+		ReportController:           reportController,
+		TaxRuleController:          taxRuleController,
+		ProviderPriceController:    providerPriceController,
+		RFQController:              rfqController,
+		RequisitionController:      requisitionController,
+		PurchaseRevisionController: purchaseRevisionController,
+		EventBus:                   events.NewBus(),
+		Worker:                     worker,
 	}
 }
 
 func (app *App) Run() {
-	userRoutes := routes.NewUserRoutes(app.fiberApp, app.UserController)
-	userRoutes.SetupRoutes()
+	routes.Setup(app.fiberApp, routes.Controllers{
+		User:             app.UserController,
+		Provider:         app.ProviderController,
+		Item:             app.ItemController,
+		PurchaseV2:       app.PurchaseV2Controller,
+		Docs:             app.DocsController,
+		Integrity:        app.IntegrityController,
+		Audit:            app.AuditController,
+		Webhook:          app.WebhookController,
+		Live:             app.LiveController,
+		Job:              app.JobController,
+		ExchangeRate:     app.ExchangeRateController,
+		Report:           app.ReportController,
+		TaxRule:          app.TaxRuleController,
+		ProviderPrice:    app.ProviderPriceController,
+		RFQ:              app.RFQController,
+		Requisition:      app.RequisitionController,
+		PurchaseRevision: app.PurchaseRevisionController,
+	})
+
+	for _, route := range docs.MissingRoutes(app.fiberApp.GetRoutes(true)) {
+		fmt.Println("Route missing from OpenAPI document:", route)
+	}
 
-	providerRoutes := routes.NewProviderRoutes(app.fiberApp, app.ProviderController)
-	providerRoutes.SetupRoutes()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Periodic work runs as recurring jobs so that only one instance at a
+	// time does it.
+	if retention := config.GetPurgeRetention(); retention > 0 {
+		queue.Schedule(app.Worker, jobs.PurgeJobType, config.GetPurgeInterval(), queue.HandlerOptions{},
+			func(ctx context.Context) error {
+				return jobs.Purge(ctx, app.db, retention)
+			})
+	}
 
-	itemRoutes := routes.NewItemRoutes(app.fiberApp, app.ItemController)
-	itemRoutes.SetupRoutes()
+	app.scheduleOutboxRelay(ctx)
+
+	dispatcher := webhooks.NewDispatcher(app.db, config.GetWebhookTimeout())
+	queue.Schedule(app.Worker, jobs.WebhookDeliveryJobType, config.GetWebhookPollInterval(), queue.HandlerOptions{},
+		func(ctx context.Context) error {
+			return jobs.DeliverWebhooks(ctx, dispatcher)
+		})
+
+	signingKey, err := audit.SigningKey()
+	if err != nil {
+		fmt.Println("Audit checkpoints disabled:", err)
+	} else if signingKey != nil {
+		queue.Schedule(app.Worker, jobs.AuditCheckpointJobType, config.GetAuditCheckpointInterval(), queue.HandlerOptions{},
+			func(ctx context.Context) error {
+				return jobs.AuditCheckpoint(ctx, app.db, signingKey)
+			})
+	}
 
-	purchasev2Routes := routes.NewPurchaseV2Routes(app.fiberApp, app.PurchaseV2Controller)
-	purchasev2Routes.SetupRoutes()
+	app.Worker.Start(ctx, config.GetJobPollInterval())
+
+	// Stop accepting requests on SIGINT/SIGTERM so main can flush traces.
+	go func() {
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+		<-quit
+		cancel()
+		app.LiveController.Shutdown()
+		fmt.Println("Shutting down server")
+		if err := app.fiberApp.Shutdown(); err != nil {
+			fmt.Println("Failed to shut down server:", err)
+		}
+	}()
 
 	port := config.GetPort()
 	fmt.Println("Server listening on port:", port)
 
-	err := app.fiberApp.Listen(":" + port)
+	err = app.fiberApp.Listen(":" + port)
 	if err != nil {
 		fmt.Println("Failed to start server:", err)
 	}
+
+	// Let running jobs finish or go back to the queue before exiting.
+	cancel()
+	app.Worker.Wait()
+}
+
+// scheduleOutboxRelay relays domain events to the event bus, to webhooks
+// and, when NATS_URL is set, to NATS.
+func (app *App) scheduleOutboxRelay(ctx context.Context) {
+	if err := events.EnsureIndexes(ctx, app.db, config.GetOutboxRetention()); err != nil {
+		fmt.Println("Failed to create outbox indexes:", err)
+	}
+
+	sinks := []events.Sink{app.EventBus, events.NewWebhookSink(app.db)}
+	if url := config.GetNATSURL(); url != "" {
+		natsSink, err := events.NewNATSSink(url, config.GetNATSSubjectPrefix())
+		if err != nil {
+			fmt.Println("Failed to connect to NATS, events will not be published there:", err)
+		} else {
+			sinks = append(sinks, natsSink)
+			go func() {
+				<-ctx.Done()
+				natsSink.Close()
+			}()
+		}
+	}
+
+	relay := events.NewRelay(app.db, sinks...)
+	queue.Schedule(app.Worker, jobs.OutboxRelayJobType, config.GetOutboxPollInterval(), queue.HandlerOptions{},
+		func(ctx context.Context) error {
+			return jobs.RelayOutbox(ctx, relay)
+		})
 }
@@ -0,0 +1,158 @@
+package audit
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/aldoramirezmartinez/fiber-api/models"
+	"github.com/aldoramirezmartinez/fiber-api/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	CollectionName = "audit_log"
+	redacted       = "[redacted]"
+)
+
+// ignoredFields change on every write or are internal, so diffs skip them.
+var ignoredFields = map[string]bool{"_id": true, "version": true, "lock": true}
+
+// redactedFields are recorded as changed without their values.
+var redactedFields = map[string]bool{"password": true, "secret": true}
+
+// Record appends an audit entry to the hash chain for the document with the given ID in
+// collection. before is the document as read before the change, or nil for
+// creates. The new state is read back with ctx, so call Record after the
+// write and, inside UnitOfWork.Do, with the transaction context. Updates that
+// changed nothing are not recorded. c is nil for changes made by background
+// jobs, which have no actor.
+func Record(ctx context.Context, c *fiber.Ctx, db *mongo.Database, action string, collection string, id primitive.ObjectID, before interface{}) error {
+	var beforeDoc bson.Raw
+	if before != nil {
+		data, err := bson.Marshal(before)
+		if err != nil {
+			return err
+		}
+		beforeDoc = data
+	}
+
+	afterDoc, err := db.Collection(collection).FindOne(ctx, bson.M{"_id": id}).DecodeBytes()
+	if err != nil && err != mongo.ErrNoDocuments {
+		return err
+	}
+
+	changes := Diff(beforeDoc, afterDoc)
+	if len(changes) == 0 && action == models.AuditUpdate {
+		return nil
+	}
+
+	return appendEntry(ctx, db, models.AuditEntry{
+		Entity:    collection,
+		EntityID:  id,
+		Action:    action,
+		Actor:     utils.ActorID(c),
+		RequestID: utils.RequestID(c),
+		Timestamp: time.Now(),
+		Changes:   changes,
+	})
+}
+
+// Diff lists the leaf fields that differ between two BSON documents, sorted
+// by path. Either document may be nil.
+func Diff(before bson.Raw, after bson.Raw) []models.FieldChange {
+	beforeFields := map[string]bson.RawValue{}
+	afterFields := map[string]bson.RawValue{}
+	flatten("", before, beforeFields)
+	flatten("", after, afterFields)
+
+	changes := []models.FieldChange{}
+	for field, value := range afterFields {
+		previous, ok := beforeFields[field]
+		if ok && previous.Equal(value) {
+			continue
+		}
+		change := models.FieldChange{Field: field, After: leafValue(field, value)}
+		if ok {
+			change.Before = leafValue(field, previous)
+		}
+		changes = append(changes, change)
+	}
+	for field, value := range beforeFields {
+		if _, ok := afterFields[field]; !ok {
+			changes = append(changes, models.FieldChange{Field: field, Before: leafValue(field, value)})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+// EnsureIndexes creates the indexes used to query the audit log, the one
+// that keeps the chain from forking and the one that keeps a sequence from
+// being signed twice.
+func EnsureIndexes(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection(CollectionName).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "sequence", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(chained),
+		},
+		{Keys: bson.D{{Key: "entity", Value: 1}, {Key: "entity_id", Value: 1}, {Key: "timestamp", Value: -1}}},
+		{Keys: bson.D{{Key: "actor", Value: 1}, {Key: "timestamp", Value: -1}}},
+		{Keys: bson.D{{Key: "timestamp", Value: -1}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Collection(CheckpointCollectionName).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "sequence", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+func flatten(prefix string, document bson.Raw, fields map[string]bson.RawValue) {
+	elements, err := document.Elements()
+	if err != nil {
+		return
+	}
+
+	for _, element := range elements {
+		key := element.Key()
+		if prefix == "" && ignoredFields[key] {
+			continue
+		}
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+
+		value := element.Value()
+		switch value.Type {
+		case bsontype.EmbeddedDocument:
+			flatten(path, value.Document(), fields)
+		case bsontype.Array:
+			flatten(path, bson.Raw(value.Array()), fields)
+		default:
+			fields[path] = value
+		}
+	}
+}
+
+func leafValue(field string, value bson.RawValue) interface{} {
+	if redactedFields[field] {
+		return redacted
+	}
+
+	var decoded interface{}
+	if err := value.Unmarshal(&decoded); err != nil {
+		return value.String()
+	}
+	return decoded
+}
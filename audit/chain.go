@@ -0,0 +1,144 @@
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/aldoramirezmartinez/fiber-api/models"
+	"github.com/aldoramirezmartinez/fiber-api/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+// countersCollectionName holds the counter that hands out audit sequence
+// numbers, in the document with _id CollectionName.
+const countersCollectionName = "counters"
+
+// chained matches entries that are part of the hash chain. Entries written
+// before the chain was introduced have no sequence.
+var chained = bson.M{"sequence": bson.M{"$gt": 0}}
+
+// appendEntry links entry to the end of the chain and stores it. Inside a
+// transaction the sequence is taken from the audit counter with $inc, which
+// holds the end of the chain until the transaction commits, so concurrent
+// appends are ordered by the counter instead of racing for the same
+// sequence. Without a transaction, on standalone servers, a concurrent
+// append taking the same sequence is rejected by the unique index and the
+// append is retried.
+func appendEntry(ctx context.Context, db *mongo.Database, entry models.AuditEntry) error {
+	collection := db.Collection(CollectionName)
+	if mongo.SessionFromContext(ctx) == nil {
+		return appendWithoutTransaction(ctx, collection, entry)
+	}
+
+	sequence, err := nextSequence(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	head, err := lastEntry(ctx, collection)
+	if err != nil {
+		return err
+	}
+	if head.Sequence != sequence-1 {
+		// The counter is new or missed entries appended without a
+		// transaction; continue from the end of the chain.
+		sequence = head.Sequence + 1
+		_, err := db.Collection(countersCollectionName).UpdateOne(ctx, bson.M{"_id": CollectionName}, bson.M{"$set": bson.M{"sequence": sequence}})
+		if err != nil {
+			return err
+		}
+	}
+
+	entry.Sequence = sequence
+	entry.PrevHash = head.Hash
+	entry.Hash, err = hashEntry(entry)
+	if err != nil {
+		return err
+	}
+
+	_, err = collection.InsertOne(ctx, entry)
+	if mongo.IsDuplicateKeyError(err) {
+		return utils.ErrRetryTransaction
+	}
+	return err
+}
+
+// nextSequence increments the audit counter and returns its new value.
+func nextSequence(ctx context.Context, db *mongo.Database) (int64, error) {
+	var counter struct {
+		Sequence int64 `bson:"sequence"`
+	}
+	err := db.Collection(countersCollectionName).FindOneAndUpdate(ctx,
+		bson.M{"_id": CollectionName},
+		bson.M{"$inc": bson.M{"sequence": 1}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&counter)
+	return counter.Sequence, err
+}
+
+func appendWithoutTransaction(ctx context.Context, collection *mongo.Collection, entry models.AuditEntry) error {
+	for {
+		head, err := lastEntry(ctx, collection)
+		if err != nil {
+			return err
+		}
+
+		entry.Sequence = head.Sequence + 1
+		entry.PrevHash = head.Hash
+		entry.Hash, err = hashEntry(entry)
+		if err != nil {
+			return err
+		}
+
+		_, err = collection.InsertOne(ctx, entry)
+		if err == nil {
+			return nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return err
+		}
+	}
+}
+
+func lastEntry(ctx context.Context, collection *mongo.Collection) (models.AuditEntry, error) {
+	var head models.AuditEntry
+	err := collection.FindOne(ctx, chained, options.FindOne().SetSort(bson.M{"sequence": -1})).Decode(&head)
+	if err == mongo.ErrNoDocuments {
+		return head, nil
+	}
+	return head, err
+}
+
+func hashEntry(entry models.AuditEntry) (string, error) {
+	entry.Hash = ""
+	data, err := bson.Marshal(entry)
+	if err != nil {
+		return "", err
+	}
+	return hashDocument(data)
+}
+
+// hashDocument hashes the BSON of an entry as stored, leaving out _id and
+// hash, so that stored entries can be checked without decoding them.
+func hashDocument(document bson.Raw) (string, error) {
+	elements, err := document.Elements()
+	if err != nil {
+		return "", err
+	}
+
+	var kept [][]byte
+	for _, element := range elements {
+		switch element.Key() {
+		case "_id", "hash":
+			continue
+		}
+		kept = append(kept, element)
+	}
+
+	sum := sha256.Sum256(bsoncore.BuildDocumentFromElements(nil, kept...))
+	return hex.EncodeToString(sum[:]), nil
+}
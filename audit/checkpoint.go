@@ -0,0 +1,90 @@
+package audit
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aldoramirezmartinez/fiber-api/config"
+	"github.com/aldoramirezmartinez/fiber-api/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const CheckpointCollectionName = "audit_checkpoints"
+
+// SigningKey returns the checkpoint signing key from AUDIT_SIGNING_KEY, or
+// nil when it is not set.
+func SigningKey() (ed25519.PrivateKey, error) {
+	encoded := config.GetAuditSigningKey()
+	if encoded == "" {
+		return nil, nil
+	}
+
+	seed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid AUDIT_SIGNING_KEY: %w", err)
+	}
+	if len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("invalid AUDIT_SIGNING_KEY: want %d bytes, got %d", ed25519.SeedSize, len(seed))
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// Checkpoint signs the current head of the audit chain. It returns nil when
+// the chain is empty or the head was already signed, including by another
+// instance at the same time.
+func Checkpoint(ctx context.Context, db *mongo.Database, key ed25519.PrivateKey) (*models.AuditCheckpoint, error) {
+	if key == nil {
+		return nil, errors.New("audit signing key is not configured")
+	}
+
+	head, err := lastEntry(ctx, db.Collection(CollectionName))
+	if err != nil || head.Sequence == 0 {
+		return nil, err
+	}
+
+	checkpoints := db.Collection(CheckpointCollectionName)
+	var latest models.AuditCheckpoint
+	err = checkpoints.FindOne(ctx, bson.M{}, options.FindOne().SetSort(bson.M{"sequence": -1})).Decode(&latest)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+	if err == nil && latest.Sequence >= head.Sequence {
+		return nil, nil
+	}
+
+	checkpoint := models.AuditCheckpoint{
+		Sequence:  head.Sequence,
+		Hash:      head.Hash,
+		CreatedAt: time.Now().Truncate(time.Millisecond),
+	}
+	checkpoint.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, checkpointMessage(checkpoint)))
+
+	result, err := checkpoints.InsertOne(ctx, checkpoint)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	checkpoint.ID, _ = result.InsertedID.(primitive.ObjectID)
+	return &checkpoint, nil
+}
+
+func verifyCheckpoint(checkpoint models.AuditCheckpoint, key ed25519.PublicKey) bool {
+	signature, err := base64.StdEncoding.DecodeString(checkpoint.Signature)
+	if err != nil {
+		return false
+	}
+	return ed25519.Verify(key, checkpointMessage(checkpoint), signature)
+}
+
+func checkpointMessage(checkpoint models.AuditCheckpoint) []byte {
+	return []byte(fmt.Sprintf("%d:%s:%d", checkpoint.Sequence, checkpoint.Hash, checkpoint.CreatedAt.UnixMilli()))
+}
@@ -0,0 +1,148 @@
+package audit
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"time"
+
+	"github.com/aldoramirezmartinez/fiber-api/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type Verification struct {
+	VerifiedAt time.Time `json:"verified_at"`
+	Entries    int64     `json:"entries"`
+	LastHash   string    `json:"last_hash,omitempty"`
+	// Unchained counts entries written before the chain was introduced.
+	Unchained   int64 `json:"unchained"`
+	Checkpoints int   `json:"checkpoints"`
+	// SignaturesChecked is false when AUDIT_SIGNING_KEY is not set, in which
+	// case checkpoints are only compared against the chain.
+	SignaturesChecked bool        `json:"signatures_checked"`
+	BrokenLink        *BrokenLink `json:"broken_link"`
+}
+
+// BrokenLink is the first place where the chain does not verify.
+type BrokenLink struct {
+	Sequence int64              `json:"sequence"`
+	EntryID  primitive.ObjectID `json:"entry_id,omitempty"`
+	Reason   string             `json:"reason"`
+}
+
+func (v Verification) Valid() bool {
+	return v.BrokenLink == nil
+}
+
+// Verify walks the audit chain from the first entry, recomputing every hash
+// and comparing the chain with the signed checkpoints, and reports the first
+// broken link.
+func Verify(ctx context.Context, db *mongo.Database) (Verification, error) {
+	verification := Verification{VerifiedAt: time.Now()}
+
+	key, err := SigningKey()
+	if err != nil {
+		return verification, err
+	}
+	var publicKey ed25519.PublicKey
+	if key != nil {
+		publicKey = key.Public().(ed25519.PublicKey)
+		verification.SignaturesChecked = true
+	}
+
+	var checkpoints []models.AuditCheckpoint
+	cursor, err := db.Collection(CheckpointCollectionName).Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"sequence": 1}))
+	if err != nil {
+		return verification, err
+	}
+	if err := cursor.All(ctx, &checkpoints); err != nil {
+		return verification, err
+	}
+	verification.Checkpoints = len(checkpoints)
+
+	for _, checkpoint := range checkpoints {
+		if publicKey != nil && !verifyCheckpoint(checkpoint, publicKey) {
+			verification.BrokenLink = &BrokenLink{
+				Sequence: checkpoint.Sequence,
+				Reason:   "checkpoint signature is invalid",
+			}
+			return verification, nil
+		}
+	}
+
+	collection := db.Collection(CollectionName)
+	verification.Unchained, err = collection.CountDocuments(ctx, bson.M{"sequence": bson.M{"$not": bson.M{"$gt": 0}}})
+	if err != nil {
+		return verification, err
+	}
+
+	cursor, err = collection.Find(ctx, chained, options.Find().SetSort(bson.M{"sequence": 1}))
+	if err != nil {
+		return verification, err
+	}
+	defer cursor.Close(ctx)
+
+	var previousSequence int64
+	var previousHash string
+	next := 0
+	for cursor.Next(ctx) {
+		entry := cursor.Current
+		entryID, _ := entry.Lookup("_id").ObjectIDOK()
+		sequence, _ := entry.Lookup("sequence").AsInt64OK()
+		prevHash, _ := entry.Lookup("prev_hash").StringValueOK()
+		hash, _ := entry.Lookup("hash").StringValueOK()
+
+		broken := func(reason string) {
+			verification.BrokenLink = &BrokenLink{Sequence: sequence, EntryID: entryID, Reason: reason}
+		}
+
+		if sequence != previousSequence+1 {
+			verification.BrokenLink = &BrokenLink{
+				Sequence: previousSequence + 1,
+				Reason:   fmt.Sprintf("entry is missing, next entry has sequence %d", sequence),
+			}
+			return verification, nil
+		}
+		if prevHash != previousHash {
+			broken("prev_hash does not match the previous entry")
+			return verification, nil
+		}
+
+		recomputed, err := hashDocument(entry)
+		if err != nil {
+			return verification, err
+		}
+		if recomputed != hash {
+			broken("hash does not match the entry contents")
+			return verification, nil
+		}
+
+		for next < len(checkpoints) && checkpoints[next].Sequence == sequence {
+			if checkpoints[next].Hash != hash {
+				broken("entry does not match the signed checkpoint")
+				return verification, nil
+			}
+			next++
+		}
+
+		previousSequence = sequence
+		previousHash = hash
+		verification.Entries++
+		verification.LastHash = hash
+	}
+	if err := cursor.Err(); err != nil {
+		return verification, err
+	}
+
+	if next < len(checkpoints) {
+		verification.BrokenLink = &BrokenLink{
+			Sequence: previousSequence + 1,
+			Reason:   fmt.Sprintf("entries up to checkpoint %d are missing", checkpoints[next].Sequence),
+		}
+	}
+
+	return verification, nil
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/aldoramirezmartinez/fiber-api/audit"
+	"github.com/aldoramirezmartinez/fiber-api/config"
+	"github.com/aldoramirezmartinez/fiber-api/integrity"
+	"github.com/aldoramirezmartinez/fiber-api/migrations"
+	"github.com/aldoramirezmartinez/fiber-api/webhooks"
+	"github.com/gofiber/fiber/v2"
+)
+
+// runCommand runs the maintenance command named by args[0] and returns the
+// process exit code. ok is false when args do not name a command.
+func runCommand(args []string) (code int, ok bool) {
+	if len(args) == 0 {
+		return 0, false
+	}
+
+	switch args[0] {
+	case "check-integrity":
+		return checkIntegrity(args[1:]), true
+	case "migrate-money":
+		return migrateMoney(args[1:]), true
+	case "migrate-snapshots":
+		return migrateSnapshots(args[1:]), true
+	case "migrate-revisions":
+		return migrateRevisions(args[1:]), true
+	case "verify-audit":
+		return verifyAudit(args[1:]), true
+	case "webhook-receiver":
+		return webhookReceiver(args[1:]), true
+	default:
+		return 0, false
+	}
+}
+
+func checkIntegrity(args []string) int {
+	flags := flag.NewFlagSet("check-integrity", flag.ContinueOnError)
+	repair := flags.Bool("repair", false, "fix the problems found")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	db, err := config.ConnectDB()
+	if err != nil {
+		fmt.Println("Failed to connect to MongoDB:", err)
+		return 1
+	}
+	defer db.Client().Disconnect(context.Background())
+
+	report, err := integrity.Check(context.Background(), db, *repair)
+	if err != nil {
+		fmt.Println("Failed to check data integrity:", err)
+		return 1
+	}
+
+	if err := printJSON(report); err != nil {
+		fmt.Println("Failed to write report:", err)
+		return 1
+	}
+
+	// Exit non-zero when problems were found but left in place.
+	if !*repair && report.Problems() > 0 {
+		return 3
+	}
+	return 0
+}
+
+func migrateMoney(args []string) int {
+	flags := flag.NewFlagSet("migrate-money", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	db, err := config.ConnectDB()
+	if err != nil {
+		fmt.Println("Failed to connect to MongoDB:", err)
+		return 1
+	}
+	defer db.Client().Disconnect(context.Background())
+
+	migration, err := migrations.MigrateMoney(context.Background(), db, config.GetDefaultCurrency())
+	if err != nil {
+		fmt.Println("Failed to migrate amounts:", err)
+		return 1
+	}
+
+	if err := printJSON(migration); err != nil {
+		fmt.Println("Failed to write report:", err)
+		return 1
+	}
+	return 0
+}
+
+func migrateSnapshots(args []string) int {
+	flags := flag.NewFlagSet("migrate-snapshots", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	db, err := config.ConnectDB()
+	if err != nil {
+		fmt.Println("Failed to connect to MongoDB:", err)
+		return 1
+	}
+	defer db.Client().Disconnect(context.Background())
+
+	migration, err := migrations.SnapshotPurchaseItems(context.Background(), db, config.GetDefaultCurrency())
+	if err != nil {
+		fmt.Println("Failed to snapshot purchase items:", err)
+		return 1
+	}
+
+	if err := printJSON(migration); err != nil {
+		fmt.Println("Failed to write report:", err)
+		return 1
+	}
+	return 0
+}
+
+func migrateRevisions(args []string) int {
+	flags := flag.NewFlagSet("migrate-revisions", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	db, err := config.ConnectDB()
+	if err != nil {
+		fmt.Println("Failed to connect to MongoDB:", err)
+		return 1
+	}
+	defer db.Client().Disconnect(context.Background())
+
+	migration, err := migrations.NumberPurchaseRevisions(context.Background(), db)
+	if err != nil {
+		fmt.Println("Failed to number purchase revisions:", err)
+		return 1
+	}
+
+	if err := printJSON(migration); err != nil {
+		fmt.Println("Failed to write report:", err)
+		return 1
+	}
+	return 0
+}
+
+func verifyAudit(args []string) int {
+	flags := flag.NewFlagSet("verify-audit", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	db, err := config.ConnectDB()
+	if err != nil {
+		fmt.Println("Failed to connect to MongoDB:", err)
+		return 1
+	}
+	defer db.Client().Disconnect(context.Background())
+
+	verification, err := audit.Verify(context.Background(), db)
+	if err != nil {
+		fmt.Println("Failed to verify audit log:", err)
+		return 1
+	}
+
+	if err := printJSON(verification); err != nil {
+		fmt.Println("Failed to write report:", err)
+		return 1
+	}
+
+	if !verification.Valid() {
+		return 3
+	}
+	return 0
+}
+
+// webhookReceiver runs a local endpoint that prints the webhooks it receives
+// and checks their signatures, for testing subscriptions by hand.
+func webhookReceiver(args []string) int {
+	flags := flag.NewFlagSet("webhook-receiver", flag.ContinueOnError)
+	addr := flags.String("addr", ":9000", "address to listen on")
+	secret := flags.String("secret", "", "subscription secret used to check signatures")
+	fail := flags.Bool("fail", false, "answer 500 to exercise retries")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	receiver := fiber.New(fiber.Config{DisableStartupMessage: true})
+	receiver.Post("/*", func(c *fiber.Ctx) error {
+		valid := webhooks.VerifySignature(*secret, c.Get(webhooks.HeaderTimestamp), c.Body(), c.Get(webhooks.HeaderSignature))
+		fmt.Printf("%s %s delivery=%s signature_valid=%t\n%s\n",
+			c.Get(webhooks.HeaderEvent), c.Path(), c.Get(webhooks.HeaderDelivery), valid, c.Body())
+
+		switch {
+		case !valid:
+			return c.SendStatus(fiber.StatusUnauthorized)
+		case *fail:
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	fmt.Println("Webhook receiver listening on", *addr)
+	if err := receiver.Listen(*addr); err != nil {
+		fmt.Println("Failed to start webhook receiver:", err)
+		return 1
+	}
+	return 0
+}
+
+func printJSON(value interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
@@ -7,6 +7,7 @@ import (
 
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
 )
 
 var db *mongo.Database
@@ -20,7 +21,7 @@ func ConnectDB() (*mongo.Database, error) {
 
 	dbName := GetDBName()
 
-	clientOptions := options.Client().ApplyURI(mongoURI)
+	clientOptions := options.Client().ApplyURI(mongoURI).SetMonitor(otelmongo.NewMonitor())
 	client, err := mongo.Connect(context.Background(), clientOptions)
 	if err != nil {
 		fmt.Printf("Error connecting to MongoDB: %s\n", err)
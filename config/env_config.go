@@ -3,7 +3,10 @@ package config
 import (
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/aldoramirezmartinez/fiber-api/money"
 	"github.com/joho/godotenv"
 )
 
@@ -35,3 +38,169 @@ func GetMongoURI() (string, error) {
 
 	return mongoURI, nil
 }
+
+// GetIdempotencyTTL returns how long stored responses are replayed for a
+// repeated Idempotency-Key, from IDEMPOTENCY_TTL (default 24h).
+func GetIdempotencyTTL() time.Duration {
+	return getDuration("IDEMPOTENCY_TTL", 24*time.Hour)
+}
+
+// GetIdempotencyWait returns how long a duplicate request waits for the
+// original one to finish before getting 409, from IDEMPOTENCY_WAIT (default 5s).
+func GetIdempotencyWait() time.Duration {
+	return getDuration("IDEMPOTENCY_WAIT", 5*time.Second)
+}
+
+// GetPurgeRetention returns how long soft-deleted documents are kept before
+// the purge job removes them, from PURGE_RETENTION (default 720h). Zero
+// disables the purge job.
+func GetPurgeRetention() time.Duration {
+	return getDuration("PURGE_RETENTION", 30*24*time.Hour)
+}
+
+func GetPurgeInterval() time.Duration {
+	return getDuration("PURGE_INTERVAL", time.Hour)
+}
+
+// GetDeletePolicy returns DELETE_POLICY_<relation>: "restrict", "cascade" or
+// "nullify".
+func GetDeletePolicy(relation string) string {
+	return strings.ToLower(os.Getenv("DELETE_POLICY_" + relation))
+}
+
+// GetTransactionMode returns MONGO_TRANSACTIONS: "auto" (default) uses
+// transactions when MongoDB runs as a replica set or sharded cluster, "on"
+// always uses them and "off" never does.
+func GetTransactionMode() string {
+	mode := strings.ToLower(os.Getenv("MONGO_TRANSACTIONS"))
+	if mode == "" {
+		return "auto"
+	}
+	return mode
+}
+
+// GetAuditSigningKey returns AUDIT_SIGNING_KEY, the base64 encoded Ed25519
+// seed used to sign audit checkpoints. Checkpoints are disabled when empty.
+func GetAuditSigningKey() string {
+	return os.Getenv("AUDIT_SIGNING_KEY")
+}
+
+func GetAuditCheckpointInterval() time.Duration {
+	return getDuration("AUDIT_CHECKPOINT_INTERVAL", time.Hour)
+}
+
+// GetWebhookPollInterval returns how often queued webhook deliveries are
+// checked, from WEBHOOK_POLL_INTERVAL (default 5s).
+func GetWebhookPollInterval() time.Duration {
+	return getDuration("WEBHOOK_POLL_INTERVAL", 5*time.Second)
+}
+
+func GetWebhookTimeout() time.Duration {
+	return getDuration("WEBHOOK_TIMEOUT", 10*time.Second)
+}
+
+// GetOutboxPollInterval returns how often the outbox is checked for events
+// to relay, from OUTBOX_POLL_INTERVAL (default 1s).
+func GetOutboxPollInterval() time.Duration {
+	return getDuration("OUTBOX_POLL_INTERVAL", time.Second)
+}
+
+// GetOutboxRetention returns how long relayed events stay in the outbox,
+// from OUTBOX_RETENTION (default 168h).
+func GetOutboxRetention() time.Duration {
+	return getDuration("OUTBOX_RETENTION", 7*24*time.Hour)
+}
+
+// GetNATSURL returns NATS_URL. Events are not published to NATS when empty.
+func GetNATSURL() string {
+	return os.Getenv("NATS_URL")
+}
+
+func GetNATSSubjectPrefix() string {
+	prefix := os.Getenv("NATS_SUBJECT_PREFIX")
+	if prefix == "" {
+		return "events"
+	}
+	return prefix
+}
+
+// GetLivePollInterval returns how often live update streams poll the audit
+// log when MongoDB has no change streams, from LIVE_POLL_INTERVAL (default
+// 2s).
+func GetLivePollInterval() time.Duration {
+	return getDuration("LIVE_POLL_INTERVAL", 2*time.Second)
+}
+
+// GetJobPollInterval returns how often idle workers check the job queue, from
+// JOB_POLL_INTERVAL (default 1s).
+func GetJobPollInterval() time.Duration {
+	return getDuration("JOB_POLL_INTERVAL", time.Second)
+}
+
+// GetJobRetention returns how long succeeded jobs stay in the queue, from
+// JOB_RETENTION (default 168h).
+func GetJobRetention() time.Duration {
+	return getDuration("JOB_RETENTION", 7*24*time.Hour)
+}
+
+// GetDefaultCurrency returns DEFAULT_CURRENCY (default MXN), the currency
+// prices, subtotals and totals are rounded in.
+func GetDefaultCurrency() money.Currency {
+	value := os.Getenv("DEFAULT_CURRENCY")
+	if value == "" {
+		return "MXN"
+	}
+
+	currency, err := money.ParseCurrency(value)
+	if err != nil {
+		fmt.Printf("Invalid DEFAULT_CURRENCY %q, using MXN\n", value)
+		return "MXN"
+	}
+	return currency
+}
+
+// GetBaseCurrency returns BASE_CURRENCY, the currency every purchase total
+// is also converted to for reporting. It defaults to DEFAULT_CURRENCY.
+func GetBaseCurrency() money.Currency {
+	value := os.Getenv("BASE_CURRENCY")
+	if value == "" {
+		return GetDefaultCurrency()
+	}
+
+	currency, err := money.ParseCurrency(value)
+	if err != nil {
+		fmt.Printf("Invalid BASE_CURRENCY %q, using %s\n", value, GetDefaultCurrency())
+		return GetDefaultCurrency()
+	}
+	return currency
+}
+
+// GetDefaultVATRate returns DEFAULT_VAT_RATE, the IVA rate applied to lines
+// no tax rule matches, as a fraction. It defaults to 0.16.
+func GetDefaultVATRate() money.Amount {
+	value := os.Getenv("DEFAULT_VAT_RATE")
+	if value == "" {
+		return money.MustParse("0.16")
+	}
+
+	rate, err := money.Parse(value)
+	if err != nil || rate.IsNegative() || rate.Cmp(money.FromInt(1)) > 0 {
+		fmt.Printf("Invalid DEFAULT_VAT_RATE %q, using 0.16\n", value)
+		return money.MustParse("0.16")
+	}
+	return rate
+}
+
+func getDuration(name string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		fmt.Printf("Invalid %s %q, using %s\n", name, value, defaultValue)
+		return defaultValue
+	}
+	return duration
+}
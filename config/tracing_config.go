@@ -0,0 +1,111 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+)
+
+const defaultServiceName = "fiber-api"
+
+func GetServiceName() string {
+	serviceName := os.Getenv("OTEL_SERVICE_NAME")
+	if serviceName == "" {
+		return defaultServiceName
+	}
+	return serviceName
+}
+
+// GetTracesExporter returns the exporter selected by OTEL_TRACES_EXPORTER:
+// "otlp", "stdout", "file" or "none" (the default).
+func GetTracesExporter() string {
+	exporter := os.Getenv("OTEL_TRACES_EXPORTER")
+	if exporter == "" {
+		return "none"
+	}
+	return exporter
+}
+
+func GetTracesFile() string {
+	tracesFile := os.Getenv("OTEL_TRACES_FILE")
+	if tracesFile == "" {
+		return "traces.json"
+	}
+	return tracesFile
+}
+
+// InitTracer installs the global tracer provider and the W3C trace context
+// propagator. The OTLP exporter reads its endpoint and headers from the
+// standard OTEL_EXPORTER_OTLP_* variables. The returned function flushes
+// pending spans, closes the traces file if any, and must be called on
+// shutdown.
+func InitTracer() (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var exporter sdktrace.SpanExporter
+	var file *os.File
+	var err error
+
+	switch GetTracesExporter() {
+	case "none":
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+		exporter, err = otlptracehttp.New(context.Background())
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case "file":
+		file, err = os.OpenFile(GetTracesFile(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err == nil {
+			exporter, err = stdouttrace.New(stdouttrace.WithWriter(file))
+			if err != nil {
+				file.Close()
+			}
+		}
+	default:
+		err = fmt.Errorf("unknown traces exporter %q", GetTracesExporter())
+	}
+	if err != nil {
+		fmt.Printf("Error creating traces exporter: %s\n", err)
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(GetServiceName()),
+	))
+	if err != nil {
+		fmt.Printf("Error creating traces resource: %s\n", err)
+		if file != nil {
+			file.Close()
+		}
+		return nil, err
+	}
+
+	tracerProvider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(tracerProvider)
+
+	if file == nil {
+		return tracerProvider.Shutdown, nil
+	}
+	return func(ctx context.Context) error {
+		err := tracerProvider.Shutdown(ctx)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+	}, nil
+}
@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aldoramirezmartinez/fiber-api/audit"
+	"github.com/aldoramirezmartinez/fiber-api/models"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+type AuditController struct {
+	db         *mongo.Database
+	collection *mongo.Collection
+}
+
+func NewAuditController(db *mongo.Database) *AuditController {
+	if err := audit.EnsureIndexes(context.Background(), db); err != nil {
+		fmt.Println("Failed to create audit log indexes:", err)
+	}
+
+	return &AuditController{
+		db:         db,
+		collection: db.Collection(audit.CollectionName),
+	}
+}
+
+func (ac *AuditController) GetAuditEntries(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	filter := bson.M{}
+	if entity := c.Query("entity"); entity != "" {
+		filter["entity"] = entity
+	}
+
+	for _, param := range []string{"entity_id", "actor"} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		objID, err := primitive.ObjectIDFromHex(value)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"message": "Invalid " + param,
+				"error":   err.Error(),
+			})
+		}
+		filter[param] = objID
+	}
+
+	timestamp := bson.M{}
+	for param, operator := range map[string]string{"from": "$gte", "to": "$lt"} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		date, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"message": "Invalid " + param + " date",
+				"error":   err.Error(),
+			})
+		}
+		timestamp[operator] = date
+	}
+	if len(timestamp) > 0 {
+		filter["timestamp"] = timestamp
+	}
+
+	limit := c.QueryInt("limit", defaultAuditLimit)
+	if limit <= 0 || limit > maxAuditLimit {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": fmt.Sprintf("limit must be between 1 and %d", maxAuditLimit),
+		})
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "timestamp", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := ac.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to retrieve audit entries",
+			"error":   err.Error(),
+		})
+	}
+	defer cursor.Close(ctx)
+
+	entries := []models.AuditEntry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to decode audit entries",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.JSON(entries)
+}
+
+func (ac *AuditController) VerifyAuditLog(c *fiber.Ctx) error {
+	verification, err := audit.Verify(c.UserContext(), ac.db)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to verify audit log",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.JSON(verification)
+}
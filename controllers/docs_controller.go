@@ -0,0 +1,33 @@
+package controllers
+
+import (
+	"github.com/aldoramirezmartinez/fiber-api/docs"
+	"github.com/gofiber/fiber/v2"
+)
+
+type DocsController struct {
+	spec fiber.Map
+}
+
+func NewDocsController() *DocsController {
+	return &DocsController{
+		spec: docs.Spec(),
+	}
+}
+
+func (dc *DocsController) GetOpenAPI(c *fiber.Ctx) error {
+	return c.JSON(dc.spec)
+}
+
+func (dc *DocsController) GetSwaggerUI(c *fiber.Ctx) error {
+	page, err := docs.SwaggerUI()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to load Swagger UI",
+			"error":   err.Error(),
+		})
+	}
+
+	c.Type("html")
+	return c.Send(page)
+}
@@ -0,0 +1,209 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aldoramirezmartinez/fiber-api/audit"
+	"github.com/aldoramirezmartinez/fiber-api/exchange"
+	"github.com/aldoramirezmartinez/fiber-api/models"
+	"github.com/aldoramirezmartinez/fiber-api/money"
+	"github.com/aldoramirezmartinez/fiber-api/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const maxListedExchangeRates = 1000
+
+type ExchangeRateController struct {
+	db         *mongo.Database
+	unitOfWork *utils.UnitOfWork
+	collection *mongo.Collection
+}
+
+func NewExchangeRateController(db *mongo.Database) *ExchangeRateController {
+	if err := exchange.EnsureIndexes(context.Background(), db); err != nil {
+		fmt.Println("Failed to create exchange rate indexes:", err)
+	}
+
+	return &ExchangeRateController{
+		db:         db,
+		unitOfWork: utils.NewUnitOfWork(db),
+		collection: db.Collection(exchange.CollectionName),
+	}
+}
+
+// exchangeRateInput is one rate as sent to CreateExchangeRates. The
+// effective date may be a plain YYYY-MM-DD date.
+type exchangeRateInput struct {
+	From          money.Currency `json:"from"`
+	To            money.Currency `json:"to"`
+	Rate          money.Amount   `json:"rate"`
+	EffectiveDate string         `json:"effective_date"`
+}
+
+func (ec *ExchangeRateController) GetExchangeRates(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	filter := bson.M{}
+	for _, param := range []string{"from", "to"} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		currency, err := money.ParseCurrency(value)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"message": "Invalid " + param + " currency",
+				"error":   err.Error(),
+			})
+		}
+		filter[param] = currency
+	}
+
+	// date returns the rates in effect on that day: the latest one per pair.
+	if value := c.Query("date"); value != "" {
+		date, err := exchange.ParseDate(value)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"message": "Invalid date",
+				"error":   err.Error(),
+			})
+		}
+		filter["effective_date"] = bson.M{"$lte": date}
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "from", Value: 1}, {Key: "to", Value: 1}, {Key: "effective_date", Value: -1}}).
+		SetLimit(maxListedExchangeRates)
+
+	cursor, err := ec.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to retrieve exchange rates",
+			"error":   err.Error(),
+		})
+	}
+	defer cursor.Close(ctx)
+
+	rates := []models.ExchangeRate{}
+	if err := cursor.All(ctx, &rates); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to decode exchange rates",
+			"error":   err.Error(),
+		})
+	}
+
+	if c.Query("date") != "" {
+		rates = latestRates(rates)
+	}
+
+	return c.JSON(rates)
+}
+
+// CreateExchangeRates loads rates from a JSON object, a JSON array or a CSV
+// file with a from,to,rate,effective_date header. A rate for the same
+// currencies and effective date replaces the one already stored. The rates
+// are saved in one transaction, so a failed import saves none of them.
+func (ec *ExchangeRateController) CreateExchangeRates(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	var rates []models.ExchangeRate
+	var err error
+	if strings.HasPrefix(c.Get(fiber.HeaderContentType), "text/csv") {
+		rates, err = exchange.ParseCSV(bytes.NewReader(c.Body()))
+	} else {
+		rates, err = parseExchangeRates(c.Body())
+	}
+	if err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"message": "Invalid exchange rates",
+			"error":   err.Error(),
+		})
+	}
+	if len(rates) == 0 {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"message": "No exchange rates given",
+		})
+	}
+
+	var saved []models.ExchangeRate
+	err = ec.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		saved = make([]models.ExchangeRate, 0, len(rates))
+		for _, rate := range rates {
+			stored, previous, err := exchange.Save(ctx, ec.db, rate)
+			if err != nil {
+				return err
+			}
+
+			if previous == nil {
+				err = audit.Record(ctx, c, ec.db, models.AuditCreate, exchange.CollectionName, stored.ID, nil)
+			} else {
+				err = audit.Record(ctx, c, ec.db, models.AuditUpdate, exchange.CollectionName, stored.ID, previous)
+			}
+			if err != nil {
+				return err
+			}
+			saved = append(saved, stored)
+		}
+		return nil
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to save exchange rates",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(saved)
+}
+
+func parseExchangeRates(body []byte) ([]models.ExchangeRate, error) {
+	var inputs []exchangeRateInput
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &inputs); err != nil {
+			return nil, err
+		}
+	} else {
+		var input exchangeRateInput
+		if err := json.Unmarshal(body, &input); err != nil {
+			return nil, err
+		}
+		inputs = append(inputs, input)
+	}
+
+	rates := make([]models.ExchangeRate, 0, len(inputs))
+	for i, input := range inputs {
+		rate := models.ExchangeRate{From: input.From, To: input.To, Rate: input.Rate}
+		if input.EffectiveDate != "" {
+			date, err := exchange.ParseDate(input.EffectiveDate)
+			if err != nil {
+				return nil, fmt.Errorf("rate %d: %w", i, err)
+			}
+			rate.EffectiveDate = date
+		}
+		if err := rate.Validate(); err != nil {
+			return nil, fmt.Errorf("rate %d: %w", i, err)
+		}
+		rates = append(rates, rate)
+	}
+	return rates, nil
+}
+
+// latestRates keeps the first rate of each pair in rates, which are sorted
+// by pair and newest effective date first.
+func latestRates(rates []models.ExchangeRate) []models.ExchangeRate {
+	latest := []models.ExchangeRate{}
+	for _, rate := range rates {
+		if n := len(latest); n > 0 && latest[n-1].From == rate.From && latest[n-1].To == rate.To {
+			continue
+		}
+		latest = append(latest, rate)
+	}
+	return latest
+}
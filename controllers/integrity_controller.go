@@ -0,0 +1,41 @@
+package controllers
+
+import (
+	"github.com/aldoramirezmartinez/fiber-api/integrity"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type IntegrityController struct {
+	db *mongo.Database
+}
+
+func NewIntegrityController(db *mongo.Database) *IntegrityController {
+	return &IntegrityController{
+		db: db,
+	}
+}
+
+func (ic *IntegrityController) CheckIntegrity(c *fiber.Ctx) error {
+	report, err := integrity.Check(c.UserContext(), ic.db, false)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to check data integrity",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.JSON(report)
+}
+
+func (ic *IntegrityController) RepairIntegrity(c *fiber.Ctx) error {
+	report, err := integrity.Check(c.UserContext(), ic.db, true)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to repair data integrity",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.JSON(report)
+}
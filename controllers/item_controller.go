@@ -2,8 +2,20 @@ package controllers
 
 import (
 	"context"
-
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aldoramirezmartinez/fiber-api/audit"
+	"github.com/aldoramirezmartinez/fiber-api/config"
+	"github.com/aldoramirezmartinez/fiber-api/events"
+	"github.com/aldoramirezmartinez/fiber-api/exchange"
+	"github.com/aldoramirezmartinez/fiber-api/integrity"
 	"github.com/aldoramirezmartinez/fiber-api/models"
+	"github.com/aldoramirezmartinez/fiber-api/money"
+	"github.com/aldoramirezmartinez/fiber-api/prices"
+	"github.com/aldoramirezmartinez/fiber-api/sourcing"
 	"github.com/aldoramirezmartinez/fiber-api/utils"
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson"
@@ -12,21 +24,29 @@ import (
 )
 
 type ItemController struct {
+	db                 *mongo.Database
+	unitOfWork         *utils.UnitOfWork
 	itemCollection     *mongo.Collection
 	providerCollection *mongo.Collection
 }
 
 func NewItemController(db *mongo.Database) *ItemController {
+	if err := prices.EnsureIndexes(context.Background(), db); err != nil {
+		fmt.Println("Failed to create item price indexes:", err)
+	}
+
 	return &ItemController{
+		db:                 db,
+		unitOfWork:         utils.NewUnitOfWork(db),
 		itemCollection:     db.Collection("items"),
 		providerCollection: db.Collection("providers"),
 	}
 }
 
 func (ic *ItemController) GetAllItems(c *fiber.Ctx) error {
-	ctx := context.TODO()
+	ctx := c.UserContext()
 
-	cursor, err := ic.itemCollection.Find(ctx, bson.M{})
+	cursor, err := ic.itemCollection.Find(ctx, utils.ScopeDeleted(c, bson.M{}))
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Failed to get items",
@@ -45,9 +65,10 @@ func (ic *ItemController) GetAllItems(c *fiber.Ctx) error {
 
 	var itemResponses []models.ItemResponse
 	for _, item := range items {
+		// A dangling provider reference should not break the whole listing.
 		var provider models.Provider
 		err := ic.providerCollection.FindOne(ctx, bson.M{"_id": item.ProviderID}).Decode(&provider)
-		if err != nil {
+		if err != nil && err != mongo.ErrNoDocuments {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"message": "Failed to retrieve provider data",
 				"error":   err.Error(),
@@ -65,7 +86,7 @@ func (ic *ItemController) GetAllItems(c *fiber.Ctx) error {
 }
 
 func (ic *ItemController) GetItem(c *fiber.Ctx) error {
-	ctx := context.TODO()
+	ctx := c.UserContext()
 
 	itemID := c.Params("id")
 
@@ -79,7 +100,7 @@ func (ic *ItemController) GetItem(c *fiber.Ctx) error {
 
 	var itemResponse models.ItemResponse
 
-	err = ic.itemCollection.FindOne(ctx, bson.M{"_id": objID}).Decode(&itemResponse.Item)
+	err = ic.itemCollection.FindOne(ctx, utils.ScopeDeleted(c, bson.M{"_id": objID})).Decode(&itemResponse.Item)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -105,11 +126,16 @@ func (ic *ItemController) GetItem(c *fiber.Ctx) error {
 		})
 	}
 
+	utils.SetETag(c, itemResponse.Item.Version)
+	if utils.IfNoneMatch(c, itemResponse.Item.Version) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
 	return c.JSON(itemResponse)
 }
 
 func (ic *ItemController) CreateItem(c *fiber.Ctx) error {
-	ctx := context.TODO()
+	ctx := c.UserContext()
 
 	item := new(models.Item)
 	if err := c.BodyParser(item); err != nil {
@@ -119,8 +145,15 @@ func (ic *ItemController) CreateItem(c *fiber.Ctx) error {
 		})
 	}
 
+	if err := item.Validate(); err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"message": "Invalid item",
+			"error":   err.Error(),
+		})
+	}
+
 	providerID := item.ProviderID
-	providerExists, err := utils.CheckDocumentExists(ic.providerCollection, providerID)
+	providerExists, err := utils.CheckDocumentExists(ctx, ic.providerCollection, providerID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Failed to check provider",
@@ -133,7 +166,26 @@ func (ic *ItemController) CreateItem(c *fiber.Ctx) error {
 		})
 	}
 
-	result, err := ic.itemCollection.InsertOne(ctx, item)
+	item.ID = primitive.NewObjectID()
+	item.Version = 1
+	item.DeletedAt = nil
+	item.DeletedBy = nil
+
+	err = ic.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		if _, err := ic.itemCollection.InsertOne(ctx, item); err != nil {
+			return err
+		}
+		_, err := prices.Record(ctx, ic.db, models.ItemPrice{
+			ItemID:   item.ID,
+			Price:    item.Price,
+			Currency: item.Currency.Or(config.GetDefaultCurrency()),
+			Actor:    utils.ActorID(c),
+		})
+		if err != nil {
+			return err
+		}
+		return audit.Record(ctx, c, ic.db, models.AuditCreate, "items", item.ID, nil)
+	})
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Failed to create item",
@@ -141,16 +193,6 @@ func (ic *ItemController) CreateItem(c *fiber.Ctx) error {
 		})
 	}
 
-	insertedID, ok := result.InsertedID.(primitive.ObjectID)
-	if !ok {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Failed to get item ID",
-			"error":   "Invalid inserted ID",
-		})
-	}
-
-	item.ID = insertedID
-
 	var provider models.Provider
 	err = ic.providerCollection.FindOne(ctx, bson.M{"_id": item.ProviderID}).Decode(&provider)
 	if err != nil {
@@ -165,11 +207,12 @@ func (ic *ItemController) CreateItem(c *fiber.Ctx) error {
 		Provider: provider,
 	}
 
+	utils.SetETag(c, item.Version)
 	return c.JSON(itemResponse)
 }
 
 func (ic *ItemController) UpdateItem(c *fiber.Ctx) error {
-	ctx := context.TODO()
+	ctx := c.UserContext()
 
 	itemID := c.Params("id")
 
@@ -189,8 +232,15 @@ func (ic *ItemController) UpdateItem(c *fiber.Ctx) error {
 		})
 	}
 
+	if err := itemToUpdate.Validate(); err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"message": "Invalid item",
+			"error":   err.Error(),
+		})
+	}
+
 	var existingItem models.Item
-	err = ic.itemCollection.FindOne(ctx, bson.M{"_id": objID}).Decode(&existingItem)
+	err = ic.itemCollection.FindOne(ctx, utils.NotDeleted(bson.M{"_id": objID})).Decode(&existingItem)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -203,9 +253,15 @@ func (ic *ItemController) UpdateItem(c *fiber.Ctx) error {
 		})
 	}
 
+	if !utils.IfMatch(c, existingItem.Version) {
+		return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+			"message": "Item has been modified",
+		})
+	}
+
 	providerID := itemToUpdate.ProviderID
 	if providerID != existingItem.ProviderID {
-		providerExists, err := utils.CheckDocumentExists(ic.providerCollection, providerID)
+		providerExists, err := utils.CheckDocumentExists(ctx, ic.providerCollection, providerID)
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"message": "Failed to check provider existence",
@@ -219,26 +275,38 @@ func (ic *ItemController) UpdateItem(c *fiber.Ctx) error {
 		}
 	}
 
+	// PUT keeps the current price when it is omitted; "0" makes it free.
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(c.Body(), &fields); err == nil {
+		if _, ok := fields["price"]; !ok {
+			itemToUpdate.Price = existingItem.Price
+		}
+	}
+
 	itemToUpdate.ID = objID
+	itemToUpdate.Version = existingItem.Version + 1
+	itemToUpdate.DeletedAt = nil
+	itemToUpdate.DeletedBy = nil
 
 	update := bson.M{
 		"$set": itemToUpdate,
 	}
 
-	result, err := ic.itemCollection.UpdateOne(ctx, bson.M{"_id": objID}, update)
+	err = ic.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		return ic.updateItem(ctx, c, existingItem, update, models.ItemPrice{Price: itemToUpdate.Price, Currency: itemToUpdate.Currency})
+	})
 	if err != nil {
+		if err == utils.ErrVersionConflict {
+			return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+				"message": "Item has been modified",
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Failed to update item",
 			"error":   err.Error(),
 		})
 	}
 
-	if result.MatchedCount == 0 {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"message": "Item not found",
-		})
-	}
-
 	var provider models.Provider
 	err = ic.providerCollection.FindOne(ctx, bson.M{"_id": providerID}).Decode(&provider)
 	if err != nil {
@@ -253,11 +321,12 @@ func (ic *ItemController) UpdateItem(c *fiber.Ctx) error {
 		Provider: provider,
 	}
 
+	utils.SetETag(c, itemToUpdate.Version)
 	return c.JSON(itemResponse)
 }
 
 func (ic *ItemController) DeleteItem(c *fiber.Ctx) error {
-	ctx := context.TODO()
+	ctx := c.UserContext()
 
 	itemID := c.Params("id")
 
@@ -269,19 +338,681 @@ func (ic *ItemController) DeleteItem(c *fiber.Ctx) error {
 		})
 	}
 
-	result, err := ic.itemCollection.DeleteOne(ctx, bson.M{"_id": objID})
+	var existingItem models.Item
+	err = ic.itemCollection.FindOne(ctx, utils.NotDeleted(bson.M{"_id": objID})).Decode(&existingItem)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"message": "Item not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to get item",
+			"error":   err.Error(),
+		})
+	}
+
+	if !utils.IfMatch(c, existingItem.Version) {
+		return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+			"message": "Item has been modified",
+		})
+	}
+
+	err = ic.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		if err := integrity.BeforeDelete(ctx, ic.db, "items", objID, utils.ActorID(c)); err != nil {
+			return err
+		}
+
+		result, err := ic.itemCollection.UpdateOne(ctx, utils.VersionFilter(objID, existingItem.Version), utils.SoftDeleteUpdate(utils.ActorID(c)))
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return utils.ErrVersionConflict
+		}
+		return audit.Record(ctx, c, ic.db, models.AuditDelete, "items", objID, existingItem)
+	})
 	if err != nil {
+		if restrictErr, ok := err.(*integrity.RestrictError); ok {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"message":    "Item is still referenced",
+				"error":      restrictErr.Error(),
+				"references": restrictErr.References,
+			})
+		}
+		if err == utils.ErrVersionConflict {
+			return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+				"message": "Item has been modified",
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Failed to delete item",
 			"error":   err.Error(),
 		})
 	}
 
-	if result.DeletedCount == 0 {
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (ic *ItemController) PatchItem(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	itemID := c.Params("id")
+
+	objID, err := primitive.ObjectIDFromHex(itemID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid item ID",
+			"error":   err.Error(),
+		})
+	}
+
+	var existingItem models.Item
+	err = ic.itemCollection.FindOne(ctx, utils.NotDeleted(bson.M{"_id": objID})).Decode(&existingItem)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"message": "Item not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to get item",
+			"error":   err.Error(),
+		})
+	}
+
+	if !utils.IfMatch(c, existingItem.Version) {
+		return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+			"message": "Item has been modified",
+		})
+	}
+
+	patchedItem := new(models.Item)
+	if err := utils.ApplyPatch(c, existingItem, patchedItem); err != nil {
+		if err == utils.ErrUnsupportedPatch {
+			return c.Status(fiber.StatusUnsupportedMediaType).JSON(fiber.Map{
+				"message": "Unsupported patch format",
+				"error":   err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"message": "Failed to apply patch",
+			"error":   err.Error(),
+		})
+	}
+
+	patchedItem.ID = objID
+	patchedItem.ProviderID = existingItem.ProviderID
+	patchedItem.Version = existingItem.Version + 1
+	patchedItem.DeletedAt = existingItem.DeletedAt
+	patchedItem.DeletedBy = existingItem.DeletedBy
+
+	if err := patchedItem.Validate(); err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"message": "Invalid item",
+			"error":   err.Error(),
+		})
+	}
+
+	update, err := utils.PatchUpdate(existingItem, patchedItem)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to update item",
+			"error":   err.Error(),
+		})
+	}
+
+	err = ic.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		return ic.updateItem(ctx, c, existingItem, update, models.ItemPrice{Price: patchedItem.Price, Currency: patchedItem.Currency})
+	})
+	if err != nil {
+		if err == utils.ErrVersionConflict {
+			return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+				"message": "Item has been modified",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to update item",
+			"error":   err.Error(),
+		})
+	}
+
+	var provider models.Provider
+	err = ic.providerCollection.FindOne(ctx, bson.M{"_id": patchedItem.ProviderID}).Decode(&provider)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to retrieve provider data",
+			"error":   err.Error(),
+		})
+	}
+
+	itemResponse := models.ItemResponse{
+		Item:     *patchedItem,
+		Provider: provider,
+	}
+
+	utils.SetETag(c, patchedItem.Version)
+	return c.JSON(itemResponse)
+}
+
+func (ic *ItemController) RestoreItem(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	itemID := c.Params("id")
+
+	objID, err := primitive.ObjectIDFromHex(itemID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid item ID",
+			"error":   err.Error(),
+		})
+	}
+
+	var deletedItem models.Item
+	err = ic.itemCollection.FindOne(ctx, utils.OnlyDeleted(bson.M{"_id": objID})).Decode(&deletedItem)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"message": "Deleted item not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to get item",
+			"error":   err.Error(),
+		})
+	}
+
+	if !utils.IfMatch(c, deletedItem.Version) {
+		return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+			"message": "Item has been modified",
+		})
+	}
+
+	err = ic.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		if err := integrity.BeforeRestore(ctx, ic.db, "items", objID); err != nil {
+			return err
+		}
+
+		result, err := ic.itemCollection.UpdateOne(ctx, utils.VersionFilter(objID, deletedItem.Version), utils.RestoreUpdate())
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return utils.ErrVersionConflict
+		}
+		return audit.Record(ctx, c, ic.db, models.AuditRestore, "items", objID, deletedItem)
+	})
+	if err != nil {
+		if parentErr, ok := err.(*integrity.DeletedParentError); ok {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"message": "Item refers to deleted documents",
+				"error":   parentErr.Error(),
+				"parents": parentErr.Parents,
+			})
+		}
+		if err == utils.ErrVersionConflict {
+			return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+				"message": "Item has been modified",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to restore item",
+			"error":   err.Error(),
+		})
+	}
+
+	deletedItem.DeletedAt = nil
+	deletedItem.DeletedBy = nil
+	deletedItem.Version++
+
+	var provider models.Provider
+	err = ic.providerCollection.FindOne(ctx, bson.M{"_id": deletedItem.ProviderID}).Decode(&provider)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to retrieve provider data",
+			"error":   err.Error(),
+		})
+	}
+
+	itemResponse := models.ItemResponse{
+		Item:     deletedItem,
+		Provider: provider,
+	}
+
+	utils.SetETag(c, deletedItem.Version)
+	return c.JSON(itemResponse)
+}
+
+// updateItem applies update to existingItem, records it in the audit log
+// and, when the price is no longer the same, adds change to the price
+// history and writes an ItemPriceChanged event to the outbox. change holds
+// the new price, and optionally its effective date and reason. Run it
+// inside UnitOfWork.Do.
+func (ic *ItemController) updateItem(ctx context.Context, c *fiber.Ctx, existingItem models.Item, update interface{}, change models.ItemPrice) error {
+	result, err := ic.itemCollection.UpdateOne(ctx, utils.VersionFilter(existingItem.ID, existingItem.Version), update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return utils.ErrVersionConflict
+	}
+	if err := audit.Record(ctx, c, ic.db, models.AuditUpdate, "items", existingItem.ID, existingItem); err != nil {
+		return err
+	}
+
+	if change.Price.Equal(existingItem.Price) {
+		return nil
+	}
+
+	change.ItemID = existingItem.ID
+	change.PreviousPrice = &existingItem.Price
+	change.Currency = change.Currency.Or(existingItem.Currency.Or(config.GetDefaultCurrency()))
+	change.Actor = utils.ActorID(c)
+	if _, err := prices.Record(ctx, ic.db, change); err != nil {
+		return err
+	}
+
+	return events.Emit(ctx, ic.db, models.EventTypeItemPriceChanged, "item", existingItem.ID, models.ItemPriceChange{
+		ItemID:        existingItem.ID,
+		PreviousPrice: existingItem.Price,
+		Price:         change.Price,
+	})
+}
+
+func (ic *ItemController) GetItemPrices(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	objID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid item ID",
+			"error":   err.Error(),
+		})
+	}
+
+	exists, err := utils.CheckDocumentExists(ctx, ic.itemCollection, objID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to get item",
+			"error":   err.Error(),
+		})
+	}
+	if !exists {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"message": "Item not found",
 		})
 	}
 
-	return c.SendStatus(fiber.StatusNoContent)
+	history, err := prices.History(ctx, ic.db, objID, c.Query("status"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to retrieve item prices",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.JSON(history)
+}
+
+// GetItemPriceAt returns what an item cost on the date in ?at, now by
+// default.
+func (ic *ItemController) GetItemPriceAt(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	objID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid item ID",
+			"error":   err.Error(),
+		})
+	}
+
+	at := time.Now()
+	if value := c.Query("at"); value != "" {
+		at, err = exchange.ParseDate(value)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"message": "Invalid at date",
+				"error":   err.Error(),
+			})
+		}
+	}
+
+	var item models.Item
+	err = ic.itemCollection.FindOne(ctx, bson.M{"_id": objID}).Decode(&item)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"message": "Item not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to get item",
+			"error":   err.Error(),
+		})
+	}
+
+	price, err := prices.At(ctx, ic.db, item, at)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to retrieve item price",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.JSON(models.ItemPriceAt{
+		ItemID:   item.ID,
+		At:       at,
+		Price:    price,
+		Currency: item.Currency.Or(config.GetDefaultCurrency()),
+	})
+}
+
+// ChangeItemPrice changes the price of an item with a reason. A future
+// effective date schedules the change. A past one backdates it: the current
+// price changes too unless a later price was already recorded, in which
+// case only the history is corrected.
+func (ic *ItemController) ChangeItemPrice(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	objID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid item ID",
+			"error":   err.Error(),
+		})
+	}
+
+	request := new(models.ItemPriceChangeRequest)
+	if err := c.BodyParser(request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid request body",
+			"error":   err.Error(),
+		})
+	}
+	if err := request.Validate(); err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"message": "Invalid price change",
+			"error":   err.Error(),
+		})
+	}
+
+	now := time.Now()
+	effectiveDate := now
+	if request.EffectiveDate != "" {
+		effectiveDate, err = exchange.ParseDate(request.EffectiveDate)
+		if err != nil {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+				"message": "Invalid price change",
+				"error":   err.Error(),
+			})
+		}
+	}
+
+	var existingItem models.Item
+	err = ic.itemCollection.FindOne(ctx, utils.NotDeleted(bson.M{"_id": objID})).Decode(&existingItem)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"message": "Item not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to get item",
+			"error":   err.Error(),
+		})
+	}
+
+	if !utils.IfMatch(c, existingItem.Version) {
+		return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+			"message": "Item has been modified",
+		})
+	}
+
+	change := models.ItemPrice{
+		ItemID:        objID,
+		Price:         request.Price,
+		Currency:      existingItem.Currency.Or(config.GetDefaultCurrency()),
+		EffectiveDate: effectiveDate,
+		Reason:        request.Reason,
+		Actor:         utils.ActorID(c),
+	}
+
+	var saved models.ItemPrice
+	err = ic.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		if effectiveDate.After(now) {
+			var err error
+			saved, err = prices.Schedule(ctx, ic.db, change)
+			if err != nil {
+				return err
+			}
+			return audit.Record(ctx, c, ic.db, models.AuditCreate, prices.CollectionName, saved.ID, nil)
+		}
+
+		latest, err := prices.LatestApplied(ctx, ic.db, objID)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return err
+		}
+		if err == nil && latest.EffectiveDate.After(effectiveDate) {
+			var corrected *models.ItemPrice
+			saved, corrected, err = prices.Backdate(ctx, ic.db, existingItem, change)
+			if err != nil {
+				return err
+			}
+			if err := audit.Record(ctx, c, ic.db, models.AuditCreate, prices.CollectionName, saved.ID, nil); err != nil {
+				return err
+			}
+			if corrected != nil {
+				if err := audit.Record(ctx, c, ic.db, models.AuditUpdate, prices.CollectionName, corrected.ID, corrected); err != nil {
+					return err
+				}
+			}
+			if saved.Price.Equal(*saved.PreviousPrice) {
+				return nil
+			}
+			return events.Emit(ctx, ic.db, models.EventTypeItemPriceChanged, "item", objID, models.ItemPriceChange{
+				ItemID:        objID,
+				PreviousPrice: *saved.PreviousPrice,
+				Price:         saved.Price,
+				EffectiveDate: &saved.EffectiveDate,
+			})
+		}
+
+		update := bson.M{"$set": bson.M{"price": request.Price, "version": existingItem.Version + 1}}
+		if err := ic.updateItem(ctx, c, existingItem, update, change); err != nil {
+			return err
+		}
+		saved, err = prices.LatestApplied(ctx, ic.db, objID)
+		return err
+	})
+	if err != nil {
+		if err == utils.ErrVersionConflict {
+			return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+				"message": "Item has been modified",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to change item price",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(saved)
+}
+
+func (ic *ItemController) CancelItemPrice(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	objID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid item ID",
+			"error":   err.Error(),
+		})
+	}
+
+	priceID, err := primitive.ObjectIDFromHex(c.Params("price_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid price ID",
+			"error":   err.Error(),
+		})
+	}
+
+	var existingItem models.Item
+	err = ic.itemCollection.FindOne(ctx, utils.NotDeleted(bson.M{"_id": objID})).Decode(&existingItem)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"message": "Item not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to get item",
+			"error":   err.Error(),
+		})
+	}
+
+	if !utils.IfMatch(c, existingItem.Version) {
+		return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+			"message": "Item has been modified",
+		})
+	}
+
+	var price models.ItemPrice
+	err = ic.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		// Lock the item at the version the caller saw.
+		result, err := ic.itemCollection.UpdateOne(ctx, utils.VersionFilter(objID, existingItem.Version), bson.M{
+			"$set": bson.M{"lock": primitive.NewObjectID()},
+		})
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return utils.ErrVersionConflict
+		}
+
+		price, err = prices.Cancel(ctx, ic.db, objID, priceID)
+		if err != nil {
+			return err
+		}
+		before := price
+		before.Status = models.ItemPriceScheduled
+		return audit.Record(ctx, c, ic.db, models.AuditUpdate, prices.CollectionName, price.ID, before)
+	})
+	if err != nil {
+		switch err {
+		case utils.ErrVersionConflict:
+			return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+				"message": "Item has been modified",
+			})
+		case mongo.ErrNoDocuments:
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"message": "Price not found",
+			})
+		case prices.ErrNotScheduled:
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"message": "Price is not scheduled",
+				"error":   err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to cancel item price",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.JSON(price)
+}
+
+// GetBestSource returns the cheapest valid provider offer for ?quantity of
+// an item on ?date, with every other valid offer. Offers are compared in
+// ?currency, BASE_CURRENCY by default.
+func (ic *ItemController) GetBestSource(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	objID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid item ID",
+			"error":   err.Error(),
+		})
+	}
+
+	quantity := c.QueryInt("quantity", 1)
+	if quantity <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid quantity",
+			"error":   "quantity must be positive",
+		})
+	}
+
+	currency := config.GetBaseCurrency()
+	if value := c.Query("currency"); value != "" {
+		currency, err = money.ParseCurrency(value)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"message": "Invalid currency",
+				"error":   err.Error(),
+			})
+		}
+	}
+
+	date := time.Now()
+	if value := c.Query("date"); value != "" {
+		date, err = exchange.ParseDate(value)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"message": "Invalid date",
+				"error":   err.Error(),
+			})
+		}
+	}
+
+	exists, err := utils.CheckDocumentExists(ctx, ic.itemCollection, objID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to get item",
+			"error":   err.Error(),
+		})
+	}
+	if !exists {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"message": "Item not found",
+		})
+	}
+
+	options, err := sourcing.Options(ctx, ic.db, objID, primitive.NilObjectID, quantity, currency, date)
+	if err != nil {
+		var rateErr *exchange.RateNotFoundError
+		if errors.As(err, &rateErr) {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+				"message": "Missing exchange rate",
+				"error":   rateErr.Error(),
+			})
+		}
+		if err == sourcing.ErrBelowMinimum {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+				"message": "No offer for that quantity",
+				"error":   err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to retrieve provider prices",
+			"error":   err.Error(),
+		})
+	}
+	if len(options) == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"message": "No provider offers this item",
+		})
+	}
+
+	return c.JSON(models.BestSource{
+		ItemID:   objID,
+		Quantity: quantity,
+		Date:     date,
+		Best:     options[0],
+		Options:  options,
+	})
 }
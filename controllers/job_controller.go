@@ -0,0 +1,138 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aldoramirezmartinez/fiber-api/config"
+	"github.com/aldoramirezmartinez/fiber-api/models"
+	"github.com/aldoramirezmartinez/fiber-api/queue"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	defaultJobLimit = 100
+	maxJobLimit     = 1000
+)
+
+type JobController struct {
+	db         *mongo.Database
+	collection *mongo.Collection
+}
+
+func NewJobController(db *mongo.Database) *JobController {
+	if err := queue.EnsureIndexes(context.Background(), db, config.GetJobRetention()); err != nil {
+		fmt.Println("Failed to create job queue indexes:", err)
+	}
+
+	return &JobController{
+		db:         db,
+		collection: db.Collection(queue.CollectionName),
+	}
+}
+
+func (jc *JobController) GetJobs(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	filter := bson.M{}
+	for _, param := range []string{"status", "type"} {
+		if value := c.Query(param); value != "" {
+			filter[param] = value
+		}
+	}
+
+	limit := c.QueryInt("limit", defaultJobLimit)
+	if limit <= 0 || limit > maxJobLimit {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": fmt.Sprintf("limit must be between 1 and %d", maxJobLimit),
+		})
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "run_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := jc.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to retrieve jobs",
+			"error":   err.Error(),
+		})
+	}
+	defer cursor.Close(ctx)
+
+	jobs := []models.Job{}
+	if err := cursor.All(ctx, &jobs); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to decode jobs",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.JSON(jobs)
+}
+
+func (jc *JobController) GetJob(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	objID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid job ID",
+			"error":   err.Error(),
+		})
+	}
+
+	var job models.Job
+	err = jc.collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&job)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"message": "Job not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to get job",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.JSON(job)
+}
+
+func (jc *JobController) RetryJob(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	objID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid job ID",
+			"error":   err.Error(),
+		})
+	}
+
+	job, err := queue.Retry(ctx, jc.db, objID)
+	if err != nil {
+		switch err {
+		case mongo.ErrNoDocuments:
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"message": "Job not found",
+			})
+		case queue.ErrNotRetryable:
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"message": "Job cannot be retried",
+				"error":   err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to retry job",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(job)
+}
@@ -0,0 +1,362 @@
+package controllers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aldoramirezmartinez/fiber-api/config"
+	"github.com/aldoramirezmartinez/fiber-api/live"
+	"github.com/aldoramirezmartinez/fiber-api/models"
+	"github.com/aldoramirezmartinez/fiber-api/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/websocket/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	liveHeartbeat       = 15 * time.Second
+	liveRetry           = 3 * time.Second
+	liveSubscriptionKey = "live_subscription"
+)
+
+// liveSubscription is what a client asked to watch, read from the query
+// string before the stream starts.
+type liveSubscription struct {
+	collections    []string
+	providerID     *primitive.ObjectID
+	userID         *primitive.ObjectID
+	statuses       map[string]bool
+	includeDeleted bool
+	lastEventID    string
+}
+
+// LiveController streams purchase and item changes over Server-Sent Events
+// and WebSockets. Streams outlive their request, so they run on the
+// controller's own context, which Shutdown cancels.
+type LiveController struct {
+	db                 *mongo.Database
+	purchaseController *PurchaseV2Controller
+	providerCollection *mongo.Collection
+	ctx                context.Context
+	cancel             context.CancelFunc
+}
+
+func NewLiveController(db *mongo.Database, purchaseController *PurchaseV2Controller) *LiveController {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &LiveController{
+		db:                 db,
+		purchaseController: purchaseController,
+		providerCollection: db.Collection("providers"),
+		ctx:                ctx,
+		cancel:             cancel,
+	}
+}
+
+// Shutdown ends every open stream so the server can stop.
+func (lc *LiveController) Shutdown() {
+	lc.cancel()
+}
+
+func (lc *LiveController) StreamEvents(c *fiber.Ctx) error {
+	subscription, err := newLiveSubscription(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid live update filter",
+			"error":   err.Error(),
+		})
+	}
+	if lastEventID := c.Get("Last-Event-ID"); lastEventID != "" {
+		subscription.lastEventID = strings.Clone(lastEventID)
+	}
+
+	ctx, cancel := context.WithCancel(lc.ctx)
+	messages, err := lc.subscribe(ctx, subscription)
+	if err != nil {
+		cancel()
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"message": "Live updates unavailable",
+			"error":   err.Error(),
+		})
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer cancel()
+
+		fmt.Fprintf(w, "retry: %d\n\n", liveRetry.Milliseconds())
+		if err := w.Flush(); err != nil {
+			return
+		}
+
+		heartbeat := time.NewTicker(liveHeartbeat)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case message, ok := <-messages:
+				if !ok {
+					return
+				}
+				data, err := json.Marshal(message)
+				if err != nil {
+					fmt.Println("Failed to encode live update:", err)
+					continue
+				}
+				if message.ID != "" {
+					fmt.Fprintf(w, "id: %s\n", message.ID)
+				}
+				fmt.Fprintf(w, "event: %s\ndata: %s\n\n", message.Type, data)
+			case <-heartbeat.C:
+				// Comments keep proxies from closing an idle stream and
+				// show us when the client went away.
+				fmt.Fprint(w, ": keep-alive\n\n")
+			}
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	})
+	return nil
+}
+
+// UpgradeWebSocket rejects requests that are not WebSocket upgrades and reads
+// the subscription before the connection is upgraded.
+func (lc *LiveController) UpgradeWebSocket(c *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(c) {
+		return c.Status(fiber.StatusUpgradeRequired).JSON(fiber.Map{
+			"message": "WebSocket upgrade required",
+		})
+	}
+
+	subscription, err := newLiveSubscription(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid live update filter",
+			"error":   err.Error(),
+		})
+	}
+	c.Locals(liveSubscriptionKey, subscription)
+	return c.Next()
+}
+
+func (lc *LiveController) StreamWebSocket(conn *websocket.Conn) {
+	subscription, _ := conn.Locals(liveSubscriptionKey).(liveSubscription)
+
+	ctx, cancel := context.WithCancel(lc.ctx)
+	defer cancel()
+
+	messages, err := lc.subscribe(ctx, subscription)
+	if err != nil {
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "Live updates unavailable"))
+		return
+	}
+
+	// Clients only send control frames; reading them is how a close from
+	// the client is noticed.
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	heartbeat := time.NewTicker(liveHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""))
+			return
+		case message, ok := <-messages:
+			if !ok {
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "Live updates interrupted"))
+				return
+			}
+			if err := conn.WriteJSON(message); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(liveHeartbeat)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// subscribe opens the change feed and sends the changes subscription matches
+// on the returned channel until ctx is done or the feed fails, then closes
+// it. Clients reconnect with the ID of the last message they got.
+func (lc *LiveController) subscribe(ctx context.Context, subscription liveSubscription) (<-chan models.LiveMessage, error) {
+	source, reset, err := live.Open(ctx, lc.db, subscription.collections, subscription.lastEventID, config.GetLivePollInterval())
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make(chan models.LiveMessage)
+	go func() {
+		defer close(messages)
+		defer source.Close(context.Background())
+
+		send := func(message models.LiveMessage) bool {
+			select {
+			case messages <- message:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		if reset && !send(models.LiveMessage{Type: models.LiveTypeReset}) {
+			return
+		}
+
+		for {
+			change, err := source.Next(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					fmt.Println("Live updates stopped:", err)
+				}
+				return
+			}
+
+			message, ok, err := lc.liveMessage(ctx, subscription, change)
+			if err != nil {
+				fmt.Println("Failed to build live update:", err)
+				continue
+			}
+			if ok && !send(message) {
+				return
+			}
+		}
+	}()
+	return messages, nil
+}
+
+// liveMessage turns change into the message for subscription, or reports
+// false when the subscription does not match it.
+func (lc *LiveController) liveMessage(ctx context.Context, subscription liveSubscription, change live.Change) (models.LiveMessage, bool, error) {
+	message := models.LiveMessage{
+		ID:         change.Token,
+		Operation:  change.Operation,
+		DocumentID: &change.DocumentID,
+	}
+	showData := change.Operation != live.OperationDeleted || subscription.includeDeleted
+
+	switch change.Collection {
+	case "purchases":
+		message.Type = models.LiveTypePurchase
+		if change.Document == nil {
+			return message, subscription.unfiltered(), nil
+		}
+
+		var purchase models.Purchasev2
+		if err := bson.Unmarshal(change.Document, &purchase); err != nil {
+			return message, false, err
+		}
+		if !subscription.matchesPurchase(purchase) {
+			return message, false, nil
+		}
+		if showData {
+			purchaseResponse, err := lc.purchaseController.buildPurchaseResponse(ctx, purchase)
+			if err != nil && err != mongo.ErrNoDocuments {
+				return message, false, err
+			}
+			message.Data = purchaseResponse
+		}
+	case "items":
+		message.Type = models.LiveTypeItem
+		if change.Document == nil {
+			return message, subscription.unfiltered(), nil
+		}
+
+		var item models.Item
+		if err := bson.Unmarshal(change.Document, &item); err != nil {
+			return message, false, err
+		}
+		if subscription.providerID != nil && item.ProviderID != *subscription.providerID {
+			return message, false, nil
+		}
+		if showData {
+			itemResponse := models.ItemResponse{Item: item}
+			err := lc.providerCollection.FindOne(ctx, bson.M{"_id": item.ProviderID}).Decode(&itemResponse.Provider)
+			if err != nil && err != mongo.ErrNoDocuments {
+				return message, false, err
+			}
+			message.Data = itemResponse
+		}
+	default:
+		return message, false, nil
+	}
+	return message, true, nil
+}
+
+func newLiveSubscription(c *fiber.Ctx) (liveSubscription, error) {
+	subscription := liveSubscription{
+		includeDeleted: utils.IncludeDeleted(c),
+		lastEventID:    strings.Clone(c.Query("last_event_id")),
+	}
+
+	types := c.Query("types", "purchases,items")
+	for _, collection := range strings.Split(types, ",") {
+		switch collection = strings.TrimSpace(collection); collection {
+		case "purchases", "items":
+			subscription.collections = append(subscription.collections, collection)
+		case "":
+		default:
+			return subscription, fmt.Errorf("unknown type %q, expected purchases or items", collection)
+		}
+	}
+	if len(subscription.collections) == 0 {
+		return subscription, errors.New("types must name purchases, items or both")
+	}
+
+	for name, target := range map[string]**primitive.ObjectID{"provider": &subscription.providerID, "user": &subscription.userID} {
+		value := c.Query(name)
+		if value == "" {
+			continue
+		}
+		objID, err := primitive.ObjectIDFromHex(value)
+		if err != nil {
+			return subscription, fmt.Errorf("%s must be an ID", name)
+		}
+		*target = &objID
+	}
+
+	if statuses := c.Query("status"); statuses != "" {
+		subscription.statuses = map[string]bool{}
+		for _, status := range strings.Split(statuses, ",") {
+			subscription.statuses[strings.Clone(strings.TrimSpace(status))] = true
+		}
+	}
+	return subscription, nil
+}
+
+func (s liveSubscription) matchesPurchase(purchase models.Purchasev2) bool {
+	if s.providerID != nil && purchase.ProviderID != *s.providerID {
+		return false
+	}
+	if s.userID != nil && purchase.UserID != *s.userID {
+		return false
+	}
+	return s.statuses == nil || s.statuses[purchase.Status]
+}
+
+// unfiltered reports whether the subscription takes every change, which is
+// the only way to match a document that was purged and can't be checked.
+func (s liveSubscription) unfiltered() bool {
+	return s.providerID == nil && s.userID == nil && s.statuses == nil
+}
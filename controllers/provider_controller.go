@@ -3,7 +3,11 @@ package controllers
 import (
 	"context"
 
+	"github.com/aldoramirezmartinez/fiber-api/audit"
+	"github.com/aldoramirezmartinez/fiber-api/events"
+	"github.com/aldoramirezmartinez/fiber-api/integrity"
 	"github.com/aldoramirezmartinez/fiber-api/models"
+	"github.com/aldoramirezmartinez/fiber-api/utils"
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -11,19 +15,23 @@ import (
 )
 
 type ProviderController struct {
+	db         *mongo.Database
+	unitOfWork *utils.UnitOfWork
 	collection *mongo.Collection
 }
 
 func NewProviderController(db *mongo.Database) *ProviderController {
 	return &ProviderController{
+		db:         db,
+		unitOfWork: utils.NewUnitOfWork(db),
 		collection: db.Collection("providers"),
 	}
 }
 
 func (pc *ProviderController) GetAllProviders(c *fiber.Ctx) error {
-	ctx := context.TODO()
+	ctx := c.UserContext()
 
-	cursor, err := pc.collection.Find(ctx, bson.M{})
+	cursor, err := pc.collection.Find(ctx, utils.ScopeDeleted(c, bson.M{}))
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Failed to get providers",
@@ -44,7 +52,7 @@ func (pc *ProviderController) GetAllProviders(c *fiber.Ctx) error {
 }
 
 func (pc *ProviderController) GetProvider(c *fiber.Ctx) error {
-	ctx := context.TODO()
+	ctx := c.UserContext()
 
 	providerID := c.Params("id")
 
@@ -57,7 +65,7 @@ func (pc *ProviderController) GetProvider(c *fiber.Ctx) error {
 	}
 
 	var provider models.Provider
-	err = pc.collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&provider)
+	err = pc.collection.FindOne(ctx, utils.ScopeDeleted(c, bson.M{"_id": objID})).Decode(&provider)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -70,11 +78,16 @@ func (pc *ProviderController) GetProvider(c *fiber.Ctx) error {
 		})
 	}
 
+	utils.SetETag(c, provider.Version)
+	if utils.IfNoneMatch(c, provider.Version) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
 	return c.JSON(provider)
 }
 
 func (pc *ProviderController) CreateProvider(c *fiber.Ctx) error {
-	ctx := context.TODO()
+	ctx := c.UserContext()
 
 	provider := new(models.Provider)
 	if err := c.BodyParser(provider); err != nil {
@@ -84,28 +97,37 @@ func (pc *ProviderController) CreateProvider(c *fiber.Ctx) error {
 		})
 	}
 
-	result, err := pc.collection.InsertOne(ctx, provider)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Failed to create provider",
+	if err := provider.Validate(); err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"message": "Invalid provider",
 			"error":   err.Error(),
 		})
 	}
 
-	insertedID, ok := result.InsertedID.(primitive.ObjectID)
-	if !ok {
+	provider.ID = primitive.NewObjectID()
+	provider.Version = 1
+	provider.DeletedAt = nil
+	provider.DeletedBy = nil
+
+	err := pc.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		if _, err := pc.collection.InsertOne(ctx, provider); err != nil {
+			return err
+		}
+		return audit.Record(ctx, c, pc.db, models.AuditCreate, "providers", provider.ID, nil)
+	})
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Failed to create provider",
+			"error":   err.Error(),
 		})
 	}
 
-	provider.ID = insertedID
-
+	utils.SetETag(c, provider.Version)
 	return c.JSON(provider)
 }
 
 func (pc *ProviderController) UpdateProvider(c *fiber.Ctx) error {
-	ctx := context.TODO()
+	ctx := c.UserContext()
 
 	providerID := c.Params("id")
 
@@ -125,31 +147,63 @@ func (pc *ProviderController) UpdateProvider(c *fiber.Ctx) error {
 		})
 	}
 
+	if err := updateData.Validate(); err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"message": "Invalid provider",
+			"error":   err.Error(),
+		})
+	}
+
+	var existingProvider models.Provider
+	err = pc.collection.FindOne(ctx, utils.NotDeleted(bson.M{"_id": objID})).Decode(&existingProvider)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"message": "Provider not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to get provider",
+			"error":   err.Error(),
+		})
+	}
+
+	if !utils.IfMatch(c, existingProvider.Version) {
+		return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+			"message": "Provider has been modified",
+		})
+	}
+
 	updateData.ID = objID
+	updateData.Version = existingProvider.Version + 1
+	updateData.DeletedAt = nil
+	updateData.DeletedBy = nil
 
 	update := bson.M{
 		"$set": updateData,
 	}
 
-	result, err := pc.collection.UpdateOne(ctx, bson.M{"_id": objID}, update)
+	err = pc.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		return pc.updateProvider(ctx, c, existingProvider, update)
+	})
 	if err != nil {
+		if err == utils.ErrVersionConflict {
+			return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+				"message": "Provider has been modified",
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Failed to update provider",
 			"error":   err.Error(),
 		})
 	}
 
-	if result.MatchedCount == 0 {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"message": "Provider not found",
-		})
-	}
-
+	utils.SetETag(c, updateData.Version)
 	return c.JSON(updateData)
 }
 
 func (pc *ProviderController) DeleteProvider(c *fiber.Ctx) error {
-	ctx := context.TODO()
+	ctx := c.UserContext()
 
 	providerID := c.Params("id")
 
@@ -161,19 +215,240 @@ func (pc *ProviderController) DeleteProvider(c *fiber.Ctx) error {
 		})
 	}
 
-	result, err := pc.collection.DeleteOne(ctx, bson.M{"_id": objID})
+	var existingProvider models.Provider
+	err = pc.collection.FindOne(ctx, utils.NotDeleted(bson.M{"_id": objID})).Decode(&existingProvider)
 	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"message": "Provider not found",
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Failed to delete provider",
+			"message": "Failed to get provider",
 			"error":   err.Error(),
 		})
 	}
 
-	if result.DeletedCount == 0 {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"message": "Provider not found",
+	if !utils.IfMatch(c, existingProvider.Version) {
+		return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+			"message": "Provider has been modified",
+		})
+	}
+
+	err = pc.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		if err := integrity.BeforeDelete(ctx, pc.db, "providers", objID, utils.ActorID(c)); err != nil {
+			return err
+		}
+
+		result, err := pc.collection.UpdateOne(ctx, utils.VersionFilter(objID, existingProvider.Version), utils.SoftDeleteUpdate(utils.ActorID(c)))
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return utils.ErrVersionConflict
+		}
+		return audit.Record(ctx, c, pc.db, models.AuditDelete, "providers", objID, existingProvider)
+	})
+	if err != nil {
+		if restrictErr, ok := err.(*integrity.RestrictError); ok {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"message":    "Provider is still referenced",
+				"error":      restrictErr.Error(),
+				"references": restrictErr.References,
+			})
+		}
+		if err == utils.ErrVersionConflict {
+			return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+				"message": "Provider has been modified",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to delete provider",
+			"error":   err.Error(),
 		})
 	}
 
 	return c.SendStatus(fiber.StatusNoContent)
 }
+
+func (pc *ProviderController) PatchProvider(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	providerID := c.Params("id")
+
+	objID, err := primitive.ObjectIDFromHex(providerID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid provider ID",
+			"error":   err.Error(),
+		})
+	}
+
+	var existingProvider models.Provider
+	err = pc.collection.FindOne(ctx, utils.NotDeleted(bson.M{"_id": objID})).Decode(&existingProvider)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"message": "Provider not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to get provider",
+			"error":   err.Error(),
+		})
+	}
+
+	if !utils.IfMatch(c, existingProvider.Version) {
+		return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+			"message": "Provider has been modified",
+		})
+	}
+
+	patchedProvider := new(models.Provider)
+	if err := utils.ApplyPatch(c, existingProvider, patchedProvider); err != nil {
+		if err == utils.ErrUnsupportedPatch {
+			return c.Status(fiber.StatusUnsupportedMediaType).JSON(fiber.Map{
+				"message": "Unsupported patch format",
+				"error":   err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"message": "Failed to apply patch",
+			"error":   err.Error(),
+		})
+	}
+
+	patchedProvider.ID = objID
+	patchedProvider.Version = existingProvider.Version + 1
+	patchedProvider.DeletedAt = existingProvider.DeletedAt
+	patchedProvider.DeletedBy = existingProvider.DeletedBy
+
+	if err := patchedProvider.Validate(); err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"message": "Invalid provider",
+			"error":   err.Error(),
+		})
+	}
+
+	update, err := utils.PatchUpdate(existingProvider, patchedProvider)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to update provider",
+			"error":   err.Error(),
+		})
+	}
+
+	err = pc.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		return pc.updateProvider(ctx, c, existingProvider, update)
+	})
+	if err != nil {
+		if err == utils.ErrVersionConflict {
+			return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+				"message": "Provider has been modified",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to update provider",
+			"error":   err.Error(),
+		})
+	}
+
+	utils.SetETag(c, patchedProvider.Version)
+	return c.JSON(patchedProvider)
+}
+
+func (pc *ProviderController) RestoreProvider(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	providerID := c.Params("id")
+
+	objID, err := primitive.ObjectIDFromHex(providerID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid provider ID",
+			"error":   err.Error(),
+		})
+	}
+
+	var deletedProvider models.Provider
+	err = pc.collection.FindOne(ctx, utils.OnlyDeleted(bson.M{"_id": objID})).Decode(&deletedProvider)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"message": "Deleted provider not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to get provider",
+			"error":   err.Error(),
+		})
+	}
+
+	if !utils.IfMatch(c, deletedProvider.Version) {
+		return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+			"message": "Provider has been modified",
+		})
+	}
+
+	err = pc.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		if err := integrity.BeforeRestore(ctx, pc.db, "providers", objID); err != nil {
+			return err
+		}
+
+		result, err := pc.collection.UpdateOne(ctx, utils.VersionFilter(objID, deletedProvider.Version), utils.RestoreUpdate())
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return utils.ErrVersionConflict
+		}
+		return audit.Record(ctx, c, pc.db, models.AuditRestore, "providers", objID, deletedProvider)
+	})
+	if err != nil {
+		if parentErr, ok := err.(*integrity.DeletedParentError); ok {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"message": "Provider refers to deleted documents",
+				"error":   parentErr.Error(),
+				"parents": parentErr.Parents,
+			})
+		}
+		if err == utils.ErrVersionConflict {
+			return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+				"message": "Provider has been modified",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to restore provider",
+			"error":   err.Error(),
+		})
+	}
+
+	deletedProvider.DeletedAt = nil
+	deletedProvider.DeletedBy = nil
+	deletedProvider.Version++
+
+	utils.SetETag(c, deletedProvider.Version)
+	return c.JSON(deletedProvider)
+}
+
+// updateProvider applies update to existingProvider, records it in the audit
+// log and writes a ProviderUpdated event with the stored provider to the
+// outbox. Run it inside UnitOfWork.Do.
+func (pc *ProviderController) updateProvider(ctx context.Context, c *fiber.Ctx, existingProvider models.Provider, update interface{}) error {
+	result, err := pc.collection.UpdateOne(ctx, utils.VersionFilter(existingProvider.ID, existingProvider.Version), update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return utils.ErrVersionConflict
+	}
+	if err := audit.Record(ctx, c, pc.db, models.AuditUpdate, "providers", existingProvider.ID, existingProvider); err != nil {
+		return err
+	}
+
+	var provider models.Provider
+	if err := pc.collection.FindOne(ctx, bson.M{"_id": existingProvider.ID}).Decode(&provider); err != nil {
+		return err
+	}
+	return events.Emit(ctx, pc.db, models.EventTypeProviderUpdated, "provider", provider.ID, provider)
+}
@@ -0,0 +1,345 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aldoramirezmartinez/fiber-api/audit"
+	"github.com/aldoramirezmartinez/fiber-api/config"
+	"github.com/aldoramirezmartinez/fiber-api/exchange"
+	"github.com/aldoramirezmartinez/fiber-api/models"
+	"github.com/aldoramirezmartinez/fiber-api/sourcing"
+	"github.com/aldoramirezmartinez/fiber-api/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var errItemNotFound = errors.New("item not found")
+
+type ProviderPriceController struct {
+	db                 *mongo.Database
+	unitOfWork         *utils.UnitOfWork
+	collection         *mongo.Collection
+	itemCollection     *mongo.Collection
+	providerCollection *mongo.Collection
+}
+
+func NewProviderPriceController(db *mongo.Database) *ProviderPriceController {
+	if err := sourcing.EnsureIndexes(context.Background(), db); err != nil {
+		fmt.Println("Failed to create provider price indexes:", err)
+	}
+
+	return &ProviderPriceController{
+		db:                 db,
+		unitOfWork:         utils.NewUnitOfWork(db),
+		collection:         db.Collection(sourcing.CollectionName),
+		itemCollection:     db.Collection("items"),
+		providerCollection: db.Collection("providers"),
+	}
+}
+
+func (pc *ProviderPriceController) GetAllProviderPrices(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	filter := bson.M{}
+	for _, param := range []string{"item_id", "provider_id"} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		objID, err := primitive.ObjectIDFromHex(value)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"message": "Invalid " + param,
+				"error":   err.Error(),
+			})
+		}
+		filter[param] = objID
+	}
+
+	// valid_at returns only the offers valid on that date.
+	if value := c.Query("valid_at"); value != "" {
+		date, err := exchange.ParseDate(value)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"message": "Invalid valid_at date",
+				"error":   err.Error(),
+			})
+		}
+		filter = sourcing.ValidAt(filter, date)
+	}
+
+	sort := bson.D{{Key: "item_id", Value: 1}, {Key: "provider_id", Value: 1}, {Key: "min_quantity", Value: 1}}
+	cursor, err := pc.collection.Find(ctx, filter, options.Find().SetSort(sort))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to retrieve provider prices",
+			"error":   err.Error(),
+		})
+	}
+	defer cursor.Close(ctx)
+
+	prices := []models.ProviderPrice{}
+	if err := cursor.All(ctx, &prices); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to decode provider prices",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.JSON(prices)
+}
+
+func (pc *ProviderPriceController) GetProviderPrice(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	objID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid provider price ID",
+			"error":   err.Error(),
+		})
+	}
+
+	var price models.ProviderPrice
+	err = pc.collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&price)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"message": "Provider price not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to retrieve provider price",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.JSON(price)
+}
+
+func (pc *ProviderPriceController) CreateProviderPrice(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	request := new(models.ProviderPriceRequest)
+	if err := c.BodyParser(request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid request body",
+			"error":   err.Error(),
+		})
+	}
+
+	price, err := providerPriceFromRequest(request)
+	if err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"message": "Invalid provider price",
+			"error":   err.Error(),
+		})
+	}
+
+	price.ID = primitive.NewObjectID()
+	price.CreatedAt = time.Now()
+	price.UpdatedAt = price.CreatedAt
+
+	err = pc.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		if err := pc.checkParties(ctx, &price); err != nil {
+			return err
+		}
+		if _, err := pc.collection.InsertOne(ctx, price); err != nil {
+			return err
+		}
+		return audit.Record(ctx, c, pc.db, models.AuditCreate, sourcing.CollectionName, price.ID, nil)
+	})
+	switch err {
+	case nil:
+	case errItemNotFound:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Item not found",
+		})
+	case errProviderNotFound:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Provider not found",
+		})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to create provider price",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(price)
+}
+
+// UpdateProviderPrice replaces an offer. Purchase lines keep the price they
+// were snapshotted with.
+func (pc *ProviderPriceController) UpdateProviderPrice(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	objID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid provider price ID",
+			"error":   err.Error(),
+		})
+	}
+
+	var existingPrice models.ProviderPrice
+	err = pc.collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&existingPrice)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"message": "Provider price not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to retrieve provider price",
+			"error":   err.Error(),
+		})
+	}
+
+	request := new(models.ProviderPriceRequest)
+	if err := c.BodyParser(request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid request body",
+			"error":   err.Error(),
+		})
+	}
+
+	price, err := providerPriceFromRequest(request)
+	if err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"message": "Invalid provider price",
+			"error":   err.Error(),
+		})
+	}
+
+	price.ID = objID
+	price.CreatedAt = existingPrice.CreatedAt
+	price.UpdatedAt = time.Now()
+
+	err = pc.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		if err := pc.checkParties(ctx, &price); err != nil {
+			return err
+		}
+		if _, err := pc.collection.ReplaceOne(ctx, bson.M{"_id": objID}, price); err != nil {
+			return err
+		}
+		return audit.Record(ctx, c, pc.db, models.AuditUpdate, sourcing.CollectionName, objID, existingPrice)
+	})
+	switch err {
+	case nil:
+	case errItemNotFound:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Item not found",
+		})
+	case errProviderNotFound:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Provider not found",
+		})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to update provider price",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.JSON(price)
+}
+
+func (pc *ProviderPriceController) DeleteProviderPrice(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	objID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid provider price ID",
+			"error":   err.Error(),
+		})
+	}
+
+	err = pc.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		var existingPrice models.ProviderPrice
+		err := pc.collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&existingPrice)
+		if err != nil {
+			return err
+		}
+
+		if _, err := pc.collection.DeleteOne(ctx, bson.M{"_id": objID}); err != nil {
+			return err
+		}
+		return audit.Record(ctx, c, pc.db, models.AuditDelete, sourcing.CollectionName, objID, existingPrice)
+	})
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"message": "Provider price not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to delete provider price",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// providerPriceFromRequest turns request into an offer and validates it.
+func providerPriceFromRequest(request *models.ProviderPriceRequest) (models.ProviderPrice, error) {
+	price := models.ProviderPrice{
+		ItemID:       request.ItemID,
+		ProviderID:   request.ProviderID,
+		Price:        request.Price,
+		Currency:     request.Currency,
+		MinQuantity:  request.MinQuantity,
+		LeadTimeDays: request.LeadTimeDays,
+	}
+	for _, field := range []struct {
+		value  string
+		target **time.Time
+	}{
+		{request.ValidFrom, &price.ValidFrom},
+		{request.ValidTo, &price.ValidTo},
+	} {
+		if field.value == "" {
+			continue
+		}
+		date, err := exchange.ParseDate(field.value)
+		if err != nil {
+			return price, err
+		}
+		*field.target = &date
+	}
+	return price, price.Validate()
+}
+
+// checkParties checks that the item and provider of price exist and
+// defaults its currency to the provider's. Both are locked with
+// utils.LockDocument, so run it inside UnitOfWork.Do.
+func (pc *ProviderPriceController) checkParties(ctx context.Context, price *models.ProviderPrice) error {
+	exists, err := utils.LockDocument(ctx, pc.itemCollection, price.ItemID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errItemNotFound
+	}
+
+	var provider models.Provider
+	err = pc.providerCollection.FindOneAndUpdate(ctx, utils.NotDeleted(bson.M{"_id": price.ProviderID}), bson.M{
+		"$set": bson.M{"lock": primitive.NewObjectID()},
+	}).Decode(&provider)
+	if err == mongo.ErrNoDocuments {
+		return errProviderNotFound
+	}
+	if err != nil {
+		return err
+	}
+	price.Currency = price.Currency.Or(provider.Currency.Or(config.GetDefaultCurrency()))
+	return nil
+}
@@ -1,9 +1,9 @@
 package controllers
 
 import (
-	"context"
 	"time"
 
+	"github.com/aldoramirezmartinez/fiber-api/integrity"
 	"github.com/aldoramirezmartinez/fiber-api/models"
 	"github.com/aldoramirezmartinez/fiber-api/utils"
 	"github.com/gofiber/fiber/v2"
@@ -13,6 +13,7 @@ import (
 )
 
 type PurchaseController struct {
+	db                 *mongo.Database
 	purchaseCollection *mongo.Collection
 	userCollection     *mongo.Collection
 	providerCollection *mongo.Collection
@@ -20,6 +21,7 @@ type PurchaseController struct {
 
 func NewPurchaseController(db *mongo.Database) *PurchaseController {
 	return &PurchaseController{
+		db:                 db,
 		purchaseCollection: db.Collection("purchases"),
 		userCollection:     db.Collection("users"),
 		providerCollection: db.Collection("providers"),
@@ -27,9 +29,9 @@ func NewPurchaseController(db *mongo.Database) *PurchaseController {
 }
 
 func (pc *PurchaseController) GetAllPurchases(c *fiber.Ctx) error {
-	ctx := context.TODO()
+	ctx := c.UserContext()
 
-	cursor, err := pc.purchaseCollection.Find(ctx, bson.M{})
+	cursor, err := pc.purchaseCollection.Find(ctx, utils.ScopeDeleted(c, bson.M{}))
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Failed to get purchases",
@@ -78,7 +80,7 @@ func (pc *PurchaseController) GetAllPurchases(c *fiber.Ctx) error {
 }
 
 func (pc *PurchaseController) GetPurchase(c *fiber.Ctx) error {
-	ctx := context.TODO()
+	ctx := c.UserContext()
 
 	purchaseID := c.Params("id")
 
@@ -92,7 +94,7 @@ func (pc *PurchaseController) GetPurchase(c *fiber.Ctx) error {
 
 	var purchaseResponse models.PurchaseResponse
 
-	err = pc.purchaseCollection.FindOne(ctx, bson.M{"_id": objID}).Decode(&purchaseResponse.Purchase)
+	err = pc.purchaseCollection.FindOne(ctx, utils.ScopeDeleted(c, bson.M{"_id": objID})).Decode(&purchaseResponse.Purchase)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -135,7 +137,7 @@ func (pc *PurchaseController) GetPurchase(c *fiber.Ctx) error {
 }
 
 func (pc *PurchaseController) CreatePurchase(c *fiber.Ctx) error {
-	ctx := context.TODO()
+	ctx := c.UserContext()
 
 	purchase := new(models.Purchase)
 	if err := c.BodyParser(purchase); err != nil {
@@ -146,9 +148,11 @@ func (pc *PurchaseController) CreatePurchase(c *fiber.Ctx) error {
 	}
 
 	purchase.Date = time.Now()
+	purchase.DeletedAt = nil
+	purchase.DeletedBy = nil
 
 	userID := purchase.UserID
-	userExists, err := utils.CheckDocumentExists(pc.userCollection, userID)
+	userExists, err := utils.CheckDocumentExists(ctx, pc.userCollection, userID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Failed to check user existence",
@@ -162,7 +166,7 @@ func (pc *PurchaseController) CreatePurchase(c *fiber.Ctx) error {
 	}
 
 	providerID := purchase.ProviderID
-	providerExists, err := utils.CheckDocumentExists(pc.providerCollection, providerID)
+	providerExists, err := utils.CheckDocumentExists(ctx, pc.providerCollection, providerID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Failed to check provider existence",
@@ -221,7 +225,7 @@ func (pc *PurchaseController) CreatePurchase(c *fiber.Ctx) error {
 }
 
 func (pc *PurchaseController) UpdatePurchase(c *fiber.Ctx) error {
-	ctx := context.TODO()
+	ctx := c.UserContext()
 
 	purchaseID := c.Params("id")
 
@@ -242,7 +246,7 @@ func (pc *PurchaseController) UpdatePurchase(c *fiber.Ctx) error {
 	}
 
 	var existingPurchase models.Purchase
-	err = pc.purchaseCollection.FindOne(ctx, bson.M{"_id": objID}).Decode(&existingPurchase)
+	err = pc.purchaseCollection.FindOne(ctx, utils.NotDeleted(bson.M{"_id": objID})).Decode(&existingPurchase)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -259,7 +263,7 @@ func (pc *PurchaseController) UpdatePurchase(c *fiber.Ctx) error {
 
 	userID := purchaseToUpdate.UserID
 	if userID != existingPurchase.UserID {
-		userExists, err := utils.CheckDocumentExists(pc.userCollection, userID)
+		userExists, err := utils.CheckDocumentExists(ctx, pc.userCollection, userID)
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"message": "Failed to check user existence",
@@ -275,7 +279,7 @@ func (pc *PurchaseController) UpdatePurchase(c *fiber.Ctx) error {
 
 	providerID := purchaseToUpdate.ProviderID
 	if providerID != existingPurchase.ProviderID {
-		providerExists, err := utils.CheckDocumentExists(pc.providerCollection, providerID)
+		providerExists, err := utils.CheckDocumentExists(ctx, pc.providerCollection, providerID)
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"message": "Failed to check provider existence",
@@ -290,12 +294,14 @@ func (pc *PurchaseController) UpdatePurchase(c *fiber.Ctx) error {
 	}
 
 	purchaseToUpdate.ID = objID
+	purchaseToUpdate.DeletedAt = nil
+	purchaseToUpdate.DeletedBy = nil
 
 	update := bson.M{
 		"$set": purchaseToUpdate,
 	}
 
-	result, err := pc.purchaseCollection.UpdateOne(ctx, bson.M{"_id": objID}, update)
+	result, err := pc.purchaseCollection.UpdateOne(ctx, utils.NotDeleted(bson.M{"_id": objID}), update)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Failed to update purchase",
@@ -337,7 +343,7 @@ func (pc *PurchaseController) UpdatePurchase(c *fiber.Ctx) error {
 }
 
 func (pc *PurchaseController) DeletePurchase(c *fiber.Ctx) error {
-	ctx := context.TODO()
+	ctx := c.UserContext()
 
 	purchaseID := c.Params("id")
 
@@ -349,7 +355,22 @@ func (pc *PurchaseController) DeletePurchase(c *fiber.Ctx) error {
 		})
 	}
 
-	result, err := pc.purchaseCollection.DeleteOne(ctx, bson.M{"_id": objID})
+	err = integrity.BeforeDelete(ctx, pc.db, "purchases", objID, utils.ActorID(c))
+	if err != nil {
+		if restrictErr, ok := err.(*integrity.RestrictError); ok {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"message":    "Purchase is still referenced",
+				"error":      restrictErr.Error(),
+				"references": restrictErr.References,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to delete purchase",
+			"error":   err.Error(),
+		})
+	}
+
+	result, err := pc.purchaseCollection.UpdateOne(ctx, utils.NotDeleted(bson.M{"_id": objID}), utils.SoftDeleteUpdate(utils.ActorID(c)))
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Failed to delete purchase",
@@ -357,7 +378,7 @@ func (pc *PurchaseController) DeletePurchase(c *fiber.Ctx) error {
 		})
 	}
 
-	if result.DeletedCount == 0 {
+	if result.MatchedCount == 0 {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"message": "Purchase not found",
 		})
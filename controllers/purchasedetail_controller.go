@@ -1,9 +1,9 @@
 package controllers
 
 import (
-	"context"
-
+	"github.com/aldoramirezmartinez/fiber-api/config"
 	"github.com/aldoramirezmartinez/fiber-api/models"
+	"github.com/aldoramirezmartinez/fiber-api/pricing"
 	"github.com/aldoramirezmartinez/fiber-api/utils"
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson"
@@ -26,9 +26,9 @@ func NewPurchaseDetailController(db *mongo.Database) *PurchaseDetailController {
 }
 
 func (pdc *PurchaseDetailController) GetAllPurchaseDetails(c *fiber.Ctx) error {
-	ctx := context.TODO()
+	ctx := c.UserContext()
 
-	cursor, err := pdc.purchaseDetailCollection.Find(ctx, bson.M{})
+	cursor, err := pdc.purchaseDetailCollection.Find(ctx, utils.ScopeDeleted(c, bson.M{}))
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Failed to get purchase details",
@@ -77,7 +77,7 @@ func (pdc *PurchaseDetailController) GetAllPurchaseDetails(c *fiber.Ctx) error {
 }
 
 func (pdc *PurchaseDetailController) GetPurchaseDetail(c *fiber.Ctx) error {
-	ctx := context.TODO()
+	ctx := c.UserContext()
 
 	purchaseDetailID := c.Params("id")
 
@@ -91,7 +91,7 @@ func (pdc *PurchaseDetailController) GetPurchaseDetail(c *fiber.Ctx) error {
 
 	var purchaseDetailResponse models.PurchaseDetailResponse
 
-	err = pdc.purchaseDetailCollection.FindOne(ctx, bson.M{"_id": objID}).Decode(&purchaseDetailResponse.PurchaseDetail)
+	err = pdc.purchaseDetailCollection.FindOne(ctx, utils.ScopeDeleted(c, bson.M{"_id": objID})).Decode(&purchaseDetailResponse.PurchaseDetail)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -135,7 +135,7 @@ func (pdc *PurchaseDetailController) GetPurchaseDetail(c *fiber.Ctx) error {
 }
 
 func (pdc *PurchaseDetailController) CreatePurchaseDetail(c *fiber.Ctx) error {
-	ctx := context.TODO()
+	ctx := c.UserContext()
 
 	purchaseDetail := new(models.PurchaseDetail)
 	if err := c.BodyParser((purchaseDetail)); err != nil {
@@ -146,7 +146,7 @@ func (pdc *PurchaseDetailController) CreatePurchaseDetail(c *fiber.Ctx) error {
 	}
 
 	itemID := purchaseDetail.ItemID
-	itemExists, err := utils.CheckDocumentExists(pdc.itemCollection, itemID)
+	itemExists, err := utils.CheckDocumentExists(ctx, pdc.itemCollection, itemID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Failed to check item existence",
@@ -160,7 +160,7 @@ func (pdc *PurchaseDetailController) CreatePurchaseDetail(c *fiber.Ctx) error {
 	}
 
 	purchaseID := purchaseDetail.PurchaseID
-	purchaseExists, err := utils.CheckDocumentExists(pdc.purchaseCollection, purchaseID)
+	purchaseExists, err := utils.CheckDocumentExists(ctx, pdc.purchaseCollection, purchaseID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Failed to check purchase existence",
@@ -197,7 +197,9 @@ func (pdc *PurchaseDetailController) CreatePurchaseDetail(c *fiber.Ctx) error {
 		})
 	}
 
-	purchaseDetail.Total = float64(purchaseDetail.Quantity) * item.Price
+	purchaseDetail.Total = pricing.Gross(item.Price, purchaseDetail.Quantity, config.GetDefaultCurrency())
+	purchaseDetail.DeletedAt = nil
+	purchaseDetail.DeletedBy = nil
 
 	result, err := pdc.purchaseDetailCollection.InsertOne(ctx, purchaseDetail)
 	if err != nil {
@@ -227,7 +229,7 @@ func (pdc *PurchaseDetailController) CreatePurchaseDetail(c *fiber.Ctx) error {
 }
 
 func (pdc *PurchaseDetailController) UpdatePurchaseDetail(c *fiber.Ctx) error {
-	ctx := context.TODO()
+	ctx := c.UserContext()
 
 	purchaseDetailID := c.Params("id")
 
@@ -248,7 +250,7 @@ func (pdc *PurchaseDetailController) UpdatePurchaseDetail(c *fiber.Ctx) error {
 	}
 
 	var existingPurchaseDetail models.PurchaseDetail
-	err = pdc.purchaseDetailCollection.FindOne(ctx, bson.M{"_id": objID}).Decode(&existingPurchaseDetail)
+	err = pdc.purchaseDetailCollection.FindOne(ctx, utils.NotDeleted(bson.M{"_id": objID})).Decode(&existingPurchaseDetail)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -272,7 +274,7 @@ func (pdc *PurchaseDetailController) UpdatePurchaseDetail(c *fiber.Ctx) error {
 		})
 	}
 
-	existingPurchaseDetail.Total = float64(existingPurchaseDetail.Quantity) * item.Price
+	existingPurchaseDetail.Total = pricing.Gross(item.Price, existingPurchaseDetail.Quantity, config.GetDefaultCurrency())
 
 	update := bson.M{
 		"$set": bson.M{
@@ -281,7 +283,7 @@ func (pdc *PurchaseDetailController) UpdatePurchaseDetail(c *fiber.Ctx) error {
 		},
 	}
 
-	result, err := pdc.purchaseDetailCollection.UpdateOne(ctx, bson.M{"_id": objID}, update)
+	result, err := pdc.purchaseDetailCollection.UpdateOne(ctx, utils.NotDeleted(bson.M{"_id": objID}), update)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Failed to update purchase detail",
@@ -314,7 +316,7 @@ func (pdc *PurchaseDetailController) UpdatePurchaseDetail(c *fiber.Ctx) error {
 }
 
 func (pdc *PurchaseDetailController) DeletePurchaseDetail(c *fiber.Ctx) error {
-	ctx := context.TODO()
+	ctx := c.UserContext()
 
 	purchaseDetailID := c.Params("id")
 
@@ -326,7 +328,7 @@ func (pdc *PurchaseDetailController) DeletePurchaseDetail(c *fiber.Ctx) error {
 		})
 	}
 
-	result, err := pdc.purchaseDetailCollection.DeleteOne(ctx, bson.M{"_id": objID})
+	result, err := pdc.purchaseDetailCollection.UpdateOne(ctx, utils.NotDeleted(bson.M{"_id": objID}), utils.SoftDeleteUpdate(utils.ActorID(c)))
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Failed to delete purchase detail",
@@ -334,7 +336,7 @@ func (pdc *PurchaseDetailController) DeletePurchaseDetail(c *fiber.Ctx) error {
 		})
 	}
 
-	if result.DeletedCount == 0 {
+	if result.MatchedCount == 0 {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"message": "Purchase detail not found",
 		})
@@ -0,0 +1,192 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/aldoramirezmartinez/fiber-api/models"
+	"github.com/aldoramirezmartinez/fiber-api/revisions"
+	"github.com/aldoramirezmartinez/fiber-api/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type PurchaseRevisionController struct {
+	db                 *mongo.Database
+	purchaseCollection *mongo.Collection
+}
+
+func NewPurchaseRevisionController(db *mongo.Database) *PurchaseRevisionController {
+	if err := revisions.EnsureIndexes(context.Background(), db); err != nil {
+		fmt.Println("Failed to create purchase revision indexes:", err)
+	}
+
+	return &PurchaseRevisionController{
+		db:                 db,
+		purchaseCollection: db.Collection("purchases"),
+	}
+}
+
+// GetPurchaseRevisions lists every revision of a purchase, oldest first,
+// ending with the current one.
+func (rc *PurchaseRevisionController) GetPurchaseRevisions(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	purchase, err := rc.findPurchase(c)
+	if purchase == nil {
+		return err
+	}
+
+	archived, err := revisions.List(ctx, rc.db, purchase.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to retrieve purchase revisions",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.JSON(append(archived, currentRevision(*purchase)))
+}
+
+func (rc *PurchaseRevisionController) GetPurchaseRevision(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	purchase, err := rc.findPurchase(c)
+	if purchase == nil {
+		return err
+	}
+
+	number, err := strconv.Atoi(c.Params("revision"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid revision",
+			"error":   err.Error(),
+		})
+	}
+
+	revision, err := rc.revision(ctx, *purchase, number)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"message": "Revision not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to retrieve purchase revision",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.JSON(revision)
+}
+
+// DiffPurchaseRevisions lists what changed between revisions from and to of
+// a purchase. to defaults to the current revision and from to the one
+// before to.
+func (rc *PurchaseRevisionController) DiffPurchaseRevisions(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	purchase, err := rc.findPurchase(c)
+	if purchase == nil {
+		return err
+	}
+
+	to := revisions.Number(*purchase)
+	if value := c.Query("to"); value != "" {
+		if to, err = strconv.Atoi(value); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"message": "Invalid to revision",
+				"error":   err.Error(),
+			})
+		}
+	}
+	from := to - 1
+	if value := c.Query("from"); value != "" {
+		if from, err = strconv.Atoi(value); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"message": "Invalid from revision",
+				"error":   err.Error(),
+			})
+		}
+	}
+
+	diff := models.PurchaseRevisionDiff{PurchaseID: purchase.ID, From: from, To: to}
+	var versions [2]models.PurchaseRevision
+	for i, number := range []int{from, to} {
+		versions[i], err = rc.revision(ctx, *purchase, number)
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+					"message": "Revision not found",
+					"error":   fmt.Sprintf("purchase has no revision %d", number),
+				})
+			}
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"message": "Failed to retrieve purchase revision",
+				"error":   err.Error(),
+			})
+		}
+	}
+
+	diff.Changes, err = revisions.Diff(versions[0].Purchase, versions[1].Purchase)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to compare purchase revisions",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.JSON(diff)
+}
+
+// findPurchase loads the purchase named by the id parameter. It writes the
+// error response and returns a nil purchase when it cannot.
+func (rc *PurchaseRevisionController) findPurchase(c *fiber.Ctx) (*models.Purchasev2, error) {
+	objID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid purchase ID",
+			"error":   err.Error(),
+		})
+	}
+
+	var purchase models.Purchasev2
+	err = rc.purchaseCollection.FindOne(c.UserContext(), utils.ScopeDeleted(c, bson.M{"_id": objID})).Decode(&purchase)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"message": "Purchase not found",
+			})
+		}
+		return nil, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to get purchase",
+			"error":   err.Error(),
+		})
+	}
+	return &purchase, nil
+}
+
+// revision returns revision number of purchase, which is purchase itself
+// when it is the current one, or mongo.ErrNoDocuments.
+func (rc *PurchaseRevisionController) revision(ctx context.Context, purchase models.Purchasev2, number int) (models.PurchaseRevision, error) {
+	current := revisions.Number(purchase)
+	switch {
+	case number == current:
+		return currentRevision(purchase), nil
+	case number < 1 || number > current:
+		return models.PurchaseRevision{}, mongo.ErrNoDocuments
+	}
+	return revisions.Get(ctx, rc.db, purchase.ID, number)
+}
+
+func currentRevision(purchase models.Purchasev2) models.PurchaseRevision {
+	return models.PurchaseRevision{
+		PurchaseID: purchase.ID,
+		Revision:   revisions.Number(purchase),
+		Current:    true,
+		Purchase:   purchase,
+	}
+}
@@ -2,9 +2,22 @@ package controllers
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"time"
 
+	"github.com/aldoramirezmartinez/fiber-api/audit"
+	"github.com/aldoramirezmartinez/fiber-api/config"
+	"github.com/aldoramirezmartinez/fiber-api/events"
+	"github.com/aldoramirezmartinez/fiber-api/exchange"
+	"github.com/aldoramirezmartinez/fiber-api/integrity"
 	"github.com/aldoramirezmartinez/fiber-api/models"
+	"github.com/aldoramirezmartinez/fiber-api/money"
+	"github.com/aldoramirezmartinez/fiber-api/prices"
+	"github.com/aldoramirezmartinez/fiber-api/pricing"
+	"github.com/aldoramirezmartinez/fiber-api/revisions"
+	"github.com/aldoramirezmartinez/fiber-api/sourcing"
+	"github.com/aldoramirezmartinez/fiber-api/tax"
 	"github.com/aldoramirezmartinez/fiber-api/utils"
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson"
@@ -12,7 +25,14 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
+var (
+	errUserNotFound     = errors.New("user not found")
+	errProviderNotFound = errors.New("provider not found")
+)
+
 type PurchaseV2Controller struct {
+	db                 *mongo.Database
+	unitOfWork         *utils.UnitOfWork
 	purchaseCollection *mongo.Collection
 	userCollection     *mongo.Collection
 	providerCollection *mongo.Collection
@@ -21,6 +41,8 @@ type PurchaseV2Controller struct {
 
 func NewPurchaseV2Controller(db *mongo.Database) *PurchaseV2Controller {
 	return &PurchaseV2Controller{
+		db:                 db,
+		unitOfWork:         utils.NewUnitOfWork(db),
 		purchaseCollection: db.Collection("purchases"),
 		userCollection:     db.Collection("users"),
 		providerCollection: db.Collection("providers"),
@@ -29,10 +51,10 @@ func NewPurchaseV2Controller(db *mongo.Database) *PurchaseV2Controller {
 }
 
 func (pc *PurchaseV2Controller) GetAllPurchasesV2(c *fiber.Ctx) error {
-	ctx := context.TODO()
+	ctx := c.UserContext()
 
 	// Obtener todas las compras de la versión 2 desde la base de datos
-	cursor, err := pc.purchaseCollection.Find(ctx, bson.M{})
+	cursor, err := pc.purchaseCollection.Find(ctx, utils.ScopeDeleted(c, bson.M{}))
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Failed to retrieve purchases",
@@ -80,19 +102,13 @@ func (pc *PurchaseV2Controller) GetAllPurchasesV2(c *fiber.Ctx) error {
 			Provider: provider,
 		}
 
-		for i := range purchaseResponse.Purchase.ItemList {
-			itemID := purchaseResponse.Purchase.ItemList[i].ItemID
-
-			var item models.Item
-			err = pc.itemCollection.FindOne(ctx, bson.M{"_id": itemID}).Decode(&item)
-			if err != nil {
+		if includeCurrentItem(c) {
+			if err := pc.attachCurrentItems(ctx, &purchaseResponse.Purchase); err != nil {
 				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 					"message": "Failed to retrieve item data",
 					"error":   err.Error(),
 				})
 			}
-
-			purchaseResponse.Purchase.ItemList[i].Item = item
 		}
 
 		// Agregar la compra a la lista de compras
@@ -110,12 +126,12 @@ func (pc *PurchaseV2Controller) GetAllPurchasesV2(c *fiber.Ctx) error {
 }
 
 func (pc *PurchaseV2Controller) GetPurchaseV2(c *fiber.Ctx) error {
-	ctx := context.TODO()
+	ctx := c.UserContext()
 
 	purchaseOrder := c.Params("purchase_order")
 
 	var purchase models.Purchasev2
-	err := pc.purchaseCollection.FindOne(ctx, bson.M{"purchase_order": purchaseOrder}).Decode(&purchase)
+	err := pc.purchaseCollection.FindOne(ctx, utils.ScopeDeleted(c, bson.M{"purchase_order": purchaseOrder})).Decode(&purchase)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -154,26 +170,25 @@ func (pc *PurchaseV2Controller) GetPurchaseV2(c *fiber.Ctx) error {
 		Provider: provider,
 	}
 
-	for i := range purchaseResponse.Purchase.ItemList {
-		itemID := purchaseResponse.Purchase.ItemList[i].ItemID
-
-		var item models.Item
-		err = pc.itemCollection.FindOne(ctx, bson.M{"_id": itemID}).Decode(&item)
-		if err != nil {
+	if includeCurrentItem(c) {
+		if err := pc.attachCurrentItems(ctx, &purchaseResponse.Purchase); err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"message": "Failed to retrieve item data",
 				"error":   err.Error(),
 			})
 		}
+	}
 
-		purchaseResponse.Purchase.ItemList[i].Item = item
+	utils.SetETag(c, purchase.Version)
+	if utils.IfNoneMatch(c, purchase.Version) {
+		return c.SendStatus(fiber.StatusNotModified)
 	}
 
 	return c.JSON(purchaseResponse)
 }
 
 func (pc *PurchaseV2Controller) CreatePurchaseV2(c *fiber.Ctx) error {
-	ctx := context.TODO()
+	ctx := c.UserContext()
 
 	purchase := new(models.Purchasev2)
 	if err := c.BodyParser(purchase); err != nil {
@@ -183,78 +198,65 @@ func (pc *PurchaseV2Controller) CreatePurchaseV2(c *fiber.Ctx) error {
 		})
 	}
 
-	userExists, err := pc.userCollection.CountDocuments(ctx, bson.M{"_id": purchase.UserID})
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Failed to validate user",
+	if err := purchase.Validate(); err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"message": "Invalid purchase",
 			"error":   err.Error(),
 		})
 	}
-	if userExists == 0 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"message": "User not found",
-		})
-	}
 
-	providerExists, err := pc.providerCollection.CountDocuments(ctx, bson.M{"_id": purchase.ProviderID})
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Failed to validate provider",
-			"error":   err.Error(),
-		})
-	}
-	if providerExists == 0 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"message": "Provider not found",
+	// Las compras pueden registrarse con fecha pasada; sin fecha se usa la
+	// actual. Los precios se toman a esa fecha.
+	if now := time.Now(); purchase.Date.IsZero() {
+		purchase.Date = now
+	} else if purchase.Date.After(now) {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"message": "Invalid purchase",
+			"error":   "date must not be in the future",
 		})
 	}
 
-	for i := range purchase.ItemList {
-		itemExits, err := pc.itemCollection.CountDocuments(ctx, bson.M{"_id": purchase.ItemList[i].ItemID})
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"message": "Failed to validate item",
-				"error":   err.Error(),
+	// Asignar valores al objeto de compra
+	purchase.ID = primitive.NewObjectID()
+	purchase.RequisitionIDs = nil
+	purchase.Revision = 1
+	purchase.RevisionReason = ""
+	purchase.RevisedAt = nil
+	purchase.Version = 1
+	purchase.DeletedAt = nil
+	purchase.DeletedBy = nil
+
+	// Validar referencias y guardar la compra en una sola transacción
+	err := pc.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		return pc.insertPurchase(ctx, c, purchase, nil)
+	})
+	if err != nil {
+		var rateErr *exchange.RateNotFoundError
+		if errors.As(err, &rateErr) {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+				"message": "Missing exchange rate",
+				"error":   rateErr.Error(),
 			})
 		}
-		if itemExits == 0 {
+		switch err {
+		case pricing.ErrDiscountTooLarge, sourcing.ErrBelowMinimum:
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+				"message": "Invalid purchase",
+				"error":   err.Error(),
+			})
+		case errUserNotFound:
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"message": "Item not found",
+				"message": "User not found",
 			})
-		}
-
-		var item models.Item
-		err = pc.itemCollection.FindOne(ctx, bson.M{"_id": purchase.ItemList[i].ItemID}).Decode(&item)
-		if err != nil {
-			if err == mongo.ErrNoDocuments {
-				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-					"message": "Item not found",
-				})
-			}
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"message": "Failed to get item",
-				"error":   err.Error(),
+		case errProviderNotFound:
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"message": "Provider not found",
+			})
+		case mongo.ErrNoDocuments:
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"message": "Item not found",
 			})
 		}
-
-		subtotal := item.Price * float64(purchase.ItemList[i].Quantity)
-		purchase.ItemList[i].Subtotal = subtotal
-	}
-
-	// Calcular el total
-	var total float64
-	for _, detail := range purchase.ItemList {
-		total += detail.Subtotal
-	}
-
-	// Asignar valores al objeto de compra
-	purchase.ID = primitive.NewObjectID()
-	purchase.Date = time.Now()
-	purchase.Total = total
-
-	// Guardar la compra en la base de datos
-	_, err = pc.purchaseCollection.InsertOne(ctx, purchase)
-	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Failed to create purchase",
 			"error":   err.Error(),
@@ -287,27 +289,21 @@ func (pc *PurchaseV2Controller) CreatePurchaseV2(c *fiber.Ctx) error {
 		Provider: provider,
 	}
 
-	for i := range purchaseResponse.Purchase.ItemList {
-		itemID := purchaseResponse.Purchase.ItemList[i].ItemID
-
-		var item models.Item
-		err = pc.itemCollection.FindOne(ctx, bson.M{"_id": itemID}).Decode(&item)
-		if err != nil {
+	if includeCurrentItem(c) {
+		if err := pc.attachCurrentItems(ctx, &purchaseResponse.Purchase); err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"message": "Failed to retrieve item data",
 				"error":   err.Error(),
 			})
 		}
-
-		purchaseResponse.Purchase.ItemList[i].Item = item
-
 	}
 
+	utils.SetETag(c, purchase.Version)
 	return c.JSON(purchaseResponse)
 }
 
 func (pvc *PurchaseV2Controller) UpdatePurchaseV2(c *fiber.Ctx) error {
-	ctx := context.TODO()
+	ctx := c.UserContext()
 
 	purchaseID := c.Params("id")
 
@@ -328,7 +324,7 @@ func (pvc *PurchaseV2Controller) UpdatePurchaseV2(c *fiber.Ctx) error {
 	}
 
 	var existingPurchase models.Purchasev2
-	err = pvc.purchaseCollection.FindOne(ctx, bson.M{"_id": objID}).Decode(&existingPurchase)
+	err = pvc.purchaseCollection.FindOne(ctx, utils.NotDeleted(bson.M{"_id": objID})).Decode(&existingPurchase)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -341,42 +337,132 @@ func (pvc *PurchaseV2Controller) UpdatePurchaseV2(c *fiber.Ctx) error {
 		})
 	}
 
+	if !utils.IfMatch(c, existingPurchase.Version) {
+		return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+			"message": "Purchase has been modified",
+		})
+	}
+
+	purchaseToUpdate.ID = objID
 	purchaseToUpdate.Date = existingPurchase.Date
+	purchaseToUpdate.Version = existingPurchase.Version + 1
+	purchaseToUpdate.RequisitionIDs = existingPurchase.RequisitionIDs
+	purchaseToUpdate.DeletedAt = nil
+
+	// revision_reason solo describe la enmienda de esta petición; los demás
+	// campos de la revisión los pone revise.
+	reason := purchaseToUpdate.RevisionReason
+	purchaseToUpdate.Revision = existingPurchase.Revision
+	purchaseToUpdate.RevisionReason = existingPurchase.RevisionReason
+	purchaseToUpdate.RevisedAt = existingPurchase.RevisedAt
+	purchaseToUpdate.DeletedBy = nil
+
+	// PUT conserva la orden, las líneas, el descuento, los cargos y la
+	// moneda que se omitan; una lista de cargos vacía los quita. La compra
+	// siempre se vuelve a calcular, así que se ignoran los totales enviados.
+	if strings.TrimSpace(purchaseToUpdate.PurchaseOrder) == "" {
+		purchaseToUpdate.PurchaseOrder = existingPurchase.PurchaseOrder
+	}
+	if len(purchaseToUpdate.ItemList) == 0 {
+		purchaseToUpdate.ItemList = existingPurchase.ItemList
+	}
+	if purchaseToUpdate.Discount == nil {
+		purchaseToUpdate.Discount = existingPurchase.Discount
+	}
+	if purchaseToUpdate.Charges == nil {
+		purchaseToUpdate.Charges = existingPurchase.Charges
+	}
+	purchaseToUpdate.Currency = purchaseToUpdate.Currency.Or(existingPurchase.Currency)
+
+	if err := purchaseToUpdate.Validate(); err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"message": "Invalid purchase",
+			"error":   err.Error(),
+		})
+	}
 
 	userID := purchaseToUpdate.UserID
-	if userID != existingPurchase.UserID {
-		userExists, err := utils.CheckDocumentExists(pvc.userCollection, userID)
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"message": "Failed to check user existence",
-				"error":   err.Error(),
-			})
+	providerID := purchaseToUpdate.ProviderID
+
+	var revised *models.Purchasev2
+	err = pvc.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		if userID != existingPurchase.UserID {
+			userExists, err := utils.LockDocument(ctx, pvc.userCollection, userID)
+			if err != nil {
+				return err
+			}
+			if !userExists {
+				return errUserNotFound
+			}
 		}
-		if !userExists {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"message": "User does not exist",
-			})
+
+		if providerID != existingPurchase.ProviderID {
+			providerExists, err := utils.LockDocument(ctx, pvc.providerCollection, providerID)
+			if err != nil {
+				return err
+			}
+			if !providerExists {
+				return errProviderNotFound
+			}
 		}
-	}
 
-	providerID := purchaseToUpdate.ProviderID
-	if providerID != existingPurchase.ProviderID {
-		providerExists, err := utils.CheckDocumentExists(pvc.providerCollection, providerID)
+		if err := pvc.pricePurchase(ctx, purchaseToUpdate, &existingPurchase); err != nil {
+			return err
+		}
+
+		result, err := pvc.purchaseCollection.UpdateOne(ctx, utils.VersionFilter(objID, existingPurchase.Version), bson.M{"$set": purchaseToUpdate})
 		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"message": "Failed to check provider existence",
-				"error":   err.Error(),
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return utils.ErrVersionConflict
+		}
+		revised, err = pvc.revise(ctx, c, existingPurchase, reason)
+		if err != nil {
+			return err
+		}
+		if err := audit.Record(ctx, c, pvc.db, models.AuditUpdate, "purchases", objID, existingPurchase); err != nil {
+			return err
+		}
+
+		// PUT conserva el estado si se omite.
+		status := purchaseToUpdate.Status
+		if status == "" {
+			status = existingPurchase.Status
+		}
+		return pvc.emitStatusChange(ctx, existingPurchase.Status, status, objID)
+	})
+	if err != nil {
+		var rateErr *exchange.RateNotFoundError
+		if errors.As(err, &rateErr) {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+				"message": "Missing exchange rate",
+				"error":   rateErr.Error(),
 			})
 		}
-		if !providerExists {
+		switch err {
+		case pricing.ErrDiscountTooLarge, sourcing.ErrBelowMinimum:
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+				"message": "Invalid purchase",
+				"error":   err.Error(),
+			})
+		case errUserNotFound:
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"message": "User does not exist",
+			})
+		case errProviderNotFound:
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 				"message": "Provider does not exist",
 			})
+		case mongo.ErrNoDocuments:
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"message": "Item not found",
+			})
+		case utils.ErrVersionConflict:
+			return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+				"message": "Purchase has been modified",
+			})
 		}
-	}
-
-	_, err = pvc.purchaseCollection.UpdateOne(ctx, bson.M{"_id": objID}, bson.M{"$set": purchaseToUpdate})
-	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Failed to update purchase",
 			"error":   err.Error(),
@@ -401,11 +487,696 @@ func (pvc *PurchaseV2Controller) UpdatePurchaseV2(c *fiber.Ctx) error {
 		})
 	}
 
+	if revised != nil {
+		purchaseToUpdate.Revision = revised.Revision
+		purchaseToUpdate.RevisionReason = revised.RevisionReason
+		purchaseToUpdate.RevisedAt = revised.RevisedAt
+	}
+
 	purchaseResponse := models.PurchaseResponsev2{
 		Purchase: *purchaseToUpdate,
 		User:     user,
 		Provider: provider,
 	}
+	if includeCurrentItem(c) {
+		if err := pvc.attachCurrentItems(ctx, &purchaseResponse.Purchase); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"message": "Failed to retrieve item data",
+				"error":   err.Error(),
+			})
+		}
+	}
+
+	utils.SetETag(c, purchaseToUpdate.Version)
+	return c.JSON(purchaseResponse)
+}
+
+func (pc *PurchaseV2Controller) PatchPurchaseV2(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	purchaseID := c.Params("id")
+
+	objID, err := primitive.ObjectIDFromHex(purchaseID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid purchase ID",
+			"error":   err.Error(),
+		})
+	}
+
+	var existingPurchase models.Purchasev2
+	err = pc.purchaseCollection.FindOne(ctx, utils.NotDeleted(bson.M{"_id": objID})).Decode(&existingPurchase)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"message": "Purchase not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to get purchase",
+			"error":   err.Error(),
+		})
+	}
+
+	if !utils.IfMatch(c, existingPurchase.Version) {
+		return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+			"message": "Purchase has been modified",
+		})
+	}
+
+	patchedPurchase := new(models.Purchasev2)
+	if err := utils.ApplyPatch(c, existingPurchase, patchedPurchase); err != nil {
+		if err == utils.ErrUnsupportedPatch {
+			return c.Status(fiber.StatusUnsupportedMediaType).JSON(fiber.Map{
+				"message": "Unsupported patch format",
+				"error":   err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"message": "Failed to apply patch",
+			"error":   err.Error(),
+		})
+	}
+
+	patchedPurchase.ID = objID
+	patchedPurchase.Date = existingPurchase.Date
+	patchedPurchase.UserID = existingPurchase.UserID
+	patchedPurchase.ProviderID = existingPurchase.ProviderID
+	patchedPurchase.RequisitionIDs = existingPurchase.RequisitionIDs
+	patchedPurchase.Version = existingPurchase.Version + 1
+	patchedPurchase.DeletedAt = existingPurchase.DeletedAt
+	patchedPurchase.DeletedBy = existingPurchase.DeletedBy
+
+	// revision_reason solo describe la enmienda de esta petición; los demás
+	// campos de la revisión los pone revise.
+	var reason string
+	if patchedPurchase.RevisionReason != existingPurchase.RevisionReason {
+		reason = patchedPurchase.RevisionReason
+	}
+	patchedPurchase.Revision = existingPurchase.Revision
+	patchedPurchase.RevisionReason = existingPurchase.RevisionReason
+	patchedPurchase.RevisedAt = existingPurchase.RevisedAt
+
+	if err := patchedPurchase.Validate(); err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"message": "Invalid purchase",
+			"error":   err.Error(),
+		})
+	}
+
+	var revised *models.Purchasev2
+	err = pc.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		if err := pc.pricePurchase(ctx, patchedPurchase, &existingPurchase); err != nil {
+			return err
+		}
+
+		update, err := utils.PatchUpdate(existingPurchase, patchedPurchase)
+		if err != nil {
+			return err
+		}
+
+		result, err := pc.purchaseCollection.UpdateOne(ctx, utils.VersionFilter(objID, existingPurchase.Version), update)
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return utils.ErrVersionConflict
+		}
+		revised, err = pc.revise(ctx, c, existingPurchase, reason)
+		if err != nil {
+			return err
+		}
+		if err := audit.Record(ctx, c, pc.db, models.AuditUpdate, "purchases", objID, existingPurchase); err != nil {
+			return err
+		}
+		return pc.emitStatusChange(ctx, existingPurchase.Status, patchedPurchase.Status, objID)
+	})
+	if err != nil {
+		var rateErr *exchange.RateNotFoundError
+		if errors.As(err, &rateErr) {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+				"message": "Missing exchange rate",
+				"error":   rateErr.Error(),
+			})
+		}
+		switch err {
+		case pricing.ErrDiscountTooLarge, sourcing.ErrBelowMinimum:
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+				"message": "Invalid purchase",
+				"error":   err.Error(),
+			})
+		case mongo.ErrNoDocuments:
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+				"message": "Invalid purchase",
+				"error":   "item not found",
+			})
+		case utils.ErrVersionConflict:
+			return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+				"message": "Purchase has been modified",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to update purchase",
+			"error":   err.Error(),
+		})
+	}
+
+	if revised != nil {
+		patchedPurchase.Revision = revised.Revision
+		patchedPurchase.RevisionReason = revised.RevisionReason
+		patchedPurchase.RevisedAt = revised.RevisedAt
+	}
 
+	purchaseResponse, err := pc.buildPurchaseResponse(ctx, *patchedPurchase)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to retrieve purchase data",
+			"error":   err.Error(),
+		})
+	}
+	if includeCurrentItem(c) {
+		if err := pc.attachCurrentItems(ctx, &purchaseResponse.Purchase); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"message": "Failed to retrieve item data",
+				"error":   err.Error(),
+			})
+		}
+	}
+
+	utils.SetETag(c, patchedPurchase.Version)
 	return c.JSON(purchaseResponse)
 }
+
+func (pc *PurchaseV2Controller) DeletePurchaseV2(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	purchaseID := c.Params("id")
+
+	objID, err := primitive.ObjectIDFromHex(purchaseID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid purchase ID",
+			"error":   err.Error(),
+		})
+	}
+
+	var existingPurchase models.Purchasev2
+	err = pc.purchaseCollection.FindOne(ctx, utils.NotDeleted(bson.M{"_id": objID})).Decode(&existingPurchase)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"message": "Purchase not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to get purchase",
+			"error":   err.Error(),
+		})
+	}
+
+	if !utils.IfMatch(c, existingPurchase.Version) {
+		return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+			"message": "Purchase has been modified",
+		})
+	}
+
+	err = pc.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		if err := integrity.BeforeDelete(ctx, pc.db, "purchases", objID, utils.ActorID(c)); err != nil {
+			return err
+		}
+
+		result, err := pc.purchaseCollection.UpdateOne(ctx, utils.VersionFilter(objID, existingPurchase.Version), utils.SoftDeleteUpdate(utils.ActorID(c)))
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return utils.ErrVersionConflict
+		}
+		return audit.Record(ctx, c, pc.db, models.AuditDelete, "purchases", objID, existingPurchase)
+	})
+	if err != nil {
+		if restrictErr, ok := err.(*integrity.RestrictError); ok {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"message":    "Purchase is still referenced",
+				"error":      restrictErr.Error(),
+				"references": restrictErr.References,
+			})
+		}
+		if err == utils.ErrVersionConflict {
+			return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+				"message": "Purchase has been modified",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to delete purchase",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (pc *PurchaseV2Controller) RestorePurchaseV2(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	purchaseID := c.Params("id")
+
+	objID, err := primitive.ObjectIDFromHex(purchaseID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid purchase ID",
+			"error":   err.Error(),
+		})
+	}
+
+	var deletedPurchase models.Purchasev2
+	err = pc.purchaseCollection.FindOne(ctx, utils.OnlyDeleted(bson.M{"_id": objID})).Decode(&deletedPurchase)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"message": "Deleted purchase not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to get purchase",
+			"error":   err.Error(),
+		})
+	}
+
+	if !utils.IfMatch(c, deletedPurchase.Version) {
+		return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+			"message": "Purchase has been modified",
+		})
+	}
+
+	err = pc.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		if err := integrity.BeforeRestore(ctx, pc.db, "purchases", objID); err != nil {
+			return err
+		}
+
+		result, err := pc.purchaseCollection.UpdateOne(ctx, utils.VersionFilter(objID, deletedPurchase.Version), utils.RestoreUpdate())
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return utils.ErrVersionConflict
+		}
+		if err := revisions.Restore(ctx, pc.db, objID); err != nil {
+			return err
+		}
+		return audit.Record(ctx, c, pc.db, models.AuditRestore, "purchases", objID, deletedPurchase)
+	})
+	if err != nil {
+		if parentErr, ok := err.(*integrity.DeletedParentError); ok {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"message": "Purchase refers to deleted documents",
+				"error":   parentErr.Error(),
+				"parents": parentErr.Parents,
+			})
+		}
+		if err == utils.ErrVersionConflict {
+			return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+				"message": "Purchase has been modified",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to restore purchase",
+			"error":   err.Error(),
+		})
+	}
+
+	deletedPurchase.DeletedAt = nil
+	deletedPurchase.DeletedBy = nil
+	deletedPurchase.Version++
+
+	purchaseResponse, err := pc.buildPurchaseResponse(ctx, deletedPurchase)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to retrieve purchase data",
+			"error":   err.Error(),
+		})
+	}
+	if includeCurrentItem(c) {
+		if err := pc.attachCurrentItems(ctx, &purchaseResponse.Purchase); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"message": "Failed to retrieve item data",
+				"error":   err.Error(),
+			})
+		}
+	}
+
+	utils.SetETag(c, deletedPurchase.Version)
+	return c.JSON(purchaseResponse)
+}
+
+// insertPurchase valida el usuario y el proveedor, calcula la compra y la
+// guarda con su auditoría y su evento PurchaseCreated, dentro de
+// UnitOfWork.Do.
+func (pc *PurchaseV2Controller) insertPurchase(ctx context.Context, c *fiber.Ctx, purchase *models.Purchasev2, previous *models.Purchasev2) error {
+	if err := pc.lockParties(ctx, purchase.UserID, purchase.ProviderID); err != nil {
+		return err
+	}
+
+	if err := pc.pricePurchase(ctx, purchase, previous); err != nil {
+		return err
+	}
+
+	_, err := pc.purchaseCollection.InsertOne(ctx, purchase)
+	if err != nil {
+		return err
+	}
+	if err := audit.Record(ctx, c, pc.db, models.AuditCreate, "purchases", purchase.ID, nil); err != nil {
+		return err
+	}
+	return pc.emitPurchaseCreated(ctx, purchase.ID)
+}
+
+// lockParties bloquea el usuario y el proveedor de la compra; devuelve
+// errUserNotFound o errProviderNotFound si falta alguno.
+func (pc *PurchaseV2Controller) lockParties(ctx context.Context, userID primitive.ObjectID, providerID primitive.ObjectID) error {
+	userExists, err := utils.LockDocument(ctx, pc.userCollection, userID)
+	if err != nil {
+		return err
+	}
+	if !userExists {
+		return errUserNotFound
+	}
+
+	providerExists, err := utils.LockDocument(ctx, pc.providerCollection, providerID)
+	if err != nil {
+		return err
+	}
+	if !providerExists {
+		return errProviderNotFound
+	}
+
+	return nil
+}
+
+// pricePurchase calcula los importes de la compra en su moneda, que por
+// defecto es la del proveedor, y su total en la moneda base.
+func (pc *PurchaseV2Controller) pricePurchase(ctx context.Context, purchase *models.Purchasev2, previous *models.Purchasev2) error {
+	var provider models.Provider
+	err := pc.providerCollection.FindOne(ctx, bson.M{"_id": purchase.ProviderID}).Decode(&provider)
+	if err == mongo.ErrNoDocuments {
+		return errProviderNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	purchase.Currency = purchase.Currency.Or(provider.Currency.Or(config.GetDefaultCurrency()))
+
+	// Al cambiar de proveedor los precios e impuestos se toman de nuevo.
+	var kept []models.PurchaseDetailv2
+	if previous != nil && previous.ProviderID == purchase.ProviderID {
+		kept = previous.ItemList
+	}
+
+	if err := pc.priceLines(ctx, purchase, kept, provider); err != nil {
+		return err
+	}
+	taxes, err := pc.lineTaxes(ctx, purchase.Currency, kept, provider)
+	if err != nil {
+		return err
+	}
+	if err := pricing.Price(purchase, purchase.Currency, taxes); err != nil {
+		return err
+	}
+	return pc.convertTotal(ctx, purchase, previous)
+}
+
+// priceLines pone a cada línea su foto del artículo y su precio unitario en
+// la moneda de la compra, al tipo de cambio de la fecha de la compra. Las
+// líneas conservan la foto de kept mientras no cambie la cantidad, salvo
+// las de cotizaciones adjudicadas, que la conservan siempre.
+func (pc *PurchaseV2Controller) priceLines(ctx context.Context, purchase *models.Purchasev2, kept []models.PurchaseDetailv2, provider models.Provider) error {
+	snapshots := map[primitive.ObjectID]*models.ItemSnapshot{}
+	quantities := map[primitive.ObjectID]int{}
+	for _, line := range kept {
+		if _, ok := snapshots[line.ItemID]; !ok && line.Snapshot != nil {
+			snapshots[line.ItemID] = line.Snapshot
+			quantities[line.ItemID] = line.Quantity
+		}
+	}
+
+	for i := range purchase.ItemList {
+		line := &purchase.ItemList[i]
+
+		line.CurrentItem = nil
+		line.Snapshot = snapshots[line.ItemID]
+		if line.Snapshot != nil && line.Snapshot.QuoteID == nil && line.Quantity != quantities[line.ItemID] {
+			line.Snapshot = nil
+		}
+		if line.Snapshot == nil {
+			snapshot, err := pc.snapshotItem(ctx, line.ItemID, provider, line.Quantity, purchase.Currency, purchase.Date)
+			if err != nil {
+				return err
+			}
+			line.Snapshot = snapshot
+		}
+		if line.Quantity < line.Snapshot.MinQuantity {
+			return sourcing.ErrBelowMinimum
+		}
+
+		unitPrice, err := exchange.Convert(ctx, pc.db, line.Snapshot.Price, line.Snapshot.Currency, purchase.Currency, purchase.Date)
+		if err != nil {
+			return err
+		}
+		line.UnitPrice = unitPrice
+	}
+	return nil
+}
+
+// lineTaxes devuelve cómo se calculan los impuestos de cada línea: con las
+// tasas que ya tenía en kept o, si es nueva, con las reglas vigentes.
+func (pc *PurchaseV2Controller) lineTaxes(ctx context.Context, currency money.Currency, kept []models.PurchaseDetailv2, provider models.Provider) (pricing.TaxFunc, error) {
+	rules, err := tax.Load(ctx, pc.db, config.GetDefaultVATRate())
+	if err != nil {
+		return nil, err
+	}
+
+	applied := map[primitive.ObjectID]*models.LineTax{}
+	for _, line := range kept {
+		if _, ok := applied[line.ItemID]; !ok && line.Tax != nil {
+			applied[line.ItemID] = line.Tax
+		}
+	}
+
+	return func(line models.PurchaseDetailv2, taxable money.Amount) *models.LineTax {
+		if lineTax := applied[line.ItemID]; lineTax != nil {
+			return tax.Reapply(*lineTax, taxable, currency)
+		}
+		return rules.Line(taxable, line.Snapshot.Category, provider, currency)
+	}, nil
+}
+
+// convertTotal pasa el total a la moneda base. El tipo de cambio se toma al
+// crear la compra o cambiar su moneda, y si no se conserva el de previous.
+func (pc *PurchaseV2Controller) convertTotal(ctx context.Context, purchase *models.Purchasev2, previous *models.Purchasev2) error {
+	if previous != nil && previous.BaseCurrency != "" && previous.Currency.Or(config.GetDefaultCurrency()) == purchase.Currency {
+		purchase.BaseCurrency = previous.BaseCurrency
+		purchase.ExchangeRate = previous.ExchangeRate
+	} else {
+		purchase.BaseCurrency = config.GetBaseCurrency()
+		rate, err := exchange.Rate(ctx, pc.db, purchase.Currency, purchase.BaseCurrency, purchase.Date)
+		if err != nil {
+			return err
+		}
+		purchase.ExchangeRate = rate
+	}
+	purchase.BaseTotal = purchase.BaseCurrency.Round(purchase.Total.Mul(purchase.ExchangeRate), money.HalfUp)
+	return nil
+}
+
+// snapshotItem toma una foto del artículo y lo bloquea. El precio es el de
+// la oferta más barata del proveedor para la cantidad o, sin ofertas, el
+// precio del artículo en la fecha at.
+func (pc *PurchaseV2Controller) snapshotItem(ctx context.Context, itemID primitive.ObjectID, provider models.Provider, quantity int, currency money.Currency, at time.Time) (*models.ItemSnapshot, error) {
+	var item models.Item
+	err := pc.itemCollection.FindOneAndUpdate(ctx, utils.NotDeleted(bson.M{"_id": itemID}), bson.M{
+		"$set": bson.M{"lock": primitive.NewObjectID()},
+	}).Decode(&item)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &models.ItemSnapshot{
+		Name:        item.Name,
+		Code:        item.Code,
+		UnitMeasure: item.UnitMeasure,
+		Category:    item.Category,
+		TakenAt:     time.Now(),
+	}
+
+	options, err := sourcing.Options(ctx, pc.db, item.ID, provider.ID, quantity, currency, at)
+	if err != nil {
+		return nil, err
+	}
+	if len(options) > 0 {
+		offer := options[0].Offer
+		snapshot.Price = offer.Price
+		snapshot.Currency = offer.Currency
+		snapshot.ProviderID = provider.ID
+		snapshot.ProviderName = provider.Name
+		snapshot.ProviderPriceID = &offer.ID
+		snapshot.MinQuantity = offer.MinQuantity
+		snapshot.LeadTimeDays = offer.LeadTimeDays
+		return snapshot, nil
+	}
+
+	snapshot.Price, err = prices.At(ctx, pc.db, item, at)
+	if err != nil {
+		return nil, err
+	}
+	snapshot.Currency = item.Currency.Or(config.GetDefaultCurrency())
+	snapshot.ProviderID = item.ProviderID
+
+	if item.ProviderID == provider.ID {
+		snapshot.ProviderName = provider.Name
+	} else if !item.ProviderID.IsZero() {
+		var itemProvider models.Provider
+		err := pc.providerCollection.FindOne(ctx, bson.M{"_id": item.ProviderID}).Decode(&itemProvider)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return nil, err
+		}
+		snapshot.ProviderName = itemProvider.Name
+	}
+	return snapshot, nil
+}
+
+// revise abre una nueva revisión si la escritura enmendó una compra ya
+// emitida: archiva previous, numera la revisión y emite PurchaseAmended.
+// Devuelve la compra guardada, o nil si no hubo enmienda. Se llama dentro
+// de UnitOfWork.Do, tras escribir y antes de auditar.
+func (pc *PurchaseV2Controller) revise(ctx context.Context, c *fiber.Ctx, previous models.Purchasev2, reason string) (*models.Purchasev2, error) {
+	var purchase models.Purchasev2
+	if err := pc.purchaseCollection.FindOne(ctx, bson.M{"_id": previous.ID}).Decode(&purchase); err != nil {
+		return nil, err
+	}
+
+	changes, err := revisions.Diff(previous, purchase)
+	if err != nil {
+		return nil, err
+	}
+	if !revisions.Amends(previous, changes) {
+		return nil, nil
+	}
+
+	if err := revisions.Archive(ctx, pc.db, previous, utils.ActorID(c)); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	purchase.Revision = revisions.Number(previous) + 1
+	purchase.RevisionReason = strings.TrimSpace(reason)
+	purchase.RevisedAt = &now
+
+	update := bson.M{"$set": bson.M{"revision": purchase.Revision, "revised_at": now}}
+	if purchase.RevisionReason != "" {
+		update["$set"].(bson.M)["revision_reason"] = purchase.RevisionReason
+	} else {
+		update["$unset"] = bson.M{"revision_reason": ""}
+	}
+	if _, err := pc.purchaseCollection.UpdateOne(ctx, bson.M{"_id": purchase.ID}, update); err != nil {
+		return nil, err
+	}
+
+	purchaseResponse, err := pc.buildPurchaseResponse(ctx, purchase)
+	if err != nil {
+		return nil, err
+	}
+	err = events.Emit(ctx, pc.db, models.EventTypePurchaseAmended, "purchase", purchase.ID, models.PurchaseAmendment{
+		PreviousRevision: revisions.Number(previous),
+		Revision:         purchase.Revision,
+		Reason:           purchase.RevisionReason,
+		Changes:          changes,
+		Purchase:         purchaseResponse,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &purchase, nil
+}
+
+// emitPurchaseCreated emite PurchaseCreated con la compra como la devuelve
+// la API.
+func (pc *PurchaseV2Controller) emitPurchaseCreated(ctx context.Context, purchaseID primitive.ObjectID) error {
+	purchaseResponse, err := pc.loadPurchaseResponse(ctx, purchaseID)
+	if err != nil {
+		return err
+	}
+	return events.Emit(ctx, pc.db, models.EventTypePurchaseCreated, "purchase", purchaseID, purchaseResponse)
+}
+
+// emitStatusChange emite PurchaseStatusChanged si la compra cambió de
+// estado.
+func (pc *PurchaseV2Controller) emitStatusChange(ctx context.Context, previous string, status string, purchaseID primitive.ObjectID) error {
+	if status == previous {
+		return nil
+	}
+
+	purchaseResponse, err := pc.loadPurchaseResponse(ctx, purchaseID)
+	if err != nil {
+		return err
+	}
+	return events.Emit(ctx, pc.db, models.EventTypePurchaseStatusChanged, "purchase", purchaseID, models.PurchaseStatusChange{
+		PreviousStatus: previous,
+		Status:         status,
+		Purchase:       purchaseResponse,
+	})
+}
+
+func (pc *PurchaseV2Controller) loadPurchaseResponse(ctx context.Context, purchaseID primitive.ObjectID) (models.PurchaseResponsev2, error) {
+	var purchase models.Purchasev2
+	if err := pc.purchaseCollection.FindOne(ctx, bson.M{"_id": purchaseID}).Decode(&purchase); err != nil {
+		return models.PurchaseResponsev2{}, err
+	}
+	return pc.buildPurchaseResponse(ctx, purchase)
+}
+
+// buildPurchaseResponse agrega el usuario y el proveedor de la compra.
+func (pc *PurchaseV2Controller) buildPurchaseResponse(ctx context.Context, purchase models.Purchasev2) (models.PurchaseResponsev2, error) {
+	purchaseResponse := models.PurchaseResponsev2{
+		Purchase: purchase,
+	}
+
+	err := pc.userCollection.FindOne(ctx, bson.M{"_id": purchase.UserID}).Decode(&purchaseResponse.User)
+	if err != nil {
+		return purchaseResponse, err
+	}
+
+	err = pc.providerCollection.FindOne(ctx, bson.M{"_id": purchase.ProviderID}).Decode(&purchaseResponse.Provider)
+	if err != nil {
+		return purchaseResponse, err
+	}
+
+	return purchaseResponse, nil
+}
+
+// includeCurrentItem indica si se pidió ?include=current_item.
+func includeCurrentItem(c *fiber.Ctx) bool {
+	for _, include := range strings.Split(c.Query("include"), ",") {
+		if strings.TrimSpace(include) == "current_item" {
+			return true
+		}
+	}
+	return false
+}
+
+// attachCurrentItems pone en cada línea el artículo actual, aunque esté
+// borrado.
+func (pc *PurchaseV2Controller) attachCurrentItems(ctx context.Context, purchase *models.Purchasev2) error {
+	for i := range purchase.ItemList {
+		var item models.Item
+		err := pc.itemCollection.FindOne(ctx, bson.M{"_id": purchase.ItemList[i].ItemID}).Decode(&item)
+		if err == mongo.ErrNoDocuments {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		purchase.ItemList[i].CurrentItem = &item
+	}
+	return nil
+}
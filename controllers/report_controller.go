@@ -0,0 +1,164 @@
+package controllers
+
+import (
+	"errors"
+
+	"github.com/aldoramirezmartinez/fiber-api/config"
+	"github.com/aldoramirezmartinez/fiber-api/exchange"
+	"github.com/aldoramirezmartinez/fiber-api/models"
+	"github.com/aldoramirezmartinez/fiber-api/money"
+	"github.com/aldoramirezmartinez/fiber-api/revisions"
+	"github.com/aldoramirezmartinez/fiber-api/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type ReportController struct {
+	db                 *mongo.Database
+	purchaseCollection *mongo.Collection
+}
+
+func NewReportController(db *mongo.Database) *ReportController {
+	return &ReportController{
+		db:                 db,
+		purchaseCollection: db.Collection("purchases"),
+	}
+}
+
+// GetPurchaseReport totals purchases in the requested currency, the base
+// currency by default. Totals already stored in that currency are used as
+// they are, so base currency reports use the rate snapshotted on each
+// purchase; any other currency is converted at the rate in effect on the
+// purchase date.
+func (rc *ReportController) GetPurchaseReport(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	report := models.PurchaseReport{
+		Currency:  config.GetBaseCurrency(),
+		Providers: []models.ProviderPurchaseSum{},
+		Purchases: []models.ReportedPurchase{},
+	}
+	if value := c.Query("currency"); value != "" {
+		currency, err := money.ParseCurrency(value)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"message": "Invalid currency",
+				"error":   err.Error(),
+			})
+		}
+		report.Currency = currency
+	}
+
+	filter := utils.NotDeleted(bson.M{})
+	if status := c.Query("status"); status != "" {
+		filter["status"] = status
+	}
+
+	date := bson.M{}
+	for param, operator := range map[string]string{"from": "$gte", "to": "$lt"} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		parsed, err := exchange.ParseDate(value)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"message": "Invalid " + param + " date",
+				"error":   err.Error(),
+			})
+		}
+		date[operator] = parsed
+		if param == "from" {
+			report.From = &parsed
+		} else {
+			report.To = &parsed
+		}
+	}
+	if len(date) > 0 {
+		filter["date"] = date
+	}
+
+	cursor, err := rc.purchaseCollection.Find(ctx, filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to retrieve purchases",
+			"error":   err.Error(),
+		})
+	}
+	defer cursor.Close(ctx)
+
+	providers := map[primitive.ObjectID]int{}
+	for cursor.Next(ctx) {
+		var purchase models.Purchasev2
+		if err := cursor.Decode(&purchase); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"message": "Failed to decode purchases",
+				"error":   err.Error(),
+			})
+		}
+
+		total, err := rc.purchaseTotal(c, purchase, report.Currency)
+		if err != nil {
+			var rateErr *exchange.RateNotFoundError
+			if errors.As(err, &rateErr) {
+				return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+					"message": "Missing exchange rate",
+					"error":   rateErr.Error(),
+				})
+			}
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"message": "Failed to convert purchase total",
+				"error":   err.Error(),
+			})
+		}
+
+		report.Count++
+		report.Total = report.Total.Add(total)
+
+		i, ok := providers[purchase.ProviderID]
+		if !ok {
+			i = len(report.Providers)
+			providers[purchase.ProviderID] = i
+			report.Providers = append(report.Providers, models.ProviderPurchaseSum{ProviderID: purchase.ProviderID})
+		}
+		report.Providers[i].Count++
+		report.Providers[i].Total = report.Providers[i].Total.Add(total)
+
+		report.Purchases = append(report.Purchases, models.ReportedPurchase{
+			ID:            purchase.ID,
+			PurchaseOrder: purchase.PurchaseOrder,
+			ProviderID:    purchase.ProviderID,
+			Date:          purchase.Date,
+			Revision:      revisions.Number(purchase),
+			Total:         total,
+		})
+	}
+	if err := cursor.Err(); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to retrieve purchases",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.JSON(report)
+}
+
+// purchaseTotal returns the total of purchase in currency. Purchases saved
+// before currencies existed are in the default currency.
+func (rc *ReportController) purchaseTotal(c *fiber.Ctx, purchase models.Purchasev2, currency money.Currency) (money.Amount, error) {
+	purchaseCurrency := purchase.Currency.Or(config.GetDefaultCurrency())
+	switch {
+	case purchaseCurrency == currency:
+		return purchase.Total, nil
+	case purchase.BaseCurrency == currency:
+		return purchase.BaseTotal, nil
+	}
+
+	total, err := exchange.Convert(c.UserContext(), rc.db, purchase.Total, purchaseCurrency, currency, purchase.Date)
+	if err != nil {
+		return money.Zero, err
+	}
+	return currency.Round(total, money.HalfUp), nil
+}
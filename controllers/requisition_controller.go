@@ -0,0 +1,609 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aldoramirezmartinez/fiber-api/audit"
+	"github.com/aldoramirezmartinez/fiber-api/exchange"
+	"github.com/aldoramirezmartinez/fiber-api/models"
+	"github.com/aldoramirezmartinez/fiber-api/sourcing"
+	"github.com/aldoramirezmartinez/fiber-api/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var errRequisitionChanged = errors.New("the requisition was changed by another request, try again")
+
+// RequisitionController manages purchase requisitions. Converting them
+// creates purchases through purchaseController, so drafts are priced,
+// audited and announced like any other purchase.
+type RequisitionController struct {
+	db                 *mongo.Database
+	unitOfWork         *utils.UnitOfWork
+	collection         *mongo.Collection
+	itemCollection     *mongo.Collection
+	providerCollection *mongo.Collection
+	purchaseController *PurchaseV2Controller
+}
+
+func NewRequisitionController(db *mongo.Database, purchaseController *PurchaseV2Controller) *RequisitionController {
+	collection := db.Collection("requisitions")
+	_, err := collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.D{{Key: "status", Value: 1}, {Key: "_id", Value: 1}},
+	})
+	if err != nil {
+		fmt.Println("Failed to create requisition indexes:", err)
+	}
+
+	return &RequisitionController{
+		db:                 db,
+		unitOfWork:         utils.NewUnitOfWork(db),
+		collection:         collection,
+		itemCollection:     db.Collection("items"),
+		providerCollection: db.Collection("providers"),
+		purchaseController: purchaseController,
+	}
+}
+
+func (rc *RequisitionController) GetAllRequisitions(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	filter := bson.M{}
+	if status := c.Query("status"); status != "" {
+		filter["status"] = status
+	}
+	if value := c.Query("requester_id"); value != "" {
+		requesterID, err := primitive.ObjectIDFromHex(value)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"message": "Invalid requester_id",
+				"error":   err.Error(),
+			})
+		}
+		filter["requester_id"] = requesterID
+	}
+
+	cursor, err := rc.collection.Find(ctx, filter, options.Find().SetSort(bson.M{"_id": -1}))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to retrieve requisitions",
+			"error":   err.Error(),
+		})
+	}
+	defer cursor.Close(ctx)
+
+	requisitions := []models.Requisition{}
+	if err := cursor.All(ctx, &requisitions); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to decode requisitions",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.JSON(requisitions)
+}
+
+func (rc *RequisitionController) GetRequisition(c *fiber.Ctx) error {
+	requisition, err := rc.findRequisition(c)
+	if requisition == nil {
+		return err
+	}
+	return c.JSON(requisition)
+}
+
+// CreateRequisition records a request for items by the caller, pending
+// approval.
+func (rc *RequisitionController) CreateRequisition(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	requesterID := utils.ActorID(c)
+	if requesterID == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"message": "Requisitions need a requester, send " + utils.HeaderUserID,
+		})
+	}
+
+	request := new(models.RequisitionRequest)
+	if err := c.BodyParser(request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid request body",
+			"error":   err.Error(),
+		})
+	}
+
+	if err := request.Validate(); err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"message": "Invalid requisition",
+			"error":   err.Error(),
+		})
+	}
+
+	now := time.Now()
+	requisition := models.Requisition{
+		ID:            primitive.NewObjectID(),
+		RequesterID:   *requesterID,
+		Lines:         request.Lines,
+		Justification: request.Justification,
+		Status:        models.RequisitionPending,
+		Version:       1,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if request.NeededBy != "" {
+		neededBy, err := exchange.ParseDate(request.NeededBy)
+		if err != nil {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+				"message": "Invalid requisition",
+				"error":   err.Error(),
+			})
+		}
+		requisition.NeededBy = &neededBy
+	}
+
+	for i := range requisition.Lines {
+		line := &requisition.Lines[i]
+		var item models.Item
+		err := rc.itemCollection.FindOne(ctx, utils.NotDeleted(bson.M{"_id": line.ItemID})).Decode(&item)
+		if err == mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"message": "Item not found",
+			})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"message": "Failed to retrieve item",
+				"error":   err.Error(),
+			})
+		}
+		line.Name = item.Name
+		line.Code = item.Code
+		line.PurchaseID = nil
+	}
+
+	err := rc.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		if _, err := rc.collection.InsertOne(ctx, requisition); err != nil {
+			return err
+		}
+		return audit.Record(ctx, c, rc.db, models.AuditCreate, "requisitions", requisition.ID, nil)
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to create requisition",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(requisition)
+}
+
+// ApproveRequisition lets an admin other than the requester approve a
+// pending requisition.
+func (rc *RequisitionController) ApproveRequisition(c *fiber.Ctx) error {
+	return rc.review(c, models.RequisitionApproved)
+}
+
+// RejectRequisition lets an admin other than the requester reject a
+// pending requisition, giving a reason.
+func (rc *RequisitionController) RejectRequisition(c *fiber.Ctx) error {
+	return rc.review(c, models.RequisitionRejected)
+}
+
+func (rc *RequisitionController) review(c *fiber.Ctx, status string) error {
+	ctx := c.UserContext()
+
+	requisition, err := rc.findRequisition(c)
+	if requisition == nil {
+		return err
+	}
+
+	review := new(models.RequisitionReview)
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(review); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"message": "Invalid request body",
+				"error":   err.Error(),
+			})
+		}
+	}
+	if status == models.RequisitionRejected && strings.TrimSpace(review.Reason) == "" {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"message": "Invalid review",
+			"error":   "reason is required to reject a requisition",
+		})
+	}
+
+	reviewerID := utils.ActorID(c)
+	if reviewerID == nil || *reviewerID == requisition.RequesterID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"message": "Requesters cannot review their own requisitions",
+		})
+	}
+	if requisition.Status != models.RequisitionPending {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"message": "Only pending requisitions can be reviewed",
+		})
+	}
+
+	now := time.Now()
+	fields := bson.M{
+		"status":      status,
+		"reviewed_by": *reviewerID,
+		"reviewed_at": now,
+	}
+	if status == models.RequisitionRejected {
+		fields["rejection_reason"] = review.Reason
+	}
+
+	err = rc.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		return rc.updateRequisition(ctx, c, requisition, models.RequisitionPending, fields)
+	})
+	if err != nil {
+		return requisitionUpdateError(c, err)
+	}
+
+	requisition.Status = status
+	requisition.Version++
+	requisition.ReviewedBy = reviewerID
+	requisition.ReviewedAt = &now
+	if status == models.RequisitionRejected {
+		requisition.RejectionReason = review.Reason
+	}
+	return c.JSON(requisition)
+}
+
+// CancelRequisition withdraws a requisition that is not on any purchase
+// yet. Only its requester or an admin may cancel it.
+func (rc *RequisitionController) CancelRequisition(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	requisition, err := rc.findRequisition(c)
+	if requisition == nil {
+		return err
+	}
+
+	actorID := utils.ActorID(c)
+	if !utils.IsAdmin(c) && (actorID == nil || *actorID != requisition.RequesterID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"message": "Only the requester or an admin can cancel a requisition",
+		})
+	}
+
+	converted := false
+	for _, line := range requisition.Lines {
+		if line.PurchaseID != nil {
+			converted = true
+		}
+	}
+	if converted || (requisition.Status != models.RequisitionPending && requisition.Status != models.RequisitionApproved) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"message": "Only pending or approved requisitions with no purchases can be cancelled",
+		})
+	}
+
+	err = rc.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		return rc.updateRequisition(ctx, c, requisition, requisition.Status, bson.M{"status": models.RequisitionCancelled})
+	})
+	if err != nil {
+		return requisitionUpdateError(c, err)
+	}
+
+	requisition.Status = models.RequisitionCancelled
+	requisition.Version++
+	return c.JSON(requisition)
+}
+
+// ConvertRequisitions puts the unconverted lines of approved requisitions
+// on draft purchases, one per provider, taking each item's provider. Lines
+// for the same item and provider are added up. Lines whose item was
+// deleted or has no provider, or whose provider was deleted, are skipped
+// and reported. Requisitions become converted once all of their lines are
+// on a purchase.
+func (rc *RequisitionController) ConvertRequisitions(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	request := new(models.RequisitionConversionRequest)
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(request); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"message": "Invalid request body",
+				"error":   err.Error(),
+			})
+		}
+	}
+
+	userID := request.UserID
+	if userID == nil {
+		userID = utils.ActorID(c)
+	}
+	if userID == nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"message": "Invalid purchase",
+			"error":   "user_id is required",
+		})
+	}
+
+	filter := bson.M{"status": models.RequisitionApproved}
+	if len(request.RequisitionIDs) > 0 {
+		filter["_id"] = bson.M{"$in": request.RequisitionIDs}
+	}
+	cursor, err := rc.collection.Find(ctx, filter, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to retrieve requisitions",
+			"error":   err.Error(),
+		})
+	}
+	var requisitions []models.Requisition
+	err = cursor.All(ctx, &requisitions)
+	cursor.Close(ctx)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to decode requisitions",
+			"error":   err.Error(),
+		})
+	}
+	if len(request.RequisitionIDs) > 0 && len(requisitions) != len(request.RequisitionIDs) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"message": "Only approved requisitions can be converted",
+		})
+	}
+
+	var conversion models.RequisitionConversion
+	var purchaseIDs []primitive.ObjectID
+	err = rc.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		conversion.Skipped = []models.SkippedRequisition{}
+		purchaseIDs, err = rc.convert(ctx, c, requisitions, *userID, &conversion)
+		return err
+	})
+	if err != nil {
+		var rateErr *exchange.RateNotFoundError
+		if errors.As(err, &rateErr) {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+				"message": "Missing exchange rate",
+				"error":   rateErr.Error(),
+			})
+		}
+		switch err {
+		case errRequisitionChanged:
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"message": "Failed to convert requisitions",
+				"error":   err.Error(),
+			})
+		case sourcing.ErrBelowMinimum:
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+				"message": "Invalid purchase",
+				"error":   err.Error(),
+			})
+		case errUserNotFound:
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"message": "User not found",
+			})
+		case mongo.ErrNoDocuments:
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"message": "Item not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to convert requisitions",
+			"error":   err.Error(),
+		})
+	}
+
+	conversion.Purchases = []models.PurchaseResponsev2{}
+	for _, purchaseID := range purchaseIDs {
+		purchaseResponse, err := rc.purchaseController.loadPurchaseResponse(ctx, purchaseID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"message": "Failed to retrieve purchase",
+				"error":   err.Error(),
+			})
+		}
+		conversion.Purchases = append(conversion.Purchases, purchaseResponse)
+	}
+
+	if len(purchaseIDs) == 0 {
+		return c.JSON(conversion)
+	}
+	return c.Status(fiber.StatusCreated).JSON(conversion)
+}
+
+// convert creates the draft purchases for requisitions and records on each
+// requisition line the purchase it went to. It works on copies of the
+// lines so UnitOfWork.Do can run it again.
+func (rc *RequisitionController) convert(ctx context.Context, c *fiber.Ctx, requisitions []models.Requisition, userID primitive.ObjectID, conversion *models.RequisitionConversion) ([]primitive.ObjectID, error) {
+	type lineRef struct {
+		requisition int
+		line        int
+	}
+	type providerGroup struct {
+		purchase *models.Purchasev2
+		lines    map[primitive.ObjectID]int
+		refs     []lineRef
+	}
+
+	now := time.Now()
+	lines := make([][]models.RequisitionLine, len(requisitions))
+	groups := map[primitive.ObjectID]*providerGroup{}
+	var order []primitive.ObjectID
+	providerExists := map[primitive.ObjectID]bool{}
+
+	for i, requisition := range requisitions {
+		lines[i] = append([]models.RequisitionLine(nil), requisition.Lines...)
+		for j, line := range lines[i] {
+			if line.PurchaseID != nil {
+				continue
+			}
+			skip := func(reason string) {
+				conversion.Skipped = append(conversion.Skipped, models.SkippedRequisition{
+					RequisitionID: requisition.ID,
+					ItemID:        line.ItemID,
+					Reason:        reason,
+				})
+			}
+
+			var item models.Item
+			err := rc.itemCollection.FindOne(ctx, utils.NotDeleted(bson.M{"_id": line.ItemID})).Decode(&item)
+			if err == mongo.ErrNoDocuments {
+				skip("item not found")
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			if item.ProviderID.IsZero() {
+				skip("item has no provider")
+				continue
+			}
+
+			exists, checked := providerExists[item.ProviderID]
+			if !checked {
+				exists, err = utils.CheckDocumentExists(ctx, rc.providerCollection, item.ProviderID)
+				if err != nil {
+					return nil, err
+				}
+				providerExists[item.ProviderID] = exists
+			}
+			if !exists {
+				skip("provider not found")
+				continue
+			}
+
+			group := groups[item.ProviderID]
+			if group == nil {
+				purchaseID := primitive.NewObjectID()
+				group = &providerGroup{
+					purchase: &models.Purchasev2{
+						ID:            purchaseID,
+						PurchaseOrder: "REQ-" + purchaseID.Hex(),
+						Date:          now,
+						Status:        models.PurchaseStatusDraft,
+						UserID:        userID,
+						ProviderID:    item.ProviderID,
+						Revision:      1,
+						Version:       1,
+					},
+					lines: map[primitive.ObjectID]int{},
+				}
+				groups[item.ProviderID] = group
+				order = append(order, item.ProviderID)
+			}
+
+			purchase := group.purchase
+			if index, ok := group.lines[item.ID]; ok {
+				purchase.ItemList[index].Quantity += line.Quantity
+			} else {
+				group.lines[item.ID] = len(purchase.ItemList)
+				purchase.ItemList = append(purchase.ItemList, models.PurchaseDetailv2{ItemID: item.ID, Quantity: line.Quantity})
+			}
+			if n := len(purchase.RequisitionIDs); n == 0 || purchase.RequisitionIDs[n-1] != requisition.ID {
+				purchase.RequisitionIDs = append(purchase.RequisitionIDs, requisition.ID)
+			}
+			group.refs = append(group.refs, lineRef{requisition: i, line: j})
+		}
+	}
+
+	purchaseIDs := make([]primitive.ObjectID, 0, len(order))
+	touched := map[int]bool{}
+	for _, providerID := range order {
+		group := groups[providerID]
+		if err := rc.purchaseController.insertPurchase(ctx, c, group.purchase, nil); err != nil {
+			return nil, err
+		}
+		purchaseIDs = append(purchaseIDs, group.purchase.ID)
+
+		for _, ref := range group.refs {
+			lines[ref.requisition][ref.line].PurchaseID = &group.purchase.ID
+			touched[ref.requisition] = true
+		}
+	}
+
+	for i := range requisitions {
+		if !touched[i] {
+			continue
+		}
+		status := models.RequisitionConverted
+		for _, line := range lines[i] {
+			if line.PurchaseID == nil {
+				status = models.RequisitionApproved
+			}
+		}
+		err := rc.updateRequisition(ctx, c, &requisitions[i], models.RequisitionApproved, bson.M{
+			"lines":  lines[i],
+			"status": status,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return purchaseIDs, nil
+}
+
+// findRequisition loads the requisition named by the id parameter. On
+// failure it writes the error response and returns a nil requisition, with
+// the error of writing it.
+func (rc *RequisitionController) findRequisition(c *fiber.Ctx) (*models.Requisition, error) {
+	objID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid requisition ID",
+			"error":   err.Error(),
+		})
+	}
+
+	var requisition models.Requisition
+	err = rc.collection.FindOne(c.UserContext(), bson.M{"_id": objID}).Decode(&requisition)
+	if err == mongo.ErrNoDocuments {
+		return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"message": "Requisition not found",
+		})
+	}
+	if err != nil {
+		return nil, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to retrieve requisition",
+			"error":   err.Error(),
+		})
+	}
+	return &requisition, nil
+}
+
+// updateRequisition sets fields on requisition as long as it still has
+// status and is unchanged since it was read, bumps the stored version and
+// records the change in the audit log. It returns errRequisitionChanged
+// otherwise. Call it inside UnitOfWork.Do.
+func (rc *RequisitionController) updateRequisition(ctx context.Context, c *fiber.Ctx, requisition *models.Requisition, status string, fields bson.M) error {
+	fields["updated_at"] = time.Now()
+
+	filter := utils.VersionFilter(requisition.ID, requisition.Version)
+	filter["status"] = status
+	result, err := rc.collection.UpdateOne(ctx, filter, bson.M{
+		"$set": fields,
+		"$inc": bson.M{"version": 1},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errRequisitionChanged
+	}
+	return audit.Record(ctx, c, rc.db, models.AuditUpdate, "requisitions", requisition.ID, requisition)
+}
+
+func requisitionUpdateError(c *fiber.Ctx, err error) error {
+	if err == errRequisitionChanged {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"message": "Failed to update requisition",
+			"error":   err.Error(),
+		})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"message": "Failed to update requisition",
+		"error":   err.Error(),
+	})
+}
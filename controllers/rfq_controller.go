@@ -0,0 +1,711 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aldoramirezmartinez/fiber-api/audit"
+	"github.com/aldoramirezmartinez/fiber-api/config"
+	"github.com/aldoramirezmartinez/fiber-api/exchange"
+	"github.com/aldoramirezmartinez/fiber-api/models"
+	"github.com/aldoramirezmartinez/fiber-api/money"
+	"github.com/aldoramirezmartinez/fiber-api/pricing"
+	"github.com/aldoramirezmartinez/fiber-api/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const rfqCollectionName = "rfqs"
+
+var errRFQChanged = errors.New("the RFQ was changed by another request, try again")
+
+// RFQController manages requests for quotation. Converting a quote creates
+// the purchase through purchaseController, so drafts are priced, audited
+// and announced like any other purchase.
+type RFQController struct {
+	db                 *mongo.Database
+	unitOfWork         *utils.UnitOfWork
+	collection         *mongo.Collection
+	itemCollection     *mongo.Collection
+	providerCollection *mongo.Collection
+	purchaseController *PurchaseV2Controller
+}
+
+func NewRFQController(db *mongo.Database, purchaseController *PurchaseV2Controller) *RFQController {
+	collection := db.Collection(rfqCollectionName)
+	_, err := collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.D{{Key: "status", Value: 1}, {Key: "_id", Value: -1}},
+	})
+	if err != nil {
+		fmt.Println("Failed to create RFQ indexes:", err)
+	}
+
+	return &RFQController{
+		db:                 db,
+		unitOfWork:         utils.NewUnitOfWork(db),
+		collection:         collection,
+		itemCollection:     db.Collection("items"),
+		providerCollection: db.Collection("providers"),
+		purchaseController: purchaseController,
+	}
+}
+
+func (rc *RFQController) GetAllRFQs(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	filter := bson.M{}
+	if status := c.Query("status"); status != "" {
+		filter["status"] = status
+	}
+	if value := c.Query("provider_id"); value != "" {
+		providerID, err := primitive.ObjectIDFromHex(value)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"message": "Invalid provider_id",
+				"error":   err.Error(),
+			})
+		}
+		filter["provider_ids"] = providerID
+	}
+
+	cursor, err := rc.collection.Find(ctx, filter, options.Find().SetSort(bson.M{"_id": -1}))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to retrieve RFQs",
+			"error":   err.Error(),
+		})
+	}
+	defer cursor.Close(ctx)
+
+	rfqs := []models.RFQ{}
+	if err := cursor.All(ctx, &rfqs); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to decode RFQs",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.JSON(rfqs)
+}
+
+func (rc *RFQController) GetRFQ(c *fiber.Ctx) error {
+	rfq, err := rc.findRFQ(c)
+	if rfq == nil {
+		return err
+	}
+	return c.JSON(rfq)
+}
+
+// CreateRFQ opens an RFQ for the given items and providers.
+func (rc *RFQController) CreateRFQ(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	request := new(models.RFQRequest)
+	if err := c.BodyParser(request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid request body",
+			"error":   err.Error(),
+		})
+	}
+
+	if err := request.Validate(); err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"message": "Invalid RFQ",
+			"error":   err.Error(),
+		})
+	}
+
+	now := time.Now()
+	rfq := models.RFQ{
+		ID:          primitive.NewObjectID(),
+		Reference:   request.Reference,
+		Description: request.Description,
+		Currency:    request.Currency.Or(config.GetBaseCurrency()),
+		Lines:       request.Lines,
+		ProviderIDs: request.ProviderIDs,
+		Status:      models.RFQStatusOpen,
+		Quotes:      []models.Quote{},
+		CreatedBy:   utils.ActorID(c),
+		Version:     1,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if request.DueDate != "" {
+		dueDate, err := exchange.ParseDate(request.DueDate)
+		if err != nil {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+				"message": "Invalid RFQ",
+				"error":   err.Error(),
+			})
+		}
+		rfq.DueDate = &dueDate
+	}
+
+	for i := range rfq.Lines {
+		line := &rfq.Lines[i]
+		var item models.Item
+		err := rc.itemCollection.FindOne(ctx, utils.NotDeleted(bson.M{"_id": line.ItemID})).Decode(&item)
+		if err == mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"message": "Item not found",
+			})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"message": "Failed to retrieve item",
+				"error":   err.Error(),
+			})
+		}
+		line.Name = item.Name
+		line.Code = item.Code
+	}
+
+	for _, providerID := range rfq.ProviderIDs {
+		exists, err := utils.CheckDocumentExists(ctx, rc.providerCollection, providerID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"message": "Failed to retrieve provider",
+				"error":   err.Error(),
+			})
+		}
+		if !exists {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"message": "Provider not found",
+			})
+		}
+	}
+
+	err := rc.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		if _, err := rc.collection.InsertOne(ctx, rfq); err != nil {
+			return err
+		}
+		return audit.Record(ctx, c, rc.db, models.AuditCreate, rfqCollectionName, rfq.ID, nil)
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to create RFQ",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(rfq)
+}
+
+// CancelRFQ closes an open RFQ without awarding it.
+func (rc *RFQController) CancelRFQ(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	rfq, err := rc.findRFQ(c)
+	if rfq == nil {
+		return err
+	}
+	if rfq.Status != models.RFQStatusOpen {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"message": "Only open RFQs can be cancelled",
+		})
+	}
+
+	err = rc.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		return rc.updateOpenRFQ(ctx, c, rfq, bson.M{"status": models.RFQStatusCancelled})
+	})
+	if err != nil {
+		return rfqUpdateError(c, err)
+	}
+
+	rfq.Status = models.RFQStatusCancelled
+	rfq.Version++
+	return c.JSON(rfq)
+}
+
+// SubmitQuote records a provider's quote for an open RFQ, replacing any
+// quote the provider sent before.
+func (rc *RFQController) SubmitQuote(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	rfq, err := rc.findRFQ(c)
+	if rfq == nil {
+		return err
+	}
+	now := time.Now()
+	if rfq.Status != models.RFQStatusOpen {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"message": "The RFQ is not open for quotes",
+		})
+	}
+	if rfq.DueDate != nil && now.After(*rfq.DueDate) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"message": "The RFQ is past its due date",
+		})
+	}
+
+	request := new(models.QuoteRequest)
+	if err := c.BodyParser(request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid request body",
+			"error":   err.Error(),
+		})
+	}
+
+	if err := request.Validate(rfq); err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"message": "Invalid quote",
+			"error":   err.Error(),
+		})
+	}
+
+	quote := models.Quote{
+		ID:          primitive.NewObjectID(),
+		ProviderID:  request.ProviderID,
+		Currency:    request.Currency,
+		Lines:       request.Lines,
+		Notes:       request.Notes,
+		SubmittedAt: now,
+	}
+	if request.ValidUntil != "" {
+		validUntil, err := exchange.ParseDate(request.ValidUntil)
+		if err != nil {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+				"message": "Invalid quote",
+				"error":   err.Error(),
+			})
+		}
+		quote.ValidUntil = &validUntil
+	}
+
+	var provider models.Provider
+	err = rc.providerCollection.FindOne(ctx, utils.NotDeleted(bson.M{"_id": quote.ProviderID})).Decode(&provider)
+	if err == mongo.ErrNoDocuments {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Provider not found",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to retrieve provider",
+			"error":   err.Error(),
+		})
+	}
+	quote.Currency = quote.Currency.Or(provider.Currency.Or(config.GetDefaultCurrency()))
+
+	quotes := []models.Quote{}
+	for _, previous := range rfq.Quotes {
+		if previous.ProviderID != quote.ProviderID {
+			quotes = append(quotes, previous)
+		}
+	}
+	quotes = append(quotes, quote)
+
+	err = rc.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		return rc.updateOpenRFQ(ctx, c, rfq, bson.M{"quotes": quotes})
+	})
+	if err != nil {
+		return rfqUpdateError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(quote)
+}
+
+// CompareQuotes sets the quotes of an RFQ side by side in the RFQ currency.
+func (rc *RFQController) CompareQuotes(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	rfq, err := rc.findRFQ(c)
+	if rfq == nil {
+		return err
+	}
+
+	comparison, err := rc.compareQuotes(ctx, rfq, time.Now())
+	if err != nil {
+		var rateErr *exchange.RateNotFoundError
+		if errors.As(err, &rateErr) {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+				"message": "Missing exchange rate",
+				"error":   rateErr.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to compare quotes",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.JSON(comparison)
+}
+
+// ConvertQuote turns a quote into a draft purchase from its provider at the
+// quoted prices, and marks the RFQ awarded. Lines the quote left out are
+// not on the purchase.
+func (rc *RFQController) ConvertQuote(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	rfq, err := rc.findRFQ(c)
+	if rfq == nil {
+		return err
+	}
+
+	quoteID, err := primitive.ObjectIDFromHex(c.Params("quote_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid quote ID",
+			"error":   err.Error(),
+		})
+	}
+	quote := rfq.Quote(quoteID)
+	if quote == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"message": "Quote not found",
+		})
+	}
+
+	request := new(models.ConvertQuoteRequest)
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(request); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"message": "Invalid request body",
+				"error":   err.Error(),
+			})
+		}
+	}
+
+	now := time.Now()
+	if rfq.Status != models.RFQStatusOpen {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"message": "Only open RFQs can be awarded",
+		})
+	}
+	if quote.Expired(now) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"message": "The quote has expired",
+		})
+	}
+
+	userID := request.UserID
+	if userID == nil {
+		userID = utils.ActorID(c)
+	}
+	if userID == nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"message": "Invalid purchase",
+			"error":   "user_id is required",
+		})
+	}
+
+	purchase := &models.Purchasev2{
+		ID:            primitive.NewObjectID(),
+		PurchaseOrder: request.PurchaseOrder,
+		Date:          now,
+		Status:        models.PurchaseStatusDraft,
+		Currency:      quote.Currency,
+		UserID:        *userID,
+		ProviderID:    quote.ProviderID,
+		Revision:      1,
+		Version:       1,
+	}
+	if purchase.PurchaseOrder == "" {
+		purchase.PurchaseOrder = rfq.Reference
+	}
+
+	err = rc.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		quoted, err := rc.quotedPurchase(ctx, rfq, quote, purchase)
+		if err != nil {
+			return err
+		}
+		if err := rc.purchaseController.insertPurchase(ctx, c, purchase, quoted); err != nil {
+			return err
+		}
+		return rc.updateOpenRFQ(ctx, c, rfq, bson.M{
+			"status":           models.RFQStatusAwarded,
+			"awarded_quote_id": quote.ID,
+			"purchase_id":      purchase.ID,
+		})
+	})
+	if err != nil {
+		var rateErr *exchange.RateNotFoundError
+		if errors.As(err, &rateErr) {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+				"message": "Missing exchange rate",
+				"error":   rateErr.Error(),
+			})
+		}
+		switch err {
+		case errRFQChanged:
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"message": "Failed to award RFQ",
+				"error":   err.Error(),
+			})
+		case errUserNotFound:
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"message": "User not found",
+			})
+		case errProviderNotFound:
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"message": "Provider not found",
+			})
+		case mongo.ErrNoDocuments:
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"message": "Item not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to create purchase",
+			"error":   err.Error(),
+		})
+	}
+
+	purchaseResponse, err := rc.purchaseController.loadPurchaseResponse(ctx, purchase.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to retrieve purchase",
+			"error":   err.Error(),
+		})
+	}
+
+	utils.SetETag(c, purchaseResponse.Purchase.Version)
+	return c.Status(fiber.StatusCreated).JSON(purchaseResponse)
+}
+
+// findRFQ loads the RFQ named by the id parameter. On failure it writes the
+// error response and returns a nil RFQ, with the error of writing it.
+func (rc *RFQController) findRFQ(c *fiber.Ctx) (*models.RFQ, error) {
+	objID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid RFQ ID",
+			"error":   err.Error(),
+		})
+	}
+
+	var rfq models.RFQ
+	err = rc.collection.FindOne(c.UserContext(), bson.M{"_id": objID}).Decode(&rfq)
+	if err == mongo.ErrNoDocuments {
+		return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"message": "RFQ not found",
+		})
+	}
+	if err != nil {
+		return nil, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to retrieve RFQ",
+			"error":   err.Error(),
+		})
+	}
+	return &rfq, nil
+}
+
+// updateOpenRFQ sets fields on rfq as long as it is still open and
+// unchanged since it was read, bumps the stored version and records the
+// change in the audit log. It returns errRFQChanged otherwise. Run it inside
+// UnitOfWork.Do.
+func (rc *RFQController) updateOpenRFQ(ctx context.Context, c *fiber.Ctx, rfq *models.RFQ, fields bson.M) error {
+	fields["updated_at"] = time.Now()
+
+	filter := utils.VersionFilter(rfq.ID, rfq.Version)
+	filter["status"] = models.RFQStatusOpen
+	result, err := rc.collection.UpdateOne(ctx, filter, bson.M{
+		"$set": fields,
+		"$inc": bson.M{"version": 1},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errRFQChanged
+	}
+	return audit.Record(ctx, c, rc.db, models.AuditUpdate, rfqCollectionName, rfq.ID, rfq)
+}
+
+func rfqUpdateError(c *fiber.Ctx, err error) error {
+	if err == errRFQChanged {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"message": "Failed to update RFQ",
+			"error":   err.Error(),
+		})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"message": "Failed to update RFQ",
+		"error":   err.Error(),
+	})
+}
+
+// quotedPurchase fills in the lines of purchase from quote and returns them
+// as an earlier version of the purchase whose snapshots carry the quoted
+// prices, for pricePurchase to keep. Items are locked like snapshotItem
+// does.
+func (rc *RFQController) quotedPurchase(ctx context.Context, rfq *models.RFQ, quote *models.Quote, purchase *models.Purchasev2) (*models.Purchasev2, error) {
+	var provider models.Provider
+	err := rc.providerCollection.FindOne(ctx, bson.M{"_id": quote.ProviderID}).Decode(&provider)
+	if err == mongo.ErrNoDocuments {
+		return nil, errProviderNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	quoted := &models.Purchasev2{ProviderID: quote.ProviderID}
+	now := time.Now()
+	for _, rfqLine := range rfq.Lines {
+		var quoteLine *models.QuoteLine
+		for i := range quote.Lines {
+			if quote.Lines[i].ItemID == rfqLine.ItemID {
+				quoteLine = &quote.Lines[i]
+			}
+		}
+		if quoteLine == nil {
+			continue
+		}
+
+		var item models.Item
+		err := rc.itemCollection.FindOneAndUpdate(ctx, utils.NotDeleted(bson.M{"_id": rfqLine.ItemID}), bson.M{
+			"$set": bson.M{"lock": primitive.NewObjectID()},
+		}).Decode(&item)
+		if err != nil {
+			return nil, err
+		}
+
+		purchase.ItemList = append(purchase.ItemList, models.PurchaseDetailv2{ItemID: item.ID, Quantity: rfqLine.Quantity})
+		quoted.ItemList = append(quoted.ItemList, models.PurchaseDetailv2{ItemID: item.ID, Quantity: rfqLine.Quantity, Snapshot: &models.ItemSnapshot{
+			Name:         item.Name,
+			Code:         item.Code,
+			UnitMeasure:  item.UnitMeasure,
+			Category:     item.Category,
+			Price:        quoteLine.UnitPrice,
+			Currency:     quote.Currency,
+			ProviderID:   provider.ID,
+			ProviderName: provider.Name,
+			LeadTimeDays: quoteLine.LeadTimeDays,
+			QuoteID:      &quote.ID,
+			TakenAt:      now,
+		}})
+	}
+	return quoted, nil
+}
+
+// compareQuotes builds the comparison of the quotes of rfq at the exchange
+// rates in effect at at.
+func (rc *RFQController) compareQuotes(ctx context.Context, rfq *models.RFQ, at time.Time) (models.RFQComparison, error) {
+	comparison := models.RFQComparison{
+		RFQID:    rfq.ID,
+		Currency: rfq.Currency,
+		Quotes:   []models.QuoteSummary{},
+		Lines:    make([]models.RFQLineComparison, len(rfq.Lines)),
+	}
+	for i, line := range rfq.Lines {
+		comparison.Lines[i] = models.RFQLineComparison{
+			ItemID:   line.ItemID,
+			Name:     line.Name,
+			Code:     line.Code,
+			Quantity: line.Quantity,
+			Bids:     []models.LineBid{},
+		}
+	}
+
+	names, err := rc.providerNames(ctx, rfq.ProviderIDs)
+	if err != nil {
+		return comparison, err
+	}
+
+	expired := map[primitive.ObjectID]bool{}
+	for _, quote := range rfq.Quotes {
+		summary := models.QuoteSummary{
+			QuoteID:      quote.ID,
+			ProviderID:   quote.ProviderID,
+			ProviderName: names[quote.ProviderID],
+			QuoteTotal:   money.Zero,
+			Currency:     rfq.Currency,
+			Total:        money.Zero,
+			ValidUntil:   quote.ValidUntil,
+			Expired:      quote.Expired(at),
+		}
+		expired[quote.ID] = summary.Expired
+
+		for _, quoteLine := range quote.Lines {
+			for i, line := range rfq.Lines {
+				if line.ItemID != quoteLine.ItemID {
+					continue
+				}
+				unitPrice, err := exchange.Convert(ctx, rc.db, quoteLine.UnitPrice, quote.Currency, rfq.Currency, at)
+				if err != nil {
+					return comparison, err
+				}
+				bid := models.LineBid{
+					QuoteID:      quote.ID,
+					ProviderID:   quote.ProviderID,
+					ProviderName: summary.ProviderName,
+					UnitPrice:    unitPrice,
+					Total:        pricing.Gross(unitPrice, line.Quantity, rfq.Currency),
+					LeadTimeDays: quoteLine.LeadTimeDays,
+				}
+				comparison.Lines[i].Bids = append(comparison.Lines[i].Bids, bid)
+
+				summary.QuoteTotal = summary.QuoteTotal.Add(pricing.Gross(quoteLine.UnitPrice, line.Quantity, quote.Currency))
+				summary.Total = summary.Total.Add(bid.Total)
+				summary.LinesQuoted++
+				if quoteLine.LeadTimeDays > summary.LeadTimeDays {
+					summary.LeadTimeDays = quoteLine.LeadTimeDays
+				}
+			}
+		}
+		summary.Complete = summary.LinesQuoted == len(rfq.Lines)
+		comparison.Quotes = append(comparison.Quotes, summary)
+	}
+
+	// Complete quotes come first, then the cheapest, then the fastest.
+	sort.SliceStable(comparison.Quotes, func(i, j int) bool {
+		a, b := comparison.Quotes[i], comparison.Quotes[j]
+		if a.Complete != b.Complete {
+			return a.Complete
+		}
+		if cmp := a.Total.Cmp(b.Total); cmp != 0 {
+			return cmp < 0
+		}
+		return a.LeadTimeDays < b.LeadTimeDays
+	})
+	for i := range comparison.Quotes {
+		if quote := &comparison.Quotes[i]; quote.Complete && !quote.Expired {
+			quote.Best = true
+			break
+		}
+	}
+
+	for i := range comparison.Lines {
+		bids := comparison.Lines[i].Bids
+		sort.SliceStable(bids, func(a, b int) bool {
+			if cmp := bids[a].Total.Cmp(bids[b].Total); cmp != 0 {
+				return cmp < 0
+			}
+			return bids[a].LeadTimeDays < bids[b].LeadTimeDays
+		})
+		for j := range bids {
+			if !expired[bids[j].QuoteID] {
+				bids[j].Best = true
+				break
+			}
+		}
+	}
+	return comparison, nil
+}
+
+// providerNames returns the names of the providers in ids, deleted ones
+// included.
+func (rc *RFQController) providerNames(ctx context.Context, ids []primitive.ObjectID) (map[primitive.ObjectID]string, error) {
+	cursor, err := rc.providerCollection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var providers []models.Provider
+	if err := cursor.All(ctx, &providers); err != nil {
+		return nil, err
+	}
+	names := map[primitive.ObjectID]string{}
+	for _, provider := range providers {
+		names[provider.ID] = provider.Name
+	}
+	return names, nil
+}
@@ -0,0 +1,226 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/aldoramirezmartinez/fiber-api/audit"
+	"github.com/aldoramirezmartinez/fiber-api/models"
+	"github.com/aldoramirezmartinez/fiber-api/tax"
+	"github.com/aldoramirezmartinez/fiber-api/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type TaxRuleController struct {
+	db         *mongo.Database
+	unitOfWork *utils.UnitOfWork
+	collection *mongo.Collection
+}
+
+func NewTaxRuleController(db *mongo.Database) *TaxRuleController {
+	return &TaxRuleController{
+		db:         db,
+		unitOfWork: utils.NewUnitOfWork(db),
+		collection: db.Collection(tax.CollectionName),
+	}
+}
+
+func (tc *TaxRuleController) GetAllTaxRules(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	filter := bson.M{}
+	for _, param := range []string{"category", "provider_type"} {
+		if value := c.Query(param); value != "" {
+			filter[param] = value
+		}
+	}
+
+	cursor, err := tc.collection.Find(ctx, filter, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to retrieve tax rules",
+			"error":   err.Error(),
+		})
+	}
+	defer cursor.Close(ctx)
+
+	rules := []models.TaxRule{}
+	if err := cursor.All(ctx, &rules); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to decode tax rules",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.JSON(rules)
+}
+
+func (tc *TaxRuleController) GetTaxRule(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	objID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid tax rule ID",
+			"error":   err.Error(),
+		})
+	}
+
+	var rule models.TaxRule
+	err = tc.collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&rule)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"message": "Tax rule not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to retrieve tax rule",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.JSON(rule)
+}
+
+func (tc *TaxRuleController) CreateTaxRule(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	rule := new(models.TaxRule)
+	if err := c.BodyParser(rule); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid request body",
+			"error":   err.Error(),
+		})
+	}
+
+	if err := rule.Validate(); err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"message": "Invalid tax rule",
+			"error":   err.Error(),
+		})
+	}
+
+	rule.ID = primitive.NewObjectID()
+	rule.CreatedAt = time.Now()
+	rule.UpdatedAt = rule.CreatedAt
+
+	err := tc.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		if _, err := tc.collection.InsertOne(ctx, rule); err != nil {
+			return err
+		}
+		return audit.Record(ctx, c, tc.db, models.AuditCreate, tax.CollectionName, rule.ID, nil)
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to create tax rule",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(rule)
+}
+
+// UpdateTaxRule replaces a rule. Lines already on a purchase keep the rates
+// they were saved with, even when the purchase is edited.
+func (tc *TaxRuleController) UpdateTaxRule(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	objID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid tax rule ID",
+			"error":   err.Error(),
+		})
+	}
+
+	var existingRule models.TaxRule
+	err = tc.collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&existingRule)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"message": "Tax rule not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to retrieve tax rule",
+			"error":   err.Error(),
+		})
+	}
+
+	rule := new(models.TaxRule)
+	if err := c.BodyParser(rule); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid request body",
+			"error":   err.Error(),
+		})
+	}
+
+	rule.ID = objID
+	rule.CreatedAt = existingRule.CreatedAt
+	rule.UpdatedAt = time.Now()
+
+	if err := rule.Validate(); err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"message": "Invalid tax rule",
+			"error":   err.Error(),
+		})
+	}
+
+	err = tc.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		if _, err := tc.collection.ReplaceOne(ctx, bson.M{"_id": objID}, rule); err != nil {
+			return err
+		}
+		return audit.Record(ctx, c, tc.db, models.AuditUpdate, tax.CollectionName, objID, existingRule)
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to update tax rule",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.JSON(rule)
+}
+
+func (tc *TaxRuleController) DeleteTaxRule(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	objID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid tax rule ID",
+			"error":   err.Error(),
+		})
+	}
+
+	err = tc.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		var existingRule models.TaxRule
+		err := tc.collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&existingRule)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tc.collection.DeleteOne(ctx, bson.M{"_id": objID}); err != nil {
+			return err
+		}
+		return audit.Record(ctx, c, tc.db, models.AuditDelete, tax.CollectionName, objID, existingRule)
+	})
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"message": "Tax rule not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to delete tax rule",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
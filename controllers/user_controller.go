@@ -3,7 +3,10 @@ package controllers
 import (
 	"context"
 
+	"github.com/aldoramirezmartinez/fiber-api/audit"
+	"github.com/aldoramirezmartinez/fiber-api/integrity"
 	"github.com/aldoramirezmartinez/fiber-api/models"
+	"github.com/aldoramirezmartinez/fiber-api/utils"
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -11,19 +14,23 @@ import (
 )
 
 type UserController struct {
+	db         *mongo.Database
+	unitOfWork *utils.UnitOfWork
 	collection *mongo.Collection
 }
 
 func NewUserController(db *mongo.Database) *UserController {
 	return &UserController{
+		db:         db,
+		unitOfWork: utils.NewUnitOfWork(db),
 		collection: db.Collection("users"),
 	}
 }
 
 func (uc *UserController) GetAllUsers(c *fiber.Ctx) error {
-	ctx := context.TODO()
+	ctx := c.UserContext()
 
-	cursor, err := uc.collection.Find(ctx, bson.M{})
+	cursor, err := uc.collection.Find(ctx, utils.ScopeDeleted(c, bson.M{}))
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Failed to get users",
@@ -44,7 +51,7 @@ func (uc *UserController) GetAllUsers(c *fiber.Ctx) error {
 }
 
 func (uc *UserController) GetUser(c *fiber.Ctx) error {
-	ctx := context.TODO()
+	ctx := c.UserContext()
 
 	userID := c.Params("id")
 
@@ -57,7 +64,7 @@ func (uc *UserController) GetUser(c *fiber.Ctx) error {
 	}
 
 	var user models.User
-	err = uc.collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&user)
+	err = uc.collection.FindOne(ctx, utils.ScopeDeleted(c, bson.M{"_id": objID})).Decode(&user)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -70,11 +77,16 @@ func (uc *UserController) GetUser(c *fiber.Ctx) error {
 		})
 	}
 
+	utils.SetETag(c, user.Version)
+	if utils.IfNoneMatch(c, user.Version) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
 	return c.JSON(user)
 }
 
 func (uc *UserController) CreateUser(c *fiber.Ctx) error {
-	ctx := context.TODO()
+	ctx := c.UserContext()
 
 	user := new(models.User)
 	if err := c.BodyParser(user); err != nil {
@@ -84,28 +96,43 @@ func (uc *UserController) CreateUser(c *fiber.Ctx) error {
 		})
 	}
 
-	result, err := uc.collection.InsertOne(ctx, user)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Failed to create user",
+	if err := user.Validate(); err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"message": "Invalid user",
 			"error":   err.Error(),
 		})
 	}
 
-	insertedID, ok := result.InsertedID.(primitive.ObjectID)
-	if !ok {
+	if !utils.CanSetRole(c, models.Role{}, user.Role) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"message": "Only admins can change roles",
+		})
+	}
+
+	user.ID = primitive.NewObjectID()
+	user.Version = 1
+	user.DeletedAt = nil
+	user.DeletedBy = nil
+
+	err := uc.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		if _, err := uc.collection.InsertOne(ctx, user); err != nil {
+			return err
+		}
+		return audit.Record(ctx, c, uc.db, models.AuditCreate, "users", user.ID, nil)
+	})
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Failed to create user",
+			"error":   err.Error(),
 		})
 	}
 
-	user.ID = insertedID
-
+	utils.SetETag(c, user.Version)
 	return c.JSON(user)
 }
 
 func (uc *UserController) UpdateUser(c *fiber.Ctx) error {
-	ctx := context.TODO()
+	ctx := c.UserContext()
 
 	userID := c.Params("id")
 
@@ -125,31 +152,69 @@ func (uc *UserController) UpdateUser(c *fiber.Ctx) error {
 		})
 	}
 
+	if err := updateData.Validate(); err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"message": "Invalid user",
+			"error":   err.Error(),
+		})
+	}
+
+	var existingUser models.User
+	err = uc.collection.FindOne(ctx, utils.NotDeleted(bson.M{"_id": objID})).Decode(&existingUser)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"message": "User not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to get user",
+			"error":   err.Error(),
+		})
+	}
+
+	if !utils.IfMatch(c, existingUser.Version) {
+		return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+			"message": "User has been modified",
+		})
+	}
+
+	if !utils.CanSetRole(c, existingUser.Role, updateData.Role) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"message": "Only admins can change roles",
+		})
+	}
+
 	updateData.ID = objID
+	updateData.Version = existingUser.Version + 1
+	updateData.DeletedAt = nil
+	updateData.DeletedBy = nil
 
 	update := bson.M{
 		"$set": updateData,
 	}
 
-	result, err := uc.collection.UpdateOne(ctx, bson.M{"_id": objID}, update)
+	err = uc.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		return uc.updateUser(ctx, c, existingUser, update)
+	})
 	if err != nil {
+		if err == utils.ErrVersionConflict {
+			return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+				"message": "User has been modified",
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"message": "Failed to update user",
 			"error":   err.Error(),
 		})
 	}
 
-	if result.MatchedCount == 0 {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"message": "User not found",
-		})
-	}
-
+	utils.SetETag(c, updateData.Version)
 	return c.JSON(updateData)
 }
 
 func (uc *UserController) DeleteUser(c *fiber.Ctx) error {
-	ctx := context.TODO()
+	ctx := c.UserContext()
 
 	userID := c.Params("id")
 
@@ -161,19 +226,238 @@ func (uc *UserController) DeleteUser(c *fiber.Ctx) error {
 		})
 	}
 
-	result, err := uc.collection.DeleteOne(ctx, bson.M{"_id": objID})
+	var existingUser models.User
+	err = uc.collection.FindOne(ctx, utils.NotDeleted(bson.M{"_id": objID})).Decode(&existingUser)
 	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"message": "User not found",
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"message": "Failed to delete user",
+			"message": "Failed to get user",
 			"error":   err.Error(),
 		})
 	}
 
-	if result.DeletedCount == 0 {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"message": "User not found",
+	if !utils.IfMatch(c, existingUser.Version) {
+		return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+			"message": "User has been modified",
+		})
+	}
+
+	err = uc.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		if err := integrity.BeforeDelete(ctx, uc.db, "users", objID, utils.ActorID(c)); err != nil {
+			return err
+		}
+
+		result, err := uc.collection.UpdateOne(ctx, utils.VersionFilter(objID, existingUser.Version), utils.SoftDeleteUpdate(utils.ActorID(c)))
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return utils.ErrVersionConflict
+		}
+		return audit.Record(ctx, c, uc.db, models.AuditDelete, "users", objID, existingUser)
+	})
+	if err != nil {
+		if restrictErr, ok := err.(*integrity.RestrictError); ok {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"message":    "User is still referenced",
+				"error":      restrictErr.Error(),
+				"references": restrictErr.References,
+			})
+		}
+		if err == utils.ErrVersionConflict {
+			return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+				"message": "User has been modified",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to delete user",
+			"error":   err.Error(),
 		})
 	}
 
 	return c.SendStatus(fiber.StatusNoContent)
 }
+
+func (uc *UserController) PatchUser(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	userID := c.Params("id")
+
+	objID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid user ID",
+			"error":   err.Error(),
+		})
+	}
+
+	var existingUser models.User
+	err = uc.collection.FindOne(ctx, utils.NotDeleted(bson.M{"_id": objID})).Decode(&existingUser)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"message": "User not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to get user",
+			"error":   err.Error(),
+		})
+	}
+
+	if !utils.IfMatch(c, existingUser.Version) {
+		return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+			"message": "User has been modified",
+		})
+	}
+
+	patchedUser := new(models.User)
+	if err := utils.ApplyPatch(c, existingUser, patchedUser); err != nil {
+		if err == utils.ErrUnsupportedPatch {
+			return c.Status(fiber.StatusUnsupportedMediaType).JSON(fiber.Map{
+				"message": "Unsupported patch format",
+				"error":   err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"message": "Failed to apply patch",
+			"error":   err.Error(),
+		})
+	}
+
+	if !utils.CanSetRole(c, existingUser.Role, patchedUser.Role) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"message": "Only admins can change roles",
+		})
+	}
+
+	patchedUser.ID = objID
+	patchedUser.Password = existingUser.Password
+	patchedUser.Version = existingUser.Version + 1
+	patchedUser.DeletedAt = existingUser.DeletedAt
+	patchedUser.DeletedBy = existingUser.DeletedBy
+
+	if err := patchedUser.Validate(); err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"message": "Invalid user",
+			"error":   err.Error(),
+		})
+	}
+
+	update, err := utils.PatchUpdate(existingUser, patchedUser)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to update user",
+			"error":   err.Error(),
+		})
+	}
+
+	err = uc.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		return uc.updateUser(ctx, c, existingUser, update)
+	})
+	if err != nil {
+		if err == utils.ErrVersionConflict {
+			return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+				"message": "User has been modified",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to update user",
+			"error":   err.Error(),
+		})
+	}
+
+	utils.SetETag(c, patchedUser.Version)
+	return c.JSON(patchedUser)
+}
+
+func (uc *UserController) RestoreUser(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	userID := c.Params("id")
+
+	objID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid user ID",
+			"error":   err.Error(),
+		})
+	}
+
+	var deletedUser models.User
+	err = uc.collection.FindOne(ctx, utils.OnlyDeleted(bson.M{"_id": objID})).Decode(&deletedUser)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"message": "Deleted user not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to get user",
+			"error":   err.Error(),
+		})
+	}
+
+	if !utils.IfMatch(c, deletedUser.Version) {
+		return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+			"message": "User has been modified",
+		})
+	}
+
+	err = uc.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		if err := integrity.BeforeRestore(ctx, uc.db, "users", objID); err != nil {
+			return err
+		}
+
+		result, err := uc.collection.UpdateOne(ctx, utils.VersionFilter(objID, deletedUser.Version), utils.RestoreUpdate())
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return utils.ErrVersionConflict
+		}
+		return audit.Record(ctx, c, uc.db, models.AuditRestore, "users", objID, deletedUser)
+	})
+	if err != nil {
+		if parentErr, ok := err.(*integrity.DeletedParentError); ok {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"message": "User refers to deleted documents",
+				"error":   parentErr.Error(),
+				"parents": parentErr.Parents,
+			})
+		}
+		if err == utils.ErrVersionConflict {
+			return c.Status(fiber.StatusPreconditionFailed).JSON(fiber.Map{
+				"message": "User has been modified",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to restore user",
+			"error":   err.Error(),
+		})
+	}
+
+	deletedUser.DeletedAt = nil
+	deletedUser.DeletedBy = nil
+	deletedUser.Version++
+
+	utils.SetETag(c, deletedUser.Version)
+	return c.JSON(deletedUser)
+}
+
+// updateUser applies update to existingUser and records it in the audit log.
+// Run it inside UnitOfWork.Do.
+func (uc *UserController) updateUser(ctx context.Context, c *fiber.Ctx, existingUser models.User, update interface{}) error {
+	result, err := uc.collection.UpdateOne(ctx, utils.VersionFilter(existingUser.ID, existingUser.Version), update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return utils.ErrVersionConflict
+	}
+	return audit.Record(ctx, c, uc.db, models.AuditUpdate, "users", existingUser.ID, existingUser)
+}
@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aldoramirezmartinez/fiber-api/models"
+	"github.com/aldoramirezmartinez/fiber-api/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TestCreateUserRoleRequiresAdmin checks that only admins can create users
+// with a role. The rejected requests never reach the database.
+func TestCreateUserRoleRequiresAdmin(t *testing.T) {
+	tests := []struct {
+		name  string
+		actor *models.User
+	}{
+		{name: "anonymous"},
+		{name: "without role", actor: &models.User{ID: primitive.NewObjectID()}},
+		{name: "buyer", actor: &models.User{ID: primitive.NewObjectID(), Role: models.Role{Name: "buyer"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Use(func(c *fiber.Ctx) error {
+				if tt.actor != nil {
+					utils.SetActor(c, *tt.actor)
+				}
+				return c.Next()
+			})
+			app.Post("/api/users", (&UserController{}).CreateUser)
+
+			req := httptest.NewRequest(fiber.MethodPost, "/api/users", strings.NewReader(`{"name":"Ana","email":"ana@example.com","role":{"name":"admin"}}`))
+			req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != fiber.StatusForbidden {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, fiber.StatusForbidden)
+			}
+		})
+	}
+}
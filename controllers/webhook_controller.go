@@ -0,0 +1,340 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aldoramirezmartinez/fiber-api/audit"
+	"github.com/aldoramirezmartinez/fiber-api/models"
+	"github.com/aldoramirezmartinez/fiber-api/utils"
+	"github.com/aldoramirezmartinez/fiber-api/webhooks"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const maxListedDeliveries = 100
+
+type WebhookController struct {
+	db                     *mongo.Database
+	unitOfWork             *utils.UnitOfWork
+	subscriptionCollection *mongo.Collection
+	deliveryCollection     *mongo.Collection
+}
+
+func NewWebhookController(db *mongo.Database) *WebhookController {
+	if err := webhooks.EnsureIndexes(context.Background(), db); err != nil {
+		fmt.Println("Failed to create webhook indexes:", err)
+	}
+
+	return &WebhookController{
+		db:                     db,
+		unitOfWork:             utils.NewUnitOfWork(db),
+		subscriptionCollection: db.Collection(webhooks.SubscriptionCollectionName),
+		deliveryCollection:     db.Collection(webhooks.DeliveryCollectionName),
+	}
+}
+
+func (wc *WebhookController) GetAllWebhooks(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	cursor, err := wc.subscriptionCollection.Find(ctx, bson.M{})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to retrieve webhooks",
+			"error":   err.Error(),
+		})
+	}
+	defer cursor.Close(ctx)
+
+	subscriptions := []models.WebhookSubscription{}
+	if err := cursor.All(ctx, &subscriptions); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to decode webhooks",
+			"error":   err.Error(),
+		})
+	}
+
+	// Secrets are write-only.
+	for i := range subscriptions {
+		subscriptions[i].Secret = ""
+	}
+
+	return c.JSON(subscriptions)
+}
+
+func (wc *WebhookController) GetWebhook(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	objID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid webhook ID",
+			"error":   err.Error(),
+		})
+	}
+
+	var subscription models.WebhookSubscription
+	err = wc.subscriptionCollection.FindOne(ctx, bson.M{"_id": objID}).Decode(&subscription)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"message": "Webhook not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to retrieve webhook",
+			"error":   err.Error(),
+		})
+	}
+
+	subscription.Secret = ""
+	return c.JSON(subscription)
+}
+
+func (wc *WebhookController) CreateWebhook(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	subscription := new(models.WebhookSubscription)
+	if err := c.BodyParser(subscription); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid request body",
+			"error":   err.Error(),
+		})
+	}
+
+	if err := subscription.Validate(); err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"message": "Invalid webhook",
+			"error":   err.Error(),
+		})
+	}
+
+	subscription.ID = primitive.NewObjectID()
+	subscription.Active = true
+	subscription.CreatedAt = time.Now()
+
+	err := wc.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		if _, err := wc.subscriptionCollection.InsertOne(ctx, subscription); err != nil {
+			return err
+		}
+		return audit.Record(ctx, c, wc.db, models.AuditCreate, webhooks.SubscriptionCollectionName, subscription.ID, nil)
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to create webhook",
+			"error":   err.Error(),
+		})
+	}
+
+	subscription.Secret = ""
+	return c.Status(fiber.StatusCreated).JSON(subscription)
+}
+
+func (wc *WebhookController) UpdateWebhook(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	objID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid webhook ID",
+			"error":   err.Error(),
+		})
+	}
+
+	var existingSubscription models.WebhookSubscription
+	err = wc.subscriptionCollection.FindOne(ctx, bson.M{"_id": objID}).Decode(&existingSubscription)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"message": "Webhook not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to retrieve webhook",
+			"error":   err.Error(),
+		})
+	}
+
+	subscription := new(models.WebhookSubscription)
+	if err := c.BodyParser(subscription); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid request body",
+			"error":   err.Error(),
+		})
+	}
+
+	// Keep the current secret unless a new one is sent.
+	if subscription.Secret == "" {
+		subscription.Secret = existingSubscription.Secret
+	}
+	subscription.ID = objID
+	subscription.CreatedAt = existingSubscription.CreatedAt
+
+	if err := subscription.Validate(); err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"message": "Invalid webhook",
+			"error":   err.Error(),
+		})
+	}
+
+	err = wc.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		if _, err := wc.subscriptionCollection.ReplaceOne(ctx, bson.M{"_id": objID}, subscription); err != nil {
+			return err
+		}
+		return audit.Record(ctx, c, wc.db, models.AuditUpdate, webhooks.SubscriptionCollectionName, objID, existingSubscription)
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to update webhook",
+			"error":   err.Error(),
+		})
+	}
+
+	subscription.Secret = ""
+	return c.JSON(subscription)
+}
+
+func (wc *WebhookController) DeleteWebhook(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	objID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid webhook ID",
+			"error":   err.Error(),
+		})
+	}
+
+	err = wc.unitOfWork.Do(ctx, func(ctx context.Context) error {
+		var existingSubscription models.WebhookSubscription
+		err := wc.subscriptionCollection.FindOne(ctx, bson.M{"_id": objID}).Decode(&existingSubscription)
+		if err != nil {
+			return err
+		}
+
+		if _, err := wc.subscriptionCollection.DeleteOne(ctx, bson.M{"_id": objID}); err != nil {
+			return err
+		}
+		return audit.Record(ctx, c, wc.db, models.AuditDelete, webhooks.SubscriptionCollectionName, objID, existingSubscription)
+	})
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"message": "Webhook not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to delete webhook",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (wc *WebhookController) GetWebhookDeliveries(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	objID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid webhook ID",
+			"error":   err.Error(),
+		})
+	}
+
+	filter := bson.M{"subscription_id": objID}
+	if status := c.Query("status"); status != "" {
+		filter["status"] = status
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.M{"created_at": -1}).
+		SetLimit(maxListedDeliveries)
+
+	cursor, err := wc.deliveryCollection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to retrieve deliveries",
+			"error":   err.Error(),
+		})
+	}
+	defer cursor.Close(ctx)
+
+	deliveries := []models.WebhookDelivery{}
+	if err := cursor.All(ctx, &deliveries); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to decode deliveries",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.JSON(deliveries)
+}
+
+func (wc *WebhookController) PingWebhook(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	objID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid webhook ID",
+			"error":   err.Error(),
+		})
+	}
+
+	var subscription models.WebhookSubscription
+	err = wc.subscriptionCollection.FindOne(ctx, bson.M{"_id": objID}).Decode(&subscription)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"message": "Webhook not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to retrieve webhook",
+			"error":   err.Error(),
+		})
+	}
+
+	delivery, err := webhooks.Ping(ctx, wc.db, subscription)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to queue ping",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(delivery)
+}
+
+func (wc *WebhookController) RedeliverWebhook(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+
+	objID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"message": "Invalid delivery ID",
+			"error":   err.Error(),
+		})
+	}
+
+	err = webhooks.Redeliver(ctx, wc.db, objID)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"message": "Delivery not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"message": "Failed to redeliver webhook",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.SendStatus(fiber.StatusAccepted)
+}
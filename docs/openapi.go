@@ -0,0 +1,331 @@
+package docs
+
+import (
+	"embed"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aldoramirezmartinez/fiber-api/money"
+	"github.com/aldoramirezmartinez/fiber-api/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+//go:embed swagger.html
+var assets embed.FS
+
+// Operation describes one registered route. Request and Response hold a zero
+// value of the body model; their schemas are derived from the json tags.
+type Operation struct {
+	Method       string
+	Path         string
+	Tag          string
+	Summary      string
+	Query        []Parameter
+	Request      interface{}
+	RequestTypes []string
+	Response     interface{}
+	ResponseType string
+	Status       int
+	Errors       []int
+	Idempotent   bool
+}
+
+type Parameter struct {
+	Name        string
+	Description string
+	Type        string
+}
+
+// JSONPatchOperation documents one operation of an RFC 6902 JSON Patch.
+type JSONPatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+	From  string      `json:"from,omitempty"`
+}
+
+type ErrorResponse struct {
+	Message string `json:"message"`
+	Error   string `json:"error,omitempty"`
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	objectIDType = reflect.TypeOf(primitive.ObjectID{})
+	amountType   = reflect.TypeOf(money.Amount{})
+)
+
+// Spec returns the OpenAPI 3 document for every operation in Operations.
+func Spec() fiber.Map {
+	schemas := fiber.Map{}
+	paths := fiber.Map{}
+
+	schemaRef(reflect.TypeOf(ErrorResponse{}), schemas)
+
+	for _, op := range Operations {
+		path := openAPIPath(op.Path)
+		item, ok := paths[path].(fiber.Map)
+		if !ok {
+			item = fiber.Map{}
+			paths[path] = item
+		}
+		item[strings.ToLower(op.Method)] = buildOperation(op, schemas)
+	}
+
+	return fiber.Map{
+		"openapi": "3.0.3",
+		"info": fiber.Map{
+			"title":   "Fiber API",
+			"version": "1.0.0",
+		},
+		"paths": paths,
+		"components": fiber.Map{
+			"schemas": schemas,
+		},
+	}
+}
+
+// SwaggerUI returns the HTML page that renders /openapi.json.
+func SwaggerUI() ([]byte, error) {
+	return assets.ReadFile("swagger.html")
+}
+
+// MissingRoutes lists the registered routes that have no Operation.
+func MissingRoutes(routes []fiber.Route) []string {
+	documented := map[string]bool{}
+	for _, op := range Operations {
+		documented[op.Method+" "+normalizePath(op.Path)] = true
+	}
+
+	var missing []string
+	seen := map[string]bool{}
+	for _, route := range routes {
+		if route.Method == fiber.MethodHead {
+			continue
+		}
+		key := route.Method + " " + normalizePath(route.Path)
+		if !documented[key] && !seen[key] {
+			missing = append(missing, key)
+			seen[key] = true
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+func buildOperation(op Operation, schemas fiber.Map) fiber.Map {
+	var parameters []fiber.Map
+	for _, segment := range strings.Split(op.Path, "/") {
+		if strings.HasPrefix(segment, ":") {
+			parameters = append(parameters, fiber.Map{
+				"name":     strings.TrimSuffix(segment[1:], "?"),
+				"in":       "path",
+				"required": true,
+				"schema":   fiber.Map{"type": "string"},
+			})
+		}
+	}
+	for _, query := range op.Query {
+		queryType := query.Type
+		if queryType == "" {
+			queryType = "string"
+		}
+		parameters = append(parameters, fiber.Map{
+			"name":        query.Name,
+			"in":          "query",
+			"description": query.Description,
+			"schema":      fiber.Map{"type": queryType},
+		})
+	}
+
+	if op.Idempotent {
+		parameters = append(parameters, fiber.Map{
+			"name":        "Idempotency-Key",
+			"in":          "header",
+			"description": "Replays the stored response when the request is retried with the same key",
+			"schema":      fiber.Map{"type": "string"},
+		})
+		op.Errors = append([]int{fiber.StatusConflict, fiber.StatusUnprocessableEntity}, op.Errors...)
+	}
+
+	operation := fiber.Map{
+		"tags":        []string{op.Tag},
+		"summary":     op.Summary,
+		"operationId": operationID(op),
+	}
+	if len(parameters) > 0 {
+		operation["parameters"] = parameters
+	}
+
+	if op.Request != nil {
+		requestTypes := op.RequestTypes
+		if len(requestTypes) == 0 {
+			requestTypes = []string{fiber.MIMEApplicationJSON}
+		}
+		content := fiber.Map{}
+		for _, requestType := range requestTypes {
+			request := op.Request
+			if requestType == utils.MIMEJSONPatch {
+				request = []JSONPatchOperation{}
+			}
+			content[requestType] = fiber.Map{"schema": schemaRef(reflect.TypeOf(request), schemas)}
+		}
+		operation["requestBody"] = fiber.Map{
+			"required": true,
+			"content":  content,
+		}
+	}
+
+	status := op.Status
+	if status == 0 {
+		status = fiber.StatusOK
+	}
+	success := fiber.Map{"description": http.StatusText(status)}
+	if op.Response != nil {
+		responseType := op.ResponseType
+		if responseType == "" {
+			responseType = fiber.MIMEApplicationJSON
+		}
+		success["content"] = fiber.Map{
+			responseType: fiber.Map{"schema": schemaRef(reflect.TypeOf(op.Response), schemas)},
+		}
+	}
+	responses := fiber.Map{strconv.Itoa(status): success}
+
+	errors := []int{fiber.StatusInternalServerError}
+	if op.Request != nil || len(parameters) > 0 {
+		errors = append(errors, fiber.StatusBadRequest)
+	}
+	if strings.Contains(op.Path, ":") {
+		errors = append(errors, fiber.StatusNotFound)
+	}
+	errors = append(errors, op.Errors...)
+	for _, code := range errors {
+		if code == fiber.StatusNotModified {
+			responses[strconv.Itoa(code)] = fiber.Map{"description": http.StatusText(code)}
+			continue
+		}
+		responses[strconv.Itoa(code)] = fiber.Map{
+			"description": http.StatusText(code),
+			"content": fiber.Map{
+				fiber.MIMEApplicationJSON: fiber.Map{"schema": fiber.Map{"$ref": "#/components/schemas/ErrorResponse"}},
+			},
+		}
+	}
+	operation["responses"] = responses
+
+	return operation
+}
+
+// schemaRef returns an inline schema for primitives and a $ref for structs,
+// registering struct schemas in components as a side effect.
+func schemaRef(t reflect.Type, schemas fiber.Map) fiber.Map {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return fiber.Map{"type": "string", "format": "date-time"}
+	case objectIDType:
+		return fiber.Map{"type": "string", "pattern": "^[0-9a-f]{24}$"}
+	case amountType:
+		return fiber.Map{"type": "string", "format": "decimal", "example": "1250.50"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return fiber.Map{"type": "string"}
+	case reflect.Bool:
+		return fiber.Map{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return fiber.Map{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return fiber.Map{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return fiber.Map{"type": "array", "items": schemaRef(t.Elem(), schemas)}
+	case reflect.Map:
+		return fiber.Map{"type": "object", "additionalProperties": schemaRef(t.Elem(), schemas)}
+	case reflect.Interface:
+		return fiber.Map{}
+	case reflect.Struct:
+		name := t.Name()
+		if _, ok := schemas[name]; !ok {
+			// Reserve the name first so recursive types terminate.
+			schemas[name] = fiber.Map{}
+			schemas[name] = structSchema(t, schemas)
+		}
+		return fiber.Map{"$ref": "#/components/schemas/" + name}
+	}
+
+	return fiber.Map{}
+}
+
+func structSchema(t reflect.Type, schemas fiber.Map) fiber.Map {
+	properties := fiber.Map{}
+	var required []string
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = schemaRef(field.Type, schemas)
+		if !strings.Contains(options, "omitempty") && field.Type.Kind() != reflect.Ptr {
+			required = append(required, name)
+		}
+	}
+
+	schema := fiber.Map{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func openAPIPath(path string) string {
+	segments := strings.Split(normalizePath(path), "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			segments[i] = "{" + strings.TrimSuffix(segment[1:], "?") + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func normalizePath(path string) string {
+	if len(path) > 1 {
+		return strings.TrimRight(path, "/")
+	}
+	return path
+}
+
+func operationID(op Operation) string {
+	id := strings.ToLower(op.Method)
+	for _, segment := range strings.Split(normalizePath(op.Path), "/") {
+		segment = strings.TrimPrefix(strings.TrimSuffix(segment, "?"), ":")
+		for _, part := range strings.FieldsFunc(segment, func(r rune) bool { return r == '_' || r == '-' }) {
+			id += strings.ToUpper(part[:1]) + part[1:]
+		}
+	}
+	return id
+}
@@ -0,0 +1,20 @@
+package docs_test
+
+import (
+	"testing"
+
+	"github.com/aldoramirezmartinez/fiber-api/docs"
+	"github.com/aldoramirezmartinez/fiber-api/routes"
+	"github.com/gofiber/fiber/v2"
+)
+
+// TestEveryRouteIsDocumented registers the routes the way App.Run does and
+// fails when one of them has no Operation.
+func TestEveryRouteIsDocumented(t *testing.T) {
+	app := fiber.New()
+	routes.Setup(app, routes.Controllers{})
+
+	if missing := docs.MissingRoutes(app.GetRoutes(true)); len(missing) > 0 {
+		t.Fatalf("routes missing from the OpenAPI document: %v", missing)
+	}
+}
@@ -0,0 +1,192 @@
+package docs
+
+import (
+	"github.com/aldoramirezmartinez/fiber-api/audit"
+	"github.com/aldoramirezmartinez/fiber-api/integrity"
+	"github.com/aldoramirezmartinez/fiber-api/models"
+	"github.com/aldoramirezmartinez/fiber-api/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+var patchTypes = []string{utils.MIMEMergePatch, utils.MIMEJSONPatch}
+
+var includeDeleted = []Parameter{
+	{Name: "include_deleted", Description: "Include soft-deleted documents (admins only)", Type: "boolean"},
+}
+
+var includeCurrentItem = Parameter{Name: "include", Description: "current_item adds each line's item as it is now next to its snapshot", Type: "string"}
+
+var purchaseQuery = append([]Parameter{includeCurrentItem}, includeDeleted...)
+
+var auditFilters = []Parameter{
+	{Name: "entity", Description: "Collection name, such as items or purchases", Type: "string"},
+	{Name: "entity_id", Description: "Document ID", Type: "string"},
+	{Name: "actor", Description: "ID of the user who made the change", Type: "string"},
+	{Name: "from", Description: "Earliest timestamp (RFC 3339), inclusive", Type: "string"},
+	{Name: "to", Description: "Latest timestamp (RFC 3339), exclusive", Type: "string"},
+	{Name: "limit", Description: "Maximum entries to return, newest first (default 100, max 1000)", Type: "integer"},
+}
+
+var liveFilters = []Parameter{
+	{Name: "types", Description: "Comma-separated purchases and/or items (default both)", Type: "string"},
+	{Name: "provider", Description: "Only purchases and items of this provider ID", Type: "string"},
+	{Name: "user", Description: "Only purchases of this user ID", Type: "string"},
+	{Name: "status", Description: "Comma-separated purchase statuses", Type: "string"},
+	{Name: "last_event_id", Description: "Resume after this message ID", Type: "string"},
+	{Name: "include_deleted", Description: "Send the data of deleted documents (admins only)", Type: "boolean"},
+}
+
+var jobFilters = []Parameter{
+	{Name: "status", Description: "pending, running, succeeded or dead", Type: "string"},
+	{Name: "type", Description: "Job type", Type: "string"},
+	{Name: "limit", Description: "Maximum jobs to return, latest run_at first (default 100, max 1000)", Type: "integer"},
+}
+
+var exchangeRateFilters = []Parameter{
+	{Name: "from", Description: "Source currency code", Type: "string"},
+	{Name: "to", Description: "Target currency code", Type: "string"},
+	{Name: "date", Description: "Only the rates in effect on this date (YYYY-MM-DD or RFC 3339)", Type: "string"},
+}
+
+var purchaseReportFilters = []Parameter{
+	{Name: "currency", Description: "Currency to report in (default BASE_CURRENCY)", Type: "string"},
+	{Name: "from", Description: "Purchases dated on or after this date (YYYY-MM-DD or RFC 3339)", Type: "string"},
+	{Name: "to", Description: "Purchases dated before this date (YYYY-MM-DD or RFC 3339)", Type: "string"},
+	{Name: "status", Description: "Purchase status", Type: "string"},
+}
+
+var taxRuleFilters = []Parameter{
+	{Name: "category", Description: "Item category", Type: "string"},
+	{Name: "provider_type", Description: "company or individual", Type: "string"},
+}
+
+var providerPriceFilters = []Parameter{
+	{Name: "item_id", Description: "Item ID", Type: "string"},
+	{Name: "provider_id", Description: "Provider ID", Type: "string"},
+	{Name: "valid_at", Description: "Only the offers valid on this date (YYYY-MM-DD or RFC 3339)", Type: "string"},
+}
+
+var bestSourceQuery = []Parameter{
+	{Name: "quantity", Description: "Quantity to buy (default 1)", Type: "integer"},
+	{Name: "currency", Description: "Currency to compare offers in (default BASE_CURRENCY)", Type: "string"},
+	{Name: "date", Description: "Date the offers must be valid on (YYYY-MM-DD or RFC 3339), now by default", Type: "string"},
+}
+
+var rfqFilters = []Parameter{
+	{Name: "status", Description: "open, awarded or cancelled", Type: "string"},
+	{Name: "provider_id", Description: "Only RFQs sent to this provider", Type: "string"},
+}
+
+var requisitionFilters = []Parameter{
+	{Name: "status", Description: "pending, approved, rejected, converted or cancelled", Type: "string"},
+	{Name: "requester_id", Description: "Only requisitions of this user ID", Type: "string"},
+}
+
+var revisionDiffQuery = append([]Parameter{
+	{Name: "from", Description: "Revision to compare from; defaults to the one before to", Type: "integer"},
+	{Name: "to", Description: "Revision to compare to; defaults to the current one", Type: "integer"},
+}, includeDeleted...)
+
+var webhookErrors = []int{fiber.StatusForbidden, fiber.StatusUnprocessableEntity}
+
+var patchErrors = []int{fiber.StatusPreconditionFailed, fiber.StatusUnsupportedMediaType, fiber.StatusUnprocessableEntity}
+
+// Operations documents every route registered in the routes package. Keep it
+// in sync when adding a route; TestEveryRouteIsDocumented fails and the server
+// reports undocumented routes on start.
+var Operations = []Operation{
+	{Method: fiber.MethodGet, Path: "/openapi.json", Tag: "docs", Summary: "OpenAPI document", Response: map[string]interface{}{}},
+	{Method: fiber.MethodGet, Path: "/docs", Tag: "docs", Summary: "Swagger UI"},
+
+	{Method: fiber.MethodGet, Path: "/api/users", Tag: "users", Summary: "List users", Query: includeDeleted, Response: []models.User{}},
+	{Method: fiber.MethodGet, Path: "/api/users/:id", Tag: "users", Summary: "Get a user", Query: includeDeleted, Response: models.User{}, Errors: []int{fiber.StatusNotModified}},
+	{Method: fiber.MethodPost, Path: "/api/users", Tag: "users", Summary: "Create a user", Request: models.User{}, Response: models.User{}, Errors: []int{fiber.StatusForbidden, fiber.StatusUnprocessableEntity}, Idempotent: true},
+	{Method: fiber.MethodPut, Path: "/api/users/:id", Tag: "users", Summary: "Update a user", Request: models.User{}, Response: models.User{}, Errors: []int{fiber.StatusForbidden, fiber.StatusPreconditionFailed, fiber.StatusUnprocessableEntity}},
+	{Method: fiber.MethodPatch, Path: "/api/users/:id", Tag: "users", Summary: "Partially update a user", Request: models.User{}, RequestTypes: patchTypes, Response: models.User{}, Errors: append([]int{fiber.StatusForbidden}, patchErrors...)},
+	{Method: fiber.MethodDelete, Path: "/api/users/:id", Tag: "users", Summary: "Delete a user", Status: fiber.StatusNoContent, Errors: []int{fiber.StatusPreconditionFailed, fiber.StatusConflict}},
+	{Method: fiber.MethodPost, Path: "/api/users/:id/restore", Tag: "users", Summary: "Restore a deleted user", Response: models.User{}, Errors: []int{fiber.StatusPreconditionFailed, fiber.StatusConflict}},
+
+	{Method: fiber.MethodGet, Path: "/api/providers", Tag: "providers", Summary: "List providers", Query: includeDeleted, Response: []models.Provider{}},
+	{Method: fiber.MethodGet, Path: "/api/providers/:id", Tag: "providers", Summary: "Get a provider", Query: includeDeleted, Response: models.Provider{}, Errors: []int{fiber.StatusNotModified}},
+	{Method: fiber.MethodPost, Path: "/api/providers", Tag: "providers", Summary: "Create a provider", Request: models.Provider{}, Response: models.Provider{}, Errors: []int{fiber.StatusUnprocessableEntity}, Idempotent: true},
+	{Method: fiber.MethodPut, Path: "/api/providers/:id", Tag: "providers", Summary: "Update a provider", Request: models.Provider{}, Response: models.Provider{}, Errors: []int{fiber.StatusPreconditionFailed, fiber.StatusUnprocessableEntity}},
+	{Method: fiber.MethodPatch, Path: "/api/providers/:id", Tag: "providers", Summary: "Partially update a provider", Request: models.Provider{}, RequestTypes: patchTypes, Response: models.Provider{}, Errors: patchErrors},
+	{Method: fiber.MethodDelete, Path: "/api/providers/:id", Tag: "providers", Summary: "Delete a provider", Status: fiber.StatusNoContent, Errors: []int{fiber.StatusPreconditionFailed, fiber.StatusConflict}},
+	{Method: fiber.MethodPost, Path: "/api/providers/:id/restore", Tag: "providers", Summary: "Restore a deleted provider", Response: models.Provider{}, Errors: []int{fiber.StatusPreconditionFailed, fiber.StatusConflict}},
+
+	{Method: fiber.MethodGet, Path: "/api/items", Tag: "items", Summary: "List items with their provider", Query: includeDeleted, Response: []models.ItemResponse{}},
+	{Method: fiber.MethodGet, Path: "/api/items/:id", Tag: "items", Summary: "Get an item with its provider", Query: includeDeleted, Response: models.ItemResponse{}, Errors: []int{fiber.StatusNotModified}},
+	{Method: fiber.MethodPost, Path: "/api/items", Tag: "items", Summary: "Create an item", Request: models.Item{}, Response: models.ItemResponse{}, Errors: []int{fiber.StatusUnprocessableEntity}, Idempotent: true},
+	{Method: fiber.MethodPut, Path: "/api/items/:id", Tag: "items", Summary: "Update an item", Request: models.Item{}, Response: models.ItemResponse{}, Errors: []int{fiber.StatusPreconditionFailed, fiber.StatusUnprocessableEntity}},
+	{Method: fiber.MethodPatch, Path: "/api/items/:id", Tag: "items", Summary: "Partially update an item", Request: models.Item{}, RequestTypes: patchTypes, Response: models.ItemResponse{}, Errors: patchErrors},
+	{Method: fiber.MethodDelete, Path: "/api/items/:id", Tag: "items", Summary: "Delete an item", Status: fiber.StatusNoContent, Errors: []int{fiber.StatusPreconditionFailed, fiber.StatusConflict}},
+	{Method: fiber.MethodPost, Path: "/api/items/:id/restore", Tag: "items", Summary: "Restore a deleted item", Response: models.ItemResponse{}, Errors: []int{fiber.StatusPreconditionFailed, fiber.StatusConflict}},
+	{Method: fiber.MethodGet, Path: "/api/items/:id/prices", Tag: "items", Summary: "List the price history of an item, latest first", Query: []Parameter{{Name: "status", Description: "applied, scheduled or cancelled", Type: "string"}}, Response: []models.ItemPrice{}},
+	{Method: fiber.MethodPost, Path: "/api/items/:id/prices", Tag: "items", Summary: "Change, backdate or schedule the price of an item", Request: models.ItemPriceChangeRequest{}, Response: models.ItemPrice{}, Status: fiber.StatusCreated, Errors: []int{fiber.StatusPreconditionFailed, fiber.StatusUnprocessableEntity}, Idempotent: true},
+	{Method: fiber.MethodDelete, Path: "/api/items/:id/prices/:price_id", Tag: "items", Summary: "Cancel a scheduled price", Response: models.ItemPrice{}, Errors: []int{fiber.StatusPreconditionFailed, fiber.StatusConflict}},
+	{Method: fiber.MethodGet, Path: "/api/items/:id/price", Tag: "items", Summary: "Get the price of an item on a date", Query: []Parameter{{Name: "at", Description: "Date (YYYY-MM-DD or RFC 3339), now by default", Type: "string"}}, Response: models.ItemPriceAt{}},
+	{Method: fiber.MethodGet, Path: "/api/items/:id/best-source", Tag: "items", Summary: "Find the cheapest provider offer for a quantity of an item", Query: bestSourceQuery, Response: models.BestSource{}, Errors: []int{fiber.StatusNotFound, fiber.StatusUnprocessableEntity}},
+
+	{Method: fiber.MethodGet, Path: "/api/purchases", Tag: "purchases", Summary: "List purchases", Query: purchaseQuery, Response: []models.PurchaseResponsev2{}},
+	{Method: fiber.MethodGet, Path: "/api/purchases/:purchase_order", Tag: "purchases", Summary: "Get a purchase by purchase order", Query: purchaseQuery, Response: models.PurchaseResponsev2{}, Errors: []int{fiber.StatusNotModified}},
+	{Method: fiber.MethodPost, Path: "/api/purchases", Tag: "purchases", Summary: "Create a purchase", Query: []Parameter{includeCurrentItem}, Request: models.Purchasev2{}, Response: models.PurchaseResponsev2{}, Errors: []int{fiber.StatusUnprocessableEntity}, Idempotent: true},
+	{Method: fiber.MethodPut, Path: "/api/purchases/:id", Tag: "purchases", Summary: "Update a purchase", Query: []Parameter{includeCurrentItem}, Request: models.Purchasev2{}, Response: models.PurchaseResponsev2{}, Errors: []int{fiber.StatusPreconditionFailed, fiber.StatusUnprocessableEntity}},
+	{Method: fiber.MethodPatch, Path: "/api/purchases/:id", Tag: "purchases", Summary: "Partially update a purchase", Query: []Parameter{includeCurrentItem}, Request: models.Purchasev2{}, RequestTypes: patchTypes, Response: models.PurchaseResponsev2{}, Errors: patchErrors},
+	{Method: fiber.MethodDelete, Path: "/api/purchases/:id", Tag: "purchases", Summary: "Delete a purchase", Status: fiber.StatusNoContent, Errors: []int{fiber.StatusPreconditionFailed, fiber.StatusConflict}},
+	{Method: fiber.MethodPost, Path: "/api/purchases/:id/restore", Tag: "purchases", Summary: "Restore a deleted purchase", Query: []Parameter{includeCurrentItem}, Response: models.PurchaseResponsev2{}, Errors: []int{fiber.StatusPreconditionFailed, fiber.StatusConflict}},
+	{Method: fiber.MethodGet, Path: "/api/purchases/:id/revisions", Tag: "purchases", Summary: "List the revisions of a purchase, oldest first", Query: includeDeleted, Response: []models.PurchaseRevision{}},
+	{Method: fiber.MethodGet, Path: "/api/purchases/:id/revisions/diff", Tag: "purchases", Summary: "Compare two revisions of a purchase", Query: revisionDiffQuery, Response: models.PurchaseRevisionDiff{}},
+	{Method: fiber.MethodGet, Path: "/api/purchases/:id/revisions/:revision", Tag: "purchases", Summary: "Get a revision of a purchase", Query: includeDeleted, Response: models.PurchaseRevision{}},
+
+	{Method: fiber.MethodGet, Path: "/api/admin/integrity", Tag: "admin", Summary: "Check data integrity (admins only)", Response: integrity.Report{}, Errors: []int{fiber.StatusForbidden}},
+	{Method: fiber.MethodPost, Path: "/api/admin/integrity/repair", Tag: "admin", Summary: "Repair data integrity problems (admins only)", Response: integrity.Report{}, Errors: []int{fiber.StatusForbidden}},
+	{Method: fiber.MethodGet, Path: "/api/admin/jobs", Tag: "admin", Summary: "List background jobs (admins only)", Query: jobFilters, Response: []models.Job{}, Errors: []int{fiber.StatusForbidden}},
+	{Method: fiber.MethodGet, Path: "/api/admin/jobs/:id", Tag: "admin", Summary: "Get a background job (admins only)", Response: models.Job{}, Errors: []int{fiber.StatusForbidden}},
+	{Method: fiber.MethodPost, Path: "/api/admin/jobs/:id/retry", Tag: "admin", Summary: "Retry a dead job or run a pending one now (admins only)", Response: models.Job{}, Status: fiber.StatusAccepted, Errors: []int{fiber.StatusForbidden, fiber.StatusConflict}},
+
+	{Method: fiber.MethodGet, Path: "/api/audit", Tag: "admin", Summary: "List audit entries (admins only)", Query: auditFilters, Response: []models.AuditEntry{}, Errors: []int{fiber.StatusForbidden}},
+	{Method: fiber.MethodGet, Path: "/api/audit/verify", Tag: "admin", Summary: "Verify the audit hash chain (admins only)", Response: audit.Verification{}, Errors: []int{fiber.StatusForbidden}},
+
+	{Method: fiber.MethodGet, Path: "/api/webhooks", Tag: "webhooks", Summary: "List webhook subscriptions (admins only)", Response: []models.WebhookSubscription{}, Errors: []int{fiber.StatusForbidden}},
+	{Method: fiber.MethodGet, Path: "/api/webhooks/:id", Tag: "webhooks", Summary: "Get a webhook subscription (admins only)", Response: models.WebhookSubscription{}, Errors: []int{fiber.StatusForbidden}},
+	{Method: fiber.MethodPost, Path: "/api/webhooks", Tag: "webhooks", Summary: "Create a webhook subscription (admins only)", Request: models.WebhookSubscription{}, Response: models.WebhookSubscription{}, Status: fiber.StatusCreated, Errors: webhookErrors, Idempotent: true},
+	{Method: fiber.MethodPut, Path: "/api/webhooks/:id", Tag: "webhooks", Summary: "Update a webhook subscription (admins only)", Request: models.WebhookSubscription{}, Response: models.WebhookSubscription{}, Errors: webhookErrors},
+	{Method: fiber.MethodDelete, Path: "/api/webhooks/:id", Tag: "webhooks", Summary: "Delete a webhook subscription (admins only)", Status: fiber.StatusNoContent, Errors: []int{fiber.StatusForbidden}},
+	{Method: fiber.MethodGet, Path: "/api/webhooks/:id/deliveries", Tag: "webhooks", Summary: "List recent deliveries of a subscription (admins only)", Query: []Parameter{{Name: "status", Description: "pending, succeeded or failed", Type: "string"}}, Response: []models.WebhookDelivery{}, Errors: []int{fiber.StatusForbidden}},
+	{Method: fiber.MethodPost, Path: "/api/webhooks/:id/ping", Tag: "webhooks", Summary: "Queue a ping event (admins only)", Response: models.WebhookDelivery{}, Status: fiber.StatusAccepted, Errors: []int{fiber.StatusForbidden}},
+	{Method: fiber.MethodPost, Path: "/api/webhooks/deliveries/:id/redeliver", Tag: "webhooks", Summary: "Send a delivery again (admins only)", Status: fiber.StatusAccepted, Errors: []int{fiber.StatusForbidden}},
+
+	{Method: fiber.MethodGet, Path: "/api/live/events", Tag: "live", Summary: "Stream purchase and item changes as Server-Sent Events", Query: liveFilters, Response: models.LiveMessage{}, ResponseType: "text/event-stream", Errors: []int{fiber.StatusServiceUnavailable}},
+	{Method: fiber.MethodGet, Path: "/api/live/ws", Tag: "live", Summary: "Stream purchase and item changes over a WebSocket", Query: liveFilters, Response: models.LiveMessage{}, Status: fiber.StatusSwitchingProtocols, Errors: []int{fiber.StatusUpgradeRequired}},
+
+	{Method: fiber.MethodGet, Path: "/api/exchange-rates", Tag: "currencies", Summary: "List exchange rates", Query: exchangeRateFilters, Response: []models.ExchangeRate{}},
+	{Method: fiber.MethodPost, Path: "/api/exchange-rates", Tag: "currencies", Summary: "Load exchange rates from JSON or CSV (admins only)", Request: []models.ExchangeRate{}, RequestTypes: []string{fiber.MIMEApplicationJSON, "text/csv"}, Response: []models.ExchangeRate{}, Status: fiber.StatusCreated, Errors: []int{fiber.StatusForbidden, fiber.StatusUnprocessableEntity}},
+	{Method: fiber.MethodGet, Path: "/api/reports/purchases", Tag: "currencies", Summary: "Total purchases in a currency and list each purchase with its revision", Query: purchaseReportFilters, Response: models.PurchaseReport{}, Errors: []int{fiber.StatusUnprocessableEntity}},
+
+	{Method: fiber.MethodGet, Path: "/api/tax-rules", Tag: "taxes", Summary: "List tax rules", Query: taxRuleFilters, Response: []models.TaxRule{}},
+	{Method: fiber.MethodGet, Path: "/api/tax-rules/:id", Tag: "taxes", Summary: "Get a tax rule", Response: models.TaxRule{}},
+	{Method: fiber.MethodPost, Path: "/api/tax-rules", Tag: "taxes", Summary: "Create a tax rule (admins only)", Request: models.TaxRule{}, Response: models.TaxRule{}, Status: fiber.StatusCreated, Errors: []int{fiber.StatusForbidden, fiber.StatusUnprocessableEntity}, Idempotent: true},
+	{Method: fiber.MethodPut, Path: "/api/tax-rules/:id", Tag: "taxes", Summary: "Replace a tax rule (admins only)", Request: models.TaxRule{}, Response: models.TaxRule{}, Errors: []int{fiber.StatusForbidden, fiber.StatusUnprocessableEntity}},
+	{Method: fiber.MethodDelete, Path: "/api/tax-rules/:id", Tag: "taxes", Summary: "Delete a tax rule (admins only)", Status: fiber.StatusNoContent, Errors: []int{fiber.StatusForbidden}},
+
+	{Method: fiber.MethodGet, Path: "/api/provider-prices", Tag: "sourcing", Summary: "List provider price list offers", Query: providerPriceFilters, Response: []models.ProviderPrice{}},
+	{Method: fiber.MethodGet, Path: "/api/provider-prices/:id", Tag: "sourcing", Summary: "Get a provider price list offer", Response: models.ProviderPrice{}},
+	{Method: fiber.MethodPost, Path: "/api/provider-prices", Tag: "sourcing", Summary: "Add an offer to a provider price list", Request: models.ProviderPriceRequest{}, Response: models.ProviderPrice{}, Status: fiber.StatusCreated, Errors: []int{fiber.StatusUnprocessableEntity}, Idempotent: true},
+	{Method: fiber.MethodPut, Path: "/api/provider-prices/:id", Tag: "sourcing", Summary: "Replace a provider price list offer", Request: models.ProviderPriceRequest{}, Response: models.ProviderPrice{}, Errors: []int{fiber.StatusUnprocessableEntity}},
+	{Method: fiber.MethodDelete, Path: "/api/provider-prices/:id", Tag: "sourcing", Summary: "Delete a provider price list offer", Status: fiber.StatusNoContent},
+	{Method: fiber.MethodGet, Path: "/api/rfqs", Tag: "sourcing", Summary: "List requests for quotation, latest first", Query: rfqFilters, Response: []models.RFQ{}},
+	{Method: fiber.MethodGet, Path: "/api/rfqs/:id", Tag: "sourcing", Summary: "Get a request for quotation with its quotes", Response: models.RFQ{}},
+	{Method: fiber.MethodPost, Path: "/api/rfqs", Tag: "sourcing", Summary: "Send a request for quotation to several providers", Request: models.RFQRequest{}, Response: models.RFQ{}, Status: fiber.StatusCreated, Errors: []int{fiber.StatusUnprocessableEntity}, Idempotent: true},
+	{Method: fiber.MethodPost, Path: "/api/rfqs/:id/cancel", Tag: "sourcing", Summary: "Cancel an open request for quotation", Response: models.RFQ{}, Errors: []int{fiber.StatusConflict}},
+	{Method: fiber.MethodPost, Path: "/api/rfqs/:id/quotes", Tag: "sourcing", Summary: "Submit or replace a provider's quote", Request: models.QuoteRequest{}, Response: models.Quote{}, Status: fiber.StatusCreated, Errors: []int{fiber.StatusConflict, fiber.StatusUnprocessableEntity}, Idempotent: true},
+	{Method: fiber.MethodGet, Path: "/api/rfqs/:id/comparison", Tag: "sourcing", Summary: "Compare the quotes of a request for quotation", Response: models.RFQComparison{}, Errors: []int{fiber.StatusUnprocessableEntity}},
+	{Method: fiber.MethodPost, Path: "/api/rfqs/:id/quotes/:quote_id/convert", Tag: "sourcing", Summary: "Turn a quote into a draft purchase and award the request", Request: models.ConvertQuoteRequest{}, Response: models.PurchaseResponsev2{}, Status: fiber.StatusCreated, Errors: []int{fiber.StatusConflict, fiber.StatusUnprocessableEntity}, Idempotent: true},
+
+	{Method: fiber.MethodGet, Path: "/api/requisitions", Tag: "requisitions", Summary: "List requisitions, latest first", Query: requisitionFilters, Response: []models.Requisition{}},
+	{Method: fiber.MethodGet, Path: "/api/requisitions/:id", Tag: "requisitions", Summary: "Get a requisition", Response: models.Requisition{}},
+	{Method: fiber.MethodPost, Path: "/api/requisitions", Tag: "requisitions", Summary: "Request items for approval", Request: models.RequisitionRequest{}, Response: models.Requisition{}, Status: fiber.StatusCreated, Errors: []int{fiber.StatusUnauthorized, fiber.StatusUnprocessableEntity}, Idempotent: true},
+	{Method: fiber.MethodPost, Path: "/api/requisitions/convert", Tag: "requisitions", Summary: "Turn approved requisitions into draft purchases, one per provider", Request: models.RequisitionConversionRequest{}, Response: models.RequisitionConversion{}, Status: fiber.StatusCreated, Errors: []int{fiber.StatusConflict, fiber.StatusUnprocessableEntity}, Idempotent: true},
+	{Method: fiber.MethodPost, Path: "/api/requisitions/:id/approve", Tag: "requisitions", Summary: "Approve a pending requisition (admins only)", Request: models.RequisitionReview{}, Response: models.Requisition{}, Errors: []int{fiber.StatusForbidden, fiber.StatusConflict}},
+	{Method: fiber.MethodPost, Path: "/api/requisitions/:id/reject", Tag: "requisitions", Summary: "Reject a pending requisition with a reason (admins only)", Request: models.RequisitionReview{}, Response: models.Requisition{}, Errors: []int{fiber.StatusForbidden, fiber.StatusConflict, fiber.StatusUnprocessableEntity}},
+	{Method: fiber.MethodPost, Path: "/api/requisitions/:id/cancel", Tag: "requisitions", Summary: "Withdraw a requisition not yet on a purchase", Response: models.Requisition{}, Errors: []int{fiber.StatusForbidden, fiber.StatusConflict}},
+}
@@ -0,0 +1,45 @@
+package events
+
+import (
+	"context"
+	"sync"
+)
+
+// Handler handles an event delivered by the Bus. Returning an error makes
+// the relay retry the event for every handler of the Bus.
+type Handler func(ctx context.Context, message Message) error
+
+// Bus is an in-process Sink that fans events out to the handlers subscribed
+// to their type.
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+}
+
+func NewBus() *Bus {
+	return &Bus{handlers: make(map[string][]Handler)}
+}
+
+// Subscribe registers handler for events of eventType.
+func (b *Bus) Subscribe(eventType string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[eventType] = append(b.handlers[eventType], handler)
+}
+
+func (b *Bus) Name() string {
+	return "bus"
+}
+
+func (b *Bus) Publish(ctx context.Context, message Message) error {
+	b.mu.RLock()
+	handlers := b.handlers[message.Type]
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		if err := handler(ctx, message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/nats-io/nats.go"
+)
+
+// NATSSink publishes every event to NATS on "<prefix>.<type>", for example
+// "events.PurchaseCreated". The body is the JSON encoded Message and the
+// Nats-Msg-Id header carries the event ID so JetStream streams can drop
+// the duplicates at-least-once delivery produces.
+type NATSSink struct {
+	conn   *nats.Conn
+	prefix string
+}
+
+// NewNATSSink connects to the NATS server at url. The connection reconnects
+// on its own; while it is down Publish fails and the relay retries later.
+func NewNATSSink(url string, prefix string) (*NATSSink, error) {
+	conn, err := nats.Connect(url, nats.Name("fiber-api outbox relay"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, err
+	}
+	return &NATSSink{conn: conn, prefix: prefix}, nil
+}
+
+func (s *NATSSink) Name() string {
+	return "nats"
+}
+
+func (s *NATSSink) Publish(ctx context.Context, message Message) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	msg := nats.NewMsg(s.prefix + "." + message.Type)
+	msg.Header.Set(nats.MsgIdHdr, message.ID.Hex())
+	msg.Data = body
+	if err := s.conn.PublishMsg(msg); err != nil {
+		return err
+	}
+	// Publish only buffers the message; flushing makes a dead connection
+	// show up as an error instead of a lost event.
+	return s.conn.FlushWithContext(ctx)
+}
+
+func (s *NATSSink) Close() {
+	s.conn.Close()
+}
@@ -0,0 +1,73 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/aldoramirezmartinez/fiber-api/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const OutboxCollectionName = "outbox"
+
+// Message is what sinks receive for an outbox event. Its JSON form is the
+// message body published to NATS.
+type Message struct {
+	ID            primitive.ObjectID `json:"id"`
+	Type          string             `json:"type"`
+	AggregateType string             `json:"aggregate_type"`
+	AggregateID   primitive.ObjectID `json:"aggregate_id"`
+	OccurredAt    time.Time          `json:"occurred_at"`
+	Data          json.RawMessage    `json:"data"`
+}
+
+// Emit writes a domain event to the outbox. Call it inside UnitOfWork.Do with
+// the transaction context so the event is stored if and only if the change
+// it describes commits; the relay publishes it afterwards.
+func Emit(ctx context.Context, db *mongo.Database, eventType string, aggregateType string, aggregateID primitive.ObjectID, data interface{}) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	_, err = db.Collection(OutboxCollectionName).InsertOne(ctx, models.OutboxEvent{
+		ID:            primitive.NewObjectID(),
+		Type:          eventType,
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		OccurredAt:    now,
+		Data:          string(encoded),
+		DeliveredTo:   []string{},
+		NextAttemptAt: now,
+	})
+	return err
+}
+
+// EnsureIndexes creates the index the relay polls with and the one that
+// expires published events after retention.
+func EnsureIndexes(ctx context.Context, db *mongo.Database, retention time.Duration) error {
+	_, err := db.Collection(OutboxCollectionName).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "published_at", Value: 1}, {Key: "next_attempt_at", Value: 1}}},
+		{
+			Keys:    bson.D{{Key: "published_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(retention.Seconds())).SetName("published_at_ttl").SetPartialFilterExpression(bson.M{"published_at": bson.M{"$exists": true}}),
+		},
+	})
+	return err
+}
+
+func messageFrom(event models.OutboxEvent) Message {
+	return Message{
+		ID:            event.ID,
+		Type:          event.Type,
+		AggregateType: event.AggregateType,
+		AggregateID:   event.AggregateID,
+		OccurredAt:    event.OccurredAt,
+		Data:          json.RawMessage(event.Data),
+	}
+}
@@ -0,0 +1,137 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aldoramirezmartinez/fiber-api/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	relayLease      = time.Minute
+	relayBaseDelay  = time.Second
+	relayMaxDelay   = 5 * time.Minute
+	relayBatchLimit = 100
+)
+
+// Sink receives the events relayed from the outbox. Publish may be called
+// more than once for the same event, so sinks must tolerate duplicates; the
+// message ID identifies the event.
+type Sink interface {
+	Name() string
+	Publish(ctx context.Context, message Message) error
+}
+
+// Relay moves events from the outbox to its sinks with at-least-once
+// delivery. An event stays in the outbox until every sink accepted it and
+// failed sinks are retried with backoff, without resending to the others.
+type Relay struct {
+	db    *mongo.Database
+	sinks []Sink
+}
+
+func NewRelay(db *mongo.Database, sinks ...Sink) *Relay {
+	return &Relay{db: db, sinks: sinks}
+}
+
+// RelayPending publishes due events in the order they occurred until none
+// are left or a batch was handled, and returns how many it claimed.
+func (r *Relay) RelayPending(ctx context.Context) (int, error) {
+	relayed := 0
+	for relayed < relayBatchLimit {
+		event, err := r.claim(ctx)
+		if err == mongo.ErrNoDocuments {
+			return relayed, nil
+		}
+		if err != nil {
+			return relayed, err
+		}
+
+		if err := r.relay(ctx, event); err != nil {
+			return relayed, err
+		}
+		relayed++
+	}
+	return relayed, nil
+}
+
+func (r *Relay) claim(ctx context.Context) (models.OutboxEvent, error) {
+	now := time.Now()
+	filter := bson.M{
+		"published_at":    nil,
+		"next_attempt_at": bson.M{"$lte": now},
+		"$or": bson.A{
+			bson.M{"locked_until": nil},
+			bson.M{"locked_until": bson.M{"$lte": now}},
+		},
+	}
+	update := bson.M{"$set": bson.M{"locked_until": now.Add(relayLease)}}
+	findOptions := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "occurred_at", Value: 1}, {Key: "_id", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var event models.OutboxEvent
+	err := r.db.Collection(OutboxCollectionName).FindOneAndUpdate(ctx, filter, update, findOptions).Decode(&event)
+	return event, err
+}
+
+func (r *Relay) relay(ctx context.Context, event models.OutboxEvent) error {
+	collection := r.db.Collection(OutboxCollectionName)
+	message := messageFrom(event)
+
+	delivered := make(map[string]bool, len(event.DeliveredTo))
+	for _, name := range event.DeliveredTo {
+		delivered[name] = true
+	}
+
+	var lastError error
+	for _, sink := range r.sinks {
+		if delivered[sink.Name()] {
+			continue
+		}
+		if err := sink.Publish(ctx, message); err != nil {
+			lastError = fmt.Errorf("%s: %w", sink.Name(), err)
+			continue
+		}
+		_, err := collection.UpdateOne(ctx, bson.M{"_id": event.ID}, bson.M{"$addToSet": bson.M{"delivered_to": sink.Name()}})
+		if err != nil {
+			return err
+		}
+	}
+
+	if lastError == nil {
+		_, err := collection.UpdateOne(ctx, bson.M{"_id": event.ID}, bson.M{
+			"$set":   bson.M{"published_at": time.Now()},
+			"$unset": bson.M{"locked_until": "", "last_error": ""},
+		})
+		return err
+	}
+
+	attempts := event.Attempts + 1
+	_, err := collection.UpdateOne(ctx, bson.M{"_id": event.ID}, bson.M{
+		"$set": bson.M{
+			"attempts":        attempts,
+			"next_attempt_at": time.Now().Add(relayBackoff(attempts)),
+			"last_error":      lastError.Error(),
+		},
+		"$unset": bson.M{"locked_until": ""},
+	})
+	return err
+}
+
+// relayBackoff doubles the delay from one second up to five minutes. Events
+// are never given up on: a sink that is down only delays them.
+func relayBackoff(attempts int) time.Duration {
+	delay := relayBaseDelay
+	for i := 1; i < attempts && delay < relayMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > relayMaxDelay {
+		return relayMaxDelay
+	}
+	return delay
+}
@@ -0,0 +1,58 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aldoramirezmartinez/fiber-api/models"
+	"github.com/aldoramirezmartinez/fiber-api/webhooks"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// WebhookSink queues webhook deliveries for the domain events subscribers can
+// ask for. The webhook event keeps the ID of the domain event, so relaying
+// an event again does not queue it twice.
+type WebhookSink struct {
+	db *mongo.Database
+}
+
+func NewWebhookSink(db *mongo.Database) *WebhookSink {
+	return &WebhookSink{db: db}
+}
+
+func (s *WebhookSink) Name() string {
+	return "webhooks"
+}
+
+func (s *WebhookSink) Publish(ctx context.Context, message Message) error {
+	eventType, data, err := webhookEvent(message)
+	if err != nil || eventType == "" {
+		return err
+	}
+	return webhooks.Publish(ctx, s.db, message.ID, eventType, data)
+}
+
+// webhookEvent maps a domain event to the webhook event and data sent to
+// subscribers, or "" when it has no webhook counterpart.
+func webhookEvent(message Message) (string, interface{}, error) {
+	switch message.Type {
+	case models.EventTypePurchaseCreated:
+		return models.EventPurchaseCreated, message.Data, nil
+	case models.EventTypeItemPriceChanged:
+		return models.EventItemPriceChanged, message.Data, nil
+	case models.EventTypePurchaseAmended:
+		return models.EventPurchaseAmended, message.Data, nil
+	case models.EventTypePurchaseStatusChanged:
+		var change models.PurchaseStatusChange
+		if err := json.Unmarshal(message.Data, &change); err != nil {
+			return "", nil, err
+		}
+		switch change.Status {
+		case models.PurchaseStatusApproved:
+			return models.EventPurchaseApproved, change.Purchase, nil
+		case models.PurchaseStatusReceived:
+			return models.EventPurchaseReceived, change.Purchase, nil
+		}
+	}
+	return "", nil, nil
+}
@@ -0,0 +1,81 @@
+package exchange
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aldoramirezmartinez/fiber-api/models"
+	"github.com/aldoramirezmartinez/fiber-api/money"
+)
+
+var csvColumns = []string{"from", "to", "rate", "effective_date"}
+
+// ParseCSV reads rates from CSV with a from,to,rate,effective_date header.
+// Dates are YYYY-MM-DD, meaning midnight UTC, or RFC 3339. Every row is
+// validated; errors name the line they were found on.
+func ParseCSV(r io.Reader) ([]models.ExchangeRate, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range csvColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing %s column", name)
+		}
+	}
+
+	var rates []models.ExchangeRate
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		var rate models.ExchangeRate
+		if rate.From, err = money.ParseCurrency(record[columns["from"]]); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if rate.To, err = money.ParseCurrency(record[columns["to"]]); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if rate.Rate, err = money.Parse(strings.TrimSpace(record[columns["rate"]])); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if rate.EffectiveDate, err = ParseDate(record[columns["effective_date"]]); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if err := rate.Validate(); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rates = append(rates, rate)
+	}
+	return rates, nil
+}
+
+// ParseDate reads a YYYY-MM-DD date as midnight UTC, or an RFC 3339
+// timestamp.
+func ParseDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if date, err := time.Parse("2006-01-02", value); err == nil {
+		return date, nil
+	}
+	date, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD or RFC 3339", value)
+	}
+	return date, nil
+}
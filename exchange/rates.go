@@ -0,0 +1,122 @@
+// Package exchange stores exchange rates by effective date and converts
+// amounts between currencies with them.
+package exchange
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aldoramirezmartinez/fiber-api/models"
+	"github.com/aldoramirezmartinez/fiber-api/money"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const CollectionName = "exchange_rates"
+
+// inversePlaces is the precision of a rate computed from the rate the
+// other way round.
+const inversePlaces = 10
+
+// RateNotFoundError is returned when no rate from From to To was in effect
+// at At.
+type RateNotFoundError struct {
+	From money.Currency
+	To   money.Currency
+	At   time.Time
+}
+
+func (e *RateNotFoundError) Error() string {
+	return fmt.Sprintf("no exchange rate from %s to %s effective on %s", e.From, e.To, e.At.Format("2006-01-02"))
+}
+
+// Rate returns how many units of to one unit of from bought at at: the rate
+// with the latest effective date not after at. A rate loaded the opposite
+// way round is inverted, and used when it is more recent than the direct
+// one.
+func Rate(ctx context.Context, db *mongo.Database, from money.Currency, to money.Currency, at time.Time) (money.Amount, error) {
+	if from == to {
+		return money.FromInt(1), nil
+	}
+
+	direct, err := find(ctx, db, from, to, at)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return money.Zero, err
+	}
+	found := err == nil
+
+	inverse, err := find(ctx, db, to, from, at)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return money.Zero, err
+	}
+	if err == nil && (!found || inverse.EffectiveDate.After(direct.EffectiveDate)) {
+		return money.FromInt(1).Div(inverse.Rate, inversePlaces, money.HalfEven), nil
+	}
+
+	if !found {
+		return money.Zero, &RateNotFoundError{From: from, To: to, At: at}
+	}
+	return direct.Rate, nil
+}
+
+// Convert returns amount in from converted to to at the rate in effect at
+// at. The result is not rounded.
+func Convert(ctx context.Context, db *mongo.Database, amount money.Amount, from money.Currency, to money.Currency, at time.Time) (money.Amount, error) {
+	rate, err := Rate(ctx, db, from, to, at)
+	if err != nil {
+		return money.Zero, err
+	}
+	return amount.Mul(rate), nil
+}
+
+func find(ctx context.Context, db *mongo.Database, from money.Currency, to money.Currency, at time.Time) (models.ExchangeRate, error) {
+	filter := bson.M{"from": from, "to": to, "effective_date": bson.M{"$lte": at}}
+	findOptions := options.FindOne().SetSort(bson.M{"effective_date": -1})
+
+	var rate models.ExchangeRate
+	err := db.Collection(CollectionName).FindOne(ctx, filter, findOptions).Decode(&rate)
+	return rate, err
+}
+
+// Save stores rate, replacing any rate already loaded for the same
+// currencies and effective date, and returns it as stored along with the
+// rate it replaced, or nil. Inside UnitOfWork.Do pass the transaction context
+// so that a batch of rates is saved all or nothing.
+func Save(ctx context.Context, db *mongo.Database, rate models.ExchangeRate) (models.ExchangeRate, *models.ExchangeRate, error) {
+	collection := db.Collection(CollectionName)
+	now := time.Now()
+
+	filter := bson.M{"from": rate.From, "to": rate.To, "effective_date": rate.EffectiveDate}
+	update := bson.M{
+		"$set":         bson.M{"rate": rate.Rate, "updated_at": now},
+		"$setOnInsert": bson.M{"created_at": now},
+	}
+	findOptions := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.Before)
+
+	var previous *models.ExchangeRate
+	var replaced models.ExchangeRate
+	err := collection.FindOneAndUpdate(ctx, filter, update, findOptions).Decode(&replaced)
+	if err == nil {
+		previous = &replaced
+	} else if err != mongo.ErrNoDocuments {
+		return models.ExchangeRate{}, nil, err
+	}
+
+	var stored models.ExchangeRate
+	if err := collection.FindOne(ctx, filter).Decode(&stored); err != nil {
+		return models.ExchangeRate{}, nil, err
+	}
+	return stored, previous, nil
+}
+
+// EnsureIndexes creates the unique index rates are looked up and replaced
+// by.
+func EnsureIndexes(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection(CollectionName).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "from", Value: 1}, {Key: "to", Value: 1}, {Key: "effective_date", Value: -1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
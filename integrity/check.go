@@ -0,0 +1,354 @@
+package integrity
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aldoramirezmartinez/fiber-api/models"
+	"github.com/aldoramirezmartinez/fiber-api/money"
+	"github.com/aldoramirezmartinez/fiber-api/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type Report struct {
+	CheckedAt               time.Time                `json:"checked_at"`
+	Repair                  bool                     `json:"repair"`
+	DanglingReferences      []DanglingReference      `json:"dangling_references"`
+	TotalMismatches         []TotalMismatch          `json:"total_mismatches"`
+	DuplicatePurchaseOrders []DuplicatePurchaseOrder `json:"duplicate_purchase_orders"`
+}
+
+// DanglingReference is a document whose Relation field points at a document
+// that does not exist. Repairs soft delete the document when the relation's
+// delete policy is Cascade and clear the reference otherwise.
+type DanglingReference struct {
+	Relation   string             `json:"relation"`
+	DocumentID primitive.ObjectID `json:"document_id"`
+	MissingID  primitive.ObjectID `json:"missing_id"`
+	Repair     string             `json:"repair,omitempty"`
+}
+
+// TotalMismatch is a purchase whose total is not the sum of its line
+// subtotals, less its discount, plus its charges. Repairs set the total to
+// Expected.
+type TotalMismatch struct {
+	PurchaseID primitive.ObjectID `json:"purchase_id"`
+	Stored     money.Amount       `json:"stored"`
+	Expected   money.Amount       `json:"expected"`
+	Repaired   bool               `json:"repaired,omitempty"`
+}
+
+// DuplicatePurchaseOrder lists purchases sharing a purchase order, oldest
+// first. Repairs keep the oldest and rename the rest with a numeric suffix.
+type DuplicatePurchaseOrder struct {
+	PurchaseOrder string               `json:"purchase_order"`
+	PurchaseIDs   []primitive.ObjectID `json:"purchase_ids"`
+	RenamedTo     []string             `json:"renamed_to,omitempty"`
+}
+
+func (r Report) Problems() int {
+	return len(r.DanglingReferences) + len(r.TotalMismatches) + len(r.DuplicatePurchaseOrders)
+}
+
+// Check scans the database for dangling references, purchase totals that do
+// not match their lines and duplicate purchase orders, fixing them when
+// repair is set. Soft-deleted documents are ignored.
+func Check(ctx context.Context, db *mongo.Database, repair bool) (Report, error) {
+	report := Report{
+		CheckedAt:               time.Now(),
+		Repair:                  repair,
+		DanglingReferences:      []DanglingReference{},
+		TotalMismatches:         []TotalMismatch{},
+		DuplicatePurchaseOrders: []DuplicatePurchaseOrder{},
+	}
+
+	for _, relation := range Relations {
+		dangling, err := checkRelation(ctx, db, relation, repair)
+		if err != nil {
+			return report, fmt.Errorf("checking %s: %w", relation.Name(), err)
+		}
+		report.DanglingReferences = append(report.DanglingReferences, dangling...)
+	}
+
+	mismatches, err := checkTotals(ctx, db, repair)
+	if err != nil {
+		return report, fmt.Errorf("checking purchase totals: %w", err)
+	}
+	report.TotalMismatches = mismatches
+
+	duplicates, err := checkPurchaseOrders(ctx, db, repair)
+	if err != nil {
+		return report, fmt.Errorf("checking purchase orders: %w", err)
+	}
+	report.DuplicatePurchaseOrders = duplicates
+
+	return report, nil
+}
+
+func checkRelation(ctx context.Context, db *mongo.Database, relation Relation, repair bool) ([]DanglingReference, error) {
+	source := db.Collection(relation.Source)
+
+	values, err := source.Distinct(ctx, relation.Field, utils.NotDeleted(bson.M{}))
+	if err != nil {
+		return nil, err
+	}
+
+	var referenced []primitive.ObjectID
+	for _, value := range values {
+		if id, ok := value.(primitive.ObjectID); ok && !id.IsZero() {
+			referenced = append(referenced, id)
+		}
+	}
+	if len(referenced) == 0 {
+		return nil, nil
+	}
+
+	existing, err := findIDs(ctx, db.Collection(relation.Target), bson.M{"_id": bson.M{"$in": referenced}})
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []primitive.ObjectID
+	for _, id := range referenced {
+		if !existing[id] {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 {
+		return nil, nil
+	}
+
+	cursor, err := source.Find(ctx, utils.NotDeleted(bson.M{relation.Field: bson.M{"$in": missing}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	missingSet := map[primitive.ObjectID]bool{}
+	for _, id := range missing {
+		missingSet[id] = true
+	}
+
+	var dangling []DanglingReference
+	for cursor.Next(ctx) {
+		document := cursor.Current
+		documentID, _ := document.Lookup("_id").ObjectIDOK()
+		for _, missingID := range referencesIn(document, relation) {
+			if missingSet[missingID] {
+				dangling = append(dangling, DanglingReference{
+					Relation:   relation.Name(),
+					DocumentID: documentID,
+					MissingID:  missingID,
+				})
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	if repair {
+		repairAction := action{relation: relation, ids: missing}
+		repairName := string(Nullify)
+		if relation.Policy() == Cascade {
+			var documentIDs []primitive.ObjectID
+			for _, reference := range dangling {
+				documentIDs = append(documentIDs, reference.DocumentID)
+			}
+			repairAction = action{relation: relation, ids: documentIDs, cascade: true}
+			repairName = string(Cascade)
+		}
+		if err := repairAction.apply(ctx, db, nil); err != nil {
+			return nil, err
+		}
+		for i := range dangling {
+			dangling[i].Repair = repairName
+		}
+	}
+
+	return dangling, nil
+}
+
+// referencesIn returns the IDs stored under the relation's field, looking
+// inside array elements for fields like item_list.item_id and lists of IDs.
+func referencesIn(document bson.Raw, relation Relation) []primitive.ObjectID {
+	if relation.List {
+		list, ok := document.Lookup(relation.Field).ArrayOK()
+		if !ok {
+			return nil
+		}
+		values, err := list.Values()
+		if err != nil {
+			return nil
+		}
+		var ids []primitive.ObjectID
+		for _, value := range values {
+			if id, ok := value.ObjectIDOK(); ok {
+				ids = append(ids, id)
+			}
+		}
+		return ids
+	}
+
+	array, element, ok := relation.arrayField()
+	if !ok {
+		if id, ok := document.Lookup(relation.Field).ObjectIDOK(); ok {
+			return []primitive.ObjectID{id}
+		}
+		return nil
+	}
+
+	values, err := document.Lookup(array).Array().Values()
+	if err != nil {
+		return nil
+	}
+
+	var ids []primitive.ObjectID
+	for _, value := range values {
+		if line, ok := value.DocumentOK(); ok {
+			if id, ok := line.Lookup(element).ObjectIDOK(); ok {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+func checkTotals(ctx context.Context, db *mongo.Database, repair bool) ([]TotalMismatch, error) {
+	purchases := db.Collection("purchases")
+
+	cursor, err := purchases.Find(ctx, utils.NotDeleted(bson.M{"item_list": bson.M{"$exists": true}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var mismatches []TotalMismatch
+	for cursor.Next(ctx) {
+		var purchase models.Purchasev2
+		if err := cursor.Decode(&purchase); err != nil {
+			return nil, err
+		}
+
+		expected := money.Zero
+		for _, detail := range purchase.ItemList {
+			expected = expected.Add(detail.Subtotal)
+		}
+		expected = expected.Sub(purchase.DiscountAmount)
+		for _, charge := range purchase.Charges {
+			expected = expected.Add(charge.Amount)
+		}
+		if !expected.Equal(purchase.Total) {
+			mismatches = append(mismatches, TotalMismatch{
+				PurchaseID: purchase.ID,
+				Stored:     purchase.Total,
+				Expected:   expected,
+			})
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	if repair {
+		for i, mismatch := range mismatches {
+			_, err := purchases.UpdateOne(ctx, bson.M{"_id": mismatch.PurchaseID}, bson.M{
+				"$set": bson.M{"total": mismatch.Expected},
+				"$inc": bson.M{"version": 1},
+			})
+			if err != nil {
+				return nil, err
+			}
+			mismatches[i].Repaired = true
+		}
+	}
+
+	return mismatches, nil
+}
+
+func checkPurchaseOrders(ctx context.Context, db *mongo.Database, repair bool) ([]DuplicatePurchaseOrder, error) {
+	purchases := db.Collection("purchases")
+
+	cursor, err := purchases.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: utils.NotDeleted(bson.M{"purchase_order": bson.M{"$exists": true}})}},
+		{{Key: "$sort", Value: bson.D{{Key: "date", Value: 1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   "$purchase_order",
+			"ids":   bson.M{"$push": "$_id"},
+			"count": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$match", Value: bson.M{"count": bson.M{"$gt": 1}}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var groups []struct {
+		PurchaseOrder string               `bson:"_id"`
+		IDs           []primitive.ObjectID `bson:"ids"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].PurchaseOrder < groups[j].PurchaseOrder })
+
+	duplicates := make([]DuplicatePurchaseOrder, len(groups))
+	for i, group := range groups {
+		duplicates[i] = DuplicatePurchaseOrder{
+			PurchaseOrder: group.PurchaseOrder,
+			PurchaseIDs:   group.IDs,
+		}
+		if !repair {
+			continue
+		}
+
+		suffix := 1
+		for _, id := range group.IDs[1:] {
+			var renamed string
+			for {
+				suffix++
+				renamed = fmt.Sprintf("%s-%d", group.PurchaseOrder, suffix)
+				count, err := purchases.CountDocuments(ctx, bson.M{"purchase_order": renamed})
+				if err != nil {
+					return nil, err
+				}
+				if count == 0 {
+					break
+				}
+			}
+
+			_, err := purchases.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+				"$set": bson.M{"purchase_order": renamed},
+				"$inc": bson.M{"version": 1},
+			})
+			if err != nil {
+				return nil, err
+			}
+			duplicates[i].RenamedTo = append(duplicates[i].RenamedTo, renamed)
+		}
+	}
+
+	return duplicates, nil
+}
+
+func findIDs(ctx context.Context, collection *mongo.Collection, filter bson.M) (map[primitive.ObjectID]bool, error) {
+	cursor, err := collection.Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	ids := map[primitive.ObjectID]bool{}
+	for cursor.Next(ctx) {
+		if id, ok := cursor.Current.Lookup("_id").ObjectIDOK(); ok {
+			ids[id] = true
+		}
+	}
+	return ids, cursor.Err()
+}
@@ -0,0 +1,183 @@
+package integrity
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aldoramirezmartinez/fiber-api/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxListedReferences caps how many referencing IDs a RestrictError lists.
+const maxListedReferences = 50
+
+// Reference lists the documents of one relation that still point at a
+// document being deleted.
+type Reference struct {
+	Relation string               `json:"relation"`
+	Count    int                  `json:"count"`
+	IDs      []primitive.ObjectID `json:"ids"`
+}
+
+// RestrictError is returned by BeforeDelete when a Restrict relation still
+// has referencing documents.
+type RestrictError struct {
+	References []Reference
+}
+
+func (e *RestrictError) Error() string {
+	names := make([]string, len(e.References))
+	for i, reference := range e.References {
+		names[i] = fmt.Sprintf("%s (%d)", reference.Relation, reference.Count)
+	}
+	return "document is still referenced by " + strings.Join(names, ", ")
+}
+
+// deletion is a set of documents of one collection about to be deleted.
+type deletion struct {
+	collection string
+	ids        []primitive.ObjectID
+}
+
+type action struct {
+	relation Relation
+	ids      []primitive.ObjectID
+	cascade  bool
+}
+
+// BeforeDelete applies the delete policy of every relation pointing at the
+// document with the given ID in collection. Nothing is changed when any
+// Restrict relation, including those reached through cascades, still has
+// references; a *RestrictError describes them instead.
+func BeforeDelete(ctx context.Context, db *mongo.Database, collection string, id primitive.ObjectID, actor *primitive.ObjectID) error {
+	var actions []action
+	var restricted []Reference
+
+	visited := map[string]bool{collection + ":" + id.Hex(): true}
+	pending := []deletion{{collection: collection, ids: []primitive.ObjectID{id}}}
+
+	for len(pending) > 0 {
+		current := pending[0]
+		pending = pending[1:]
+
+		for _, relation := range Relations {
+			if relation.Target != current.collection {
+				continue
+			}
+
+			referencing, err := findReferencing(ctx, db, relation, current.ids)
+			if err != nil {
+				return err
+			}
+			if len(referencing) == 0 {
+				continue
+			}
+
+			switch relation.Policy() {
+			case Restrict:
+				listed := referencing
+				if len(listed) > maxListedReferences {
+					listed = listed[:maxListedReferences]
+				}
+				restricted = append(restricted, Reference{
+					Relation: relation.Name(),
+					Count:    len(referencing),
+					IDs:      listed,
+				})
+			case Nullify:
+				actions = append(actions, action{relation: relation, ids: current.ids})
+			case Cascade:
+				var next []primitive.ObjectID
+				for _, referencingID := range referencing {
+					key := relation.Source + ":" + referencingID.Hex()
+					if !visited[key] {
+						visited[key] = true
+						next = append(next, referencingID)
+					}
+				}
+				if len(next) > 0 {
+					actions = append(actions, action{relation: relation, ids: next, cascade: true})
+					pending = append(pending, deletion{collection: relation.Source, ids: next})
+				}
+			}
+		}
+	}
+
+	if len(restricted) > 0 {
+		return &RestrictError{References: restricted}
+	}
+
+	for _, a := range actions {
+		if err := a.apply(ctx, db, actor); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func findReferencing(ctx context.Context, db *mongo.Database, relation Relation, ids []primitive.ObjectID) ([]primitive.ObjectID, error) {
+	filter := utils.NotDeleted(bson.M{relation.Field: bson.M{"$in": ids}})
+	cursor, err := db.Collection(relation.Source).Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var documents []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &documents); err != nil {
+		return nil, err
+	}
+
+	referencing := make([]primitive.ObjectID, len(documents))
+	for i, document := range documents {
+		referencing[i] = document.ID
+	}
+	return referencing, nil
+}
+
+func (a action) apply(ctx context.Context, db *mongo.Database, actor *primitive.ObjectID) error {
+	collection := db.Collection(a.relation.Source)
+
+	if a.cascade {
+		// Collections without soft delete, such as provider offers, lose
+		// the referencing documents for good.
+		if !utils.SoftDeletes(a.relation.Source) {
+			_, err := collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": a.ids}})
+			return err
+		}
+		_, err := collection.UpdateMany(ctx, utils.NotDeleted(bson.M{"_id": bson.M{"$in": a.ids}}), utils.SoftDeleteUpdate(actor))
+		return err
+	}
+
+	// Nullify: a.ids are the deleted targets, clear every reference to them.
+	filter := utils.NotDeleted(bson.M{a.relation.Field: bson.M{"$in": a.ids}})
+	if a.relation.List {
+		_, err := collection.UpdateMany(ctx, filter, bson.M{
+			"$pull": bson.M{a.relation.Field: bson.M{"$in": a.ids}},
+			"$inc":  bson.M{"version": 1},
+		})
+		return err
+	}
+	if array, element, ok := a.relation.arrayField(); ok {
+		_, err := collection.UpdateMany(ctx, filter, bson.M{
+			"$unset": bson.M{array + ".$[line]." + element: ""},
+			"$inc":   bson.M{"version": 1},
+		}, options.Update().SetArrayFilters(options.ArrayFilters{
+			Filters: []interface{}{bson.M{"line." + element: bson.M{"$in": a.ids}}},
+		}))
+		return err
+	}
+
+	_, err := collection.UpdateMany(ctx, filter, bson.M{
+		"$unset": bson.M{a.relation.Field: ""},
+		"$inc":   bson.M{"version": 1},
+	})
+	return err
+}
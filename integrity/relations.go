@@ -0,0 +1,77 @@
+package integrity
+
+import (
+	"strings"
+
+	"github.com/aldoramirezmartinez/fiber-api/config"
+)
+
+type Policy string
+
+const (
+	// Restrict refuses to delete a document that is still referenced.
+	Restrict Policy = "restrict"
+	// Cascade deletes the referencing documents as well, softly where the
+	// collection supports it.
+	Cascade Policy = "cascade"
+	// Nullify clears the reference in the referencing documents.
+	Nullify Policy = "nullify"
+)
+
+// Relation is a reference from Field in the Source collection to the _id of
+// a document in the Target collection. Fields inside arrays are written with
+// a dot, e.g. item_list.item_id; List marks a Field that is itself an array
+// of IDs, e.g. provider_ids. Default is the policy used when none is
+// configured, Restrict when empty.
+type Relation struct {
+	Source  string
+	Field   string
+	Target  string
+	List    bool
+	Default Policy
+}
+
+var Relations = []Relation{
+	{Source: "items", Field: "provider_id", Target: "providers"},
+	{Source: "purchases", Field: "user_id", Target: "users"},
+	{Source: "purchases", Field: "provider_id", Target: "providers"},
+	{Source: "purchases", Field: "item_list.item_id", Target: "items"},
+	{Source: "purchase_details", Field: "item_id", Target: "items"},
+	{Source: "purchase_details", Field: "purchase_id", Target: "purchases"},
+	{Source: "provider_prices", Field: "item_id", Target: "items"},
+	{Source: "provider_prices", Field: "provider_id", Target: "providers"},
+	{Source: "rfqs", Field: "lines.item_id", Target: "items"},
+	{Source: "rfqs", Field: "provider_ids", Target: "providers", List: true},
+	{Source: "rfqs", Field: "quotes.provider_id", Target: "providers"},
+	{Source: "requisitions", Field: "lines.item_id", Target: "items"},
+	{Source: "requisitions", Field: "requester_id", Target: "users"},
+	// Revisions are the history of their purchase and go with it.
+	{Source: "purchase_revisions", Field: "purchase_id", Target: "purchases", Default: Cascade},
+}
+
+// Name identifies the relation in errors and configuration, e.g.
+// items.provider_id.
+func (r Relation) Name() string {
+	return r.Source + "." + r.Field
+}
+
+// Policy returns the delete policy configured for the relation with
+// DELETE_POLICY_<SOURCE>_<FIELD>, e.g. DELETE_POLICY_ITEMS_PROVIDER_ID.
+// Relations default to their Default policy, or Restrict.
+func (r Relation) Policy() Policy {
+	name := strings.ToUpper(strings.NewReplacer(".", "_").Replace(r.Name()))
+	switch policy := Policy(config.GetDeletePolicy(name)); policy {
+	case Restrict, Cascade, Nullify:
+		return policy
+	}
+	if r.Default != "" {
+		return r.Default
+	}
+	return Restrict
+}
+
+// arrayField splits a field inside an array into the array and the
+// element field; ok is false for top-level fields.
+func (r Relation) arrayField() (array string, element string, ok bool) {
+	return strings.Cut(r.Field, ".")
+}
@@ -0,0 +1,103 @@
+package integrity
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aldoramirezmartinez/fiber-api/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Parent is a document that a document being restored refers to.
+type Parent struct {
+	Relation string             `json:"relation"`
+	ID       primitive.ObjectID `json:"id"`
+}
+
+// DeletedParentError is returned by BeforeRestore when the document refers
+// to documents that were deleted since.
+type DeletedParentError struct {
+	Parents []Parent
+}
+
+func (e *DeletedParentError) Error() string {
+	names := make([]string, len(e.Parents))
+	for i, parent := range e.Parents {
+		names[i] = fmt.Sprintf("%s (%s)", parent.Relation, parent.ID.Hex())
+	}
+	return "document refers to deleted documents: " + strings.Join(names, ", ")
+}
+
+// BeforeRestore checks that every document the document with the given ID
+// in collection refers to still exists and is not deleted, returning a
+// *DeletedParentError listing those that are not.
+func BeforeRestore(ctx context.Context, db *mongo.Database, collection string, id primitive.ObjectID) error {
+	document, err := db.Collection(collection).FindOne(ctx, bson.M{"_id": id}).DecodeBytes()
+	if err != nil {
+		return err
+	}
+
+	var deleted []Parent
+	for _, relation := range Relations {
+		if relation.Source != collection {
+			continue
+		}
+
+		var ids []primitive.ObjectID
+		for _, parentID := range referencesIn(document, relation) {
+			if !parentID.IsZero() {
+				ids = append(ids, parentID)
+			}
+		}
+		if len(ids) == 0 {
+			continue
+		}
+
+		existing, err := findIDs(ctx, db.Collection(relation.Target), utils.NotDeleted(bson.M{"_id": bson.M{"$in": ids}}))
+		if err != nil {
+			return err
+		}
+		seen := map[primitive.ObjectID]bool{}
+		for _, parentID := range ids {
+			if !existing[parentID] && !seen[parentID] {
+				seen[parentID] = true
+				deleted = append(deleted, Parent{Relation: relation.Name(), ID: parentID})
+			}
+		}
+	}
+
+	if len(deleted) > 0 {
+		return &DeletedParentError{Parents: deleted}
+	}
+	return nil
+}
+
+// Referenced returns which of ids, documents of collection, are still
+// referenced by a document that is not deleted.
+func Referenced(ctx context.Context, db *mongo.Database, collection string, ids []primitive.ObjectID) (map[primitive.ObjectID]bool, error) {
+	wanted := map[primitive.ObjectID]bool{}
+	for _, id := range ids {
+		wanted[id] = true
+	}
+
+	referenced := map[primitive.ObjectID]bool{}
+	for _, relation := range Relations {
+		if relation.Target != collection {
+			continue
+		}
+
+		values, err := db.Collection(relation.Source).Distinct(ctx, relation.Field, utils.NotDeleted(bson.M{relation.Field: bson.M{"$in": ids}}))
+		if err != nil {
+			return nil, err
+		}
+		for _, value := range values {
+			if id, ok := value.(primitive.ObjectID); ok && wanted[id] {
+				referenced[id] = true
+			}
+		}
+	}
+	return referenced, nil
+}
@@ -0,0 +1,26 @@
+package jobs
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+
+	"github.com/aldoramirezmartinez/fiber-api/audit"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// AuditCheckpointJobType signs the head of the audit chain.
+const AuditCheckpointJobType = "audit.checkpoint"
+
+// AuditCheckpoint signs the head of the audit chain with key, unless it has
+// not moved since the last checkpoint.
+func AuditCheckpoint(ctx context.Context, db *mongo.Database, key ed25519.PrivateKey) error {
+	checkpoint, err := audit.Checkpoint(ctx, db, key)
+	if err != nil {
+		return err
+	}
+	if checkpoint != nil {
+		fmt.Printf("Signed audit checkpoint at sequence %d\n", checkpoint.Sequence)
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+package jobs
+
+import (
+	"context"
+
+	"github.com/aldoramirezmartinez/fiber-api/events"
+)
+
+// OutboxRelayJobType relays outbox events to their sinks.
+const OutboxRelayJobType = "outbox.relay"
+
+// RelayOutbox hands the pending outbox events to the sinks of relay.
+func RelayOutbox(ctx context.Context, relay *events.Relay) error {
+	_, err := relay.RelayPending(ctx)
+	return err
+}
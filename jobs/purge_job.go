@@ -0,0 +1,74 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aldoramirezmartinez/fiber-api/integrity"
+	"github.com/aldoramirezmartinez/fiber-api/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PurgeJobType hard-deletes documents whose soft delete retention is over.
+const PurgeJobType = "soft_delete.purge"
+
+// Purge hard-deletes the documents soft deleted more than retention ago.
+// Documents still referenced by a document that is not deleted, such as a
+// provider with offers, are kept until nothing refers to them.
+func Purge(ctx context.Context, db *mongo.Database, retention time.Duration) error {
+	cutoff := time.Now().Add(-retention)
+
+	for _, name := range utils.SoftDeleteCollections {
+		collection := db.Collection(name)
+
+		cursor, err := collection.Find(ctx, bson.M{"deleted_at": bson.M{"$lt": cutoff}}, options.Find().SetProjection(bson.M{"_id": 1}))
+		if err != nil {
+			return err
+		}
+		var documents []struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if err := cursor.All(ctx, &documents); err != nil {
+			return err
+		}
+		if len(documents) == 0 {
+			continue
+		}
+
+		ids := make([]primitive.ObjectID, len(documents))
+		for i, document := range documents {
+			ids[i] = document.ID
+		}
+		referenced, err := integrity.Referenced(ctx, db, name, ids)
+		if err != nil {
+			return err
+		}
+		var purgeable []primitive.ObjectID
+		for _, id := range ids {
+			if !referenced[id] {
+				purgeable = append(purgeable, id)
+			}
+		}
+		if len(referenced) > 0 {
+			fmt.Printf("Kept %d deleted documents in %s that are still referenced\n", len(referenced), name)
+		}
+		if len(purgeable) == 0 {
+			continue
+		}
+
+		// deleted_at is checked again in case a document was restored meanwhile.
+		result, err := collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": purgeable}, "deleted_at": bson.M{"$lt": cutoff}})
+		if err != nil {
+			return err
+		}
+		if result.DeletedCount > 0 {
+			fmt.Printf("Purged %d deleted documents from %s\n", result.DeletedCount, name)
+		}
+	}
+
+	return nil
+}
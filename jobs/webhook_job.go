@@ -0,0 +1,16 @@
+package jobs
+
+import (
+	"context"
+
+	"github.com/aldoramirezmartinez/fiber-api/webhooks"
+)
+
+// WebhookDeliveryJobType sends due webhook deliveries.
+const WebhookDeliveryJobType = "webhooks.deliver"
+
+// DeliverWebhooks sends the webhook deliveries that are due.
+func DeliverWebhooks(ctx context.Context, dispatcher *webhooks.Dispatcher) error {
+	_, err := dispatcher.DeliverDue(ctx)
+	return err
+}
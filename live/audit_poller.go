@@ -0,0 +1,106 @@
+package live
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/aldoramirezmartinez/fiber-api/audit"
+	"github.com/aldoramirezmartinez/fiber-api/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const pollBatchSize = 100
+
+// auditPoller follows the audit log by sequence number. Every write made
+// through the API is recorded there, so it sees the same changes as a change
+// stream would on a standalone server, only later.
+type auditPoller struct {
+	db          *mongo.Database
+	collections []string
+	interval    time.Duration
+	sequence    int64
+	pending     []models.AuditEntry
+}
+
+func openAuditPoller(ctx context.Context, db *mongo.Database, collections []string, token string, interval time.Duration) (Source, bool, error) {
+	poller := &auditPoller{db: db, collections: collections, interval: interval}
+
+	if sequence, ok := parseSequenceToken(token); ok {
+		poller.sequence = sequence
+		return poller, false, nil
+	}
+
+	var last models.AuditEntry
+	findOptions := options.FindOne().SetSort(bson.M{"sequence": -1}).SetProjection(bson.M{"sequence": 1})
+	err := db.Collection(audit.CollectionName).FindOne(ctx, bson.M{}, findOptions).Decode(&last)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, false, err
+	}
+	poller.sequence = last.Sequence
+	return poller, token != "", nil
+}
+
+func (p *auditPoller) Next(ctx context.Context) (Change, error) {
+	for len(p.pending) == 0 {
+		if err := p.poll(ctx); err != nil {
+			return Change{}, err
+		}
+		if len(p.pending) > 0 {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return Change{}, ctx.Err()
+		case <-time.After(p.interval):
+		}
+	}
+
+	entry := p.pending[0]
+	p.pending = p.pending[1:]
+	p.sequence = entry.Sequence
+
+	change := Change{
+		Token:      auditTokenPrefix + strconv.FormatInt(entry.Sequence, 10),
+		Collection: entry.Entity,
+		DocumentID: entry.EntityID,
+	}
+	switch entry.Action {
+	case models.AuditCreate:
+		change.Operation = OperationCreated
+	case models.AuditDelete:
+		change.Operation = OperationDeleted
+	case models.AuditRestore:
+		change.Operation = OperationRestored
+	default:
+		change.Operation = OperationUpdated
+	}
+
+	document, err := p.db.Collection(entry.Entity).FindOne(ctx, bson.M{"_id": entry.EntityID}).DecodeBytes()
+	if err != nil && err != mongo.ErrNoDocuments {
+		return Change{}, err
+	}
+	change.Document = document
+	return change, nil
+}
+
+func (p *auditPoller) poll(ctx context.Context) error {
+	filter := bson.M{
+		"sequence": bson.M{"$gt": p.sequence},
+		"entity":   bson.M{"$in": p.collections},
+	}
+	findOptions := options.Find().SetSort(bson.M{"sequence": 1}).SetLimit(pollBatchSize)
+
+	cursor, err := p.db.Collection(audit.CollectionName).Find(ctx, filter, findOptions)
+	if err != nil {
+		return err
+	}
+	return cursor.All(ctx, &p.pending)
+}
+
+func (p *auditPoller) Close(ctx context.Context) error {
+	return nil
+}
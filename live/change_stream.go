@@ -0,0 +1,120 @@
+package live
+
+import (
+	"context"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type changeStreamSource struct {
+	stream *mongo.ChangeStream
+}
+
+type changeEvent struct {
+	OperationType string `bson:"operationType"`
+	Namespace     struct {
+		Collection string `bson:"coll"`
+	} `bson:"ns"`
+	DocumentKey struct {
+		ID primitive.ObjectID `bson:"_id"`
+	} `bson:"documentKey"`
+	FullDocument      bson.Raw `bson:"fullDocument"`
+	UpdateDescription struct {
+		UpdatedFields bson.Raw `bson:"updatedFields"`
+		RemovedFields []string `bson:"removedFields"`
+	} `bson:"updateDescription"`
+}
+
+// changesPersistedFields drops updates that only set the lock field, which
+// utils.LockDocument writes to serialize transactions without changing the
+// document.
+var changesPersistedFields = bson.M{"$or": bson.A{
+	bson.M{"$ne": bson.A{"$operationType", "update"}},
+	bson.M{"$gt": bson.A{bson.M{"$size": bson.M{"$ifNull": bson.A{"$updateDescription.removedFields", bson.A{}}}}, 0}},
+	bson.M{"$gt": bson.A{bson.M{"$size": bson.M{"$filter": bson.M{
+		"input": bson.M{"$objectToArray": "$updateDescription.updatedFields"},
+		"cond":  bson.M{"$ne": bson.A{"$$this.k", "lock"}},
+	}}}, 0}},
+}}
+
+func openChangeStream(ctx context.Context, db *mongo.Database, collections []string, token string) (Source, bool, error) {
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: bson.M{
+		"ns.coll":       bson.M{"$in": collections},
+		"operationType": bson.M{"$in": bson.A{"insert", "update", "replace", "delete"}},
+		"$expr":         changesPersistedFields,
+	}}}}
+
+	reset := token != ""
+	streamOptions := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+	if strings.HasPrefix(token, changeStreamTokenPrefix) {
+		resumeOptions := options.ChangeStream().SetFullDocument(options.UpdateLookup).
+			SetResumeAfter(bson.M{"_data": strings.TrimPrefix(token, changeStreamTokenPrefix)})
+		stream, err := db.Watch(ctx, pipeline, resumeOptions)
+		if err == nil {
+			return &changeStreamSource{stream: stream}, false, nil
+		}
+		// The token may be older than the oplog; start from now.
+	}
+
+	stream, err := db.Watch(ctx, pipeline, streamOptions)
+	if err != nil {
+		return nil, false, err
+	}
+	return &changeStreamSource{stream: stream}, reset, nil
+}
+
+func (s *changeStreamSource) Next(ctx context.Context) (Change, error) {
+	if !s.stream.Next(ctx) {
+		if err := s.stream.Err(); err != nil {
+			return Change{}, err
+		}
+		return Change{}, ctx.Err()
+	}
+
+	var event changeEvent
+	if err := s.stream.Decode(&event); err != nil {
+		return Change{}, err
+	}
+
+	change := Change{
+		Token:      changeStreamTokenPrefix + s.stream.ResumeToken().Lookup("_data").StringValue(),
+		Collection: event.Namespace.Collection,
+		DocumentID: event.DocumentKey.ID,
+		Document:   event.FullDocument,
+		Operation:  OperationUpdated,
+	}
+
+	softDeleted := change.Document != nil && !isNull(change.Document.Lookup("deleted_at"))
+	switch {
+	case event.OperationType == "insert":
+		change.Operation = OperationCreated
+	case event.OperationType == "delete" || change.Document == nil:
+		change.Operation = OperationDeleted
+	case softDeleted:
+		change.Operation = OperationDeleted
+	case contains(event.UpdateDescription.RemovedFields, "deleted_at"):
+		change.Operation = OperationRestored
+	}
+	return change, nil
+}
+
+func (s *changeStreamSource) Close(ctx context.Context) error {
+	return s.stream.Close(ctx)
+}
+
+func isNull(value bson.RawValue) bool {
+	return value.Type == 0 || value.Type == bson.TypeNull
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package live
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Operations reported for a change.
+const (
+	OperationCreated  = "created"
+	OperationUpdated  = "updated"
+	OperationDeleted  = "deleted"
+	OperationRestored = "restored"
+)
+
+const (
+	changeStreamTokenPrefix = "cs:"
+	auditTokenPrefix        = "seq:"
+)
+
+// Change is one change to a watched document. Token resumes the feed right
+// after it. Document is the document as stored now, nil when it no longer
+// exists.
+type Change struct {
+	Token      string
+	Collection string
+	Operation  string
+	DocumentID primitive.ObjectID
+	Document   bson.Raw
+}
+
+// Source is a feed of changes. Next blocks until a change is available or
+// ctx is done.
+type Source interface {
+	Next(ctx context.Context) (Change, error)
+	Close(ctx context.Context) error
+}
+
+// Open starts a feed of the changes to collections after token, or from now
+// when token is empty. It uses a MongoDB change stream and falls back to
+// polling the audit log, checked every pollInterval, on servers without
+// change streams. reset reports that token could not be resumed and the
+// feed starts from now instead, so the caller missed changes.
+func Open(ctx context.Context, db *mongo.Database, collections []string, token string, pollInterval time.Duration) (source Source, reset bool, err error) {
+	source, reset, err = openChangeStream(ctx, db, collections, token)
+	if err == nil {
+		return source, reset, nil
+	}
+	if ctx.Err() != nil {
+		return nil, false, ctx.Err()
+	}
+
+	source, reset, err = openAuditPoller(ctx, db, collections, token, pollInterval)
+	if err != nil {
+		return nil, false, fmt.Errorf("change streams unavailable and polling failed: %w", err)
+	}
+	return source, reset, nil
+}
+
+func parseSequenceToken(token string) (int64, bool) {
+	if !strings.HasPrefix(token, auditTokenPrefix) {
+		return 0, false
+	}
+	sequence, err := strconv.ParseInt(strings.TrimPrefix(token, auditTokenPrefix), 10, 64)
+	return sequence, err == nil
+}
@@ -1,9 +1,31 @@
 package main
 
-import "github.com/aldoramirezmartinez/fiber-api/config"
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/aldoramirezmartinez/fiber-api/config"
+)
 
 func main() {
 	config.LoadEnv()
+
+	if code, ok := runCommand(os.Args[1:]); ok {
+		os.Exit(code)
+	}
+
+	shutdownTracer, err := config.InitTracer()
+	if err != nil {
+		fmt.Println("Failed to initialize tracing:", err)
+	} else {
+		defer func() {
+			if err := shutdownTracer(context.Background()); err != nil {
+				fmt.Println("Failed to flush traces:", err)
+			}
+		}()
+	}
+
 	app := NewApp()
 	app.Run()
 }
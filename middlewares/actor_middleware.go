@@ -0,0 +1,72 @@
+package middlewares
+
+import (
+	"github.com/aldoramirezmartinez/fiber-api/models"
+	"github.com/aldoramirezmartinez/fiber-api/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// NewActor resolves the X-User-ID header to a user so handlers can record who
+// made a change and check the caller's role.
+func NewActor(db *mongo.Database) fiber.Handler {
+	collection := db.Collection("users")
+
+	return func(c *fiber.Ctx) error {
+		userID := c.Get(utils.HeaderUserID)
+		if userID == "" {
+			return c.Next()
+		}
+
+		objID, err := primitive.ObjectIDFromHex(userID)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"message": "Invalid user ID",
+				"error":   err.Error(),
+			})
+		}
+
+		var user models.User
+		err = collection.FindOne(c.UserContext(), utils.NotDeleted(bson.M{"_id": objID})).Decode(&user)
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+					"message": "User not found",
+				})
+			}
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"message": "Failed to get user",
+				"error":   err.Error(),
+			})
+		}
+
+		utils.SetActor(c, user)
+		return c.Next()
+	}
+}
+
+// NewIncludeDeletedGuard only lets admins list soft-deleted documents.
+func NewIncludeDeletedGuard() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if utils.IncludeDeleted(c) && !utils.IsAdmin(c) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"message": "Only admins can include deleted documents",
+			})
+		}
+		return c.Next()
+	}
+}
+
+// NewRequireAdmin rejects callers whose role is not admin.
+func NewRequireAdmin() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !utils.IsAdmin(c) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"message": "Admin role required",
+			})
+		}
+		return c.Next()
+	}
+}
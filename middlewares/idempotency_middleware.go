@@ -0,0 +1,195 @@
+package middlewares
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/aldoramirezmartinez/fiber-api/config"
+	"github.com/aldoramirezmartinez/fiber-api/models"
+	"github.com/aldoramirezmartinez/fiber-api/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	HeaderIdempotencyKey      = "Idempotency-Key"
+	HeaderIdempotentReplayed  = "Idempotent-Replayed"
+	idempotencyPollInterval   = 100 * time.Millisecond
+	idempotencyLockTTL        = time.Minute
+	idempotencyKeysCollection = "idempotency_keys"
+)
+
+// NewIdempotency makes POST requests carrying an Idempotency-Key header safe
+// to retry. The first response is stored until it expires and replayed for
+// later requests from the same caller to the same path with the same key and
+// body; keys of different callers never collide. A duplicate that arrives while
+// the first request is still running waits up to IDEMPOTENCY_WAIT for it to
+// finish and gets 409 if it does not. Reusing a key with a different body gets
+// 422. Server errors are not stored, so the request can be retried.
+func NewIdempotency(db *mongo.Database) fiber.Handler {
+	collection := db.Collection(idempotencyKeysCollection)
+
+	_, err := collection.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.M{"expires_at": 1},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		fmt.Println("Failed to create idempotency keys index:", err)
+	}
+
+	return func(c *fiber.Ctx) error {
+		key := c.Get(HeaderIdempotencyKey)
+		if c.Method() != fiber.MethodPost || key == "" {
+			return c.Next()
+		}
+
+		ctx := c.UserContext()
+		recordID := idempotencyRecordID(c, key)
+		hash := sha256.Sum256(c.Body())
+		requestHash := hex.EncodeToString(hash[:])
+		deadline := time.Now().Add(config.GetIdempotencyWait())
+
+		for {
+			now := time.Now()
+			token := primitive.NewObjectID().Hex()
+			_, err := collection.InsertOne(ctx, models.IdempotencyKey{
+				ID:          recordID,
+				Token:       token,
+				RequestHash: requestHash,
+				Status:      models.IdempotencyProcessing,
+				CreatedAt:   now,
+				// Renewed while the request runs and released by the TTL
+				// index if the process dies mid-request.
+				ExpiresAt: now.Add(idempotencyLockTTL),
+			})
+			if err == nil {
+				return handleIdempotentRequest(c, collection, recordID, token)
+			}
+			if !mongo.IsDuplicateKeyError(err) {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"message": "Failed to store idempotency key",
+					"error":   err.Error(),
+				})
+			}
+
+			var record models.IdempotencyKey
+			err = collection.FindOne(ctx, bson.M{"_id": recordID}).Decode(&record)
+			if err == mongo.ErrNoDocuments {
+				// The first request failed or the key expired; try to claim it again.
+				continue
+			}
+			if err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"message": "Failed to get idempotency key",
+					"error":   err.Error(),
+				})
+			}
+
+			if record.RequestHash != requestHash {
+				return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+					"message": "Idempotency key was already used with a different request body",
+				})
+			}
+
+			if record.Status == models.IdempotencyCompleted {
+				c.Set(HeaderIdempotentReplayed, "true")
+				if record.ContentType != "" {
+					c.Set(fiber.HeaderContentType, record.ContentType)
+				}
+				return c.Status(record.ResponseStatus).Send(record.ResponseBody)
+			}
+
+			if time.Now().After(deadline) {
+				return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+					"message": "A request with this idempotency key is still being processed",
+				})
+			}
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(idempotencyPollInterval):
+			}
+		}
+	}
+}
+
+// idempotencyRecordID scopes key to the method, path and caller of the
+// request.
+func idempotencyRecordID(c *fiber.Ctx, key string) string {
+	actor := "anonymous"
+	if actorID := utils.ActorID(c); actorID != nil {
+		actor = actorID.Hex()
+	}
+	return c.Method() + " " + c.Path() + " " + actor + " " + key
+}
+
+// handleIdempotentRequest runs the request holding the record with token
+// and stores its response. The record is renewed while the request runs so
+// a slow request keeps it.
+func handleIdempotentRequest(c *fiber.Ctx, collection *mongo.Collection, recordID string, token string) error {
+	// Use a fresh context so the record is settled even if the client went away.
+	ctx := context.Background()
+	owned := bson.M{"_id": recordID, "token": token}
+
+	done := make(chan struct{})
+	go renewIdempotencyLock(collection, owned, done)
+	err := c.Next()
+	close(done)
+
+	if err != nil {
+		collection.DeleteOne(ctx, owned)
+		return err
+	}
+
+	status := c.Response().StatusCode()
+	if status >= fiber.StatusInternalServerError {
+		collection.DeleteOne(ctx, owned)
+		return nil
+	}
+
+	result, err := collection.UpdateOne(ctx, owned, bson.M{
+		"$set": bson.M{
+			"status":          models.IdempotencyCompleted,
+			"response_status": status,
+			"content_type":    string(c.Response().Header.ContentType()),
+			"response_body":   append([]byte(nil), c.Response().Body()...),
+			"expires_at":      time.Now().Add(config.GetIdempotencyTTL()),
+		},
+		"$unset": bson.M{"token": ""},
+	})
+	if err != nil {
+		fmt.Println("Failed to store idempotent response:", err)
+	} else if result.MatchedCount == 0 {
+		fmt.Println("Failed to store idempotent response: the idempotency key expired before the request finished:", recordID)
+	}
+
+	return nil
+}
+
+// renewIdempotencyLock pushes back the expiry of the record matched by
+// filter until done is closed.
+func renewIdempotencyLock(collection *mongo.Collection, filter bson.M, done <-chan struct{}) {
+	ticker := time.NewTicker(idempotencyLockTTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			_, err := collection.UpdateOne(context.Background(), filter, bson.M{
+				"$set": bson.M{"expires_at": time.Now().Add(idempotencyLockTTL)},
+			})
+			if err != nil {
+				fmt.Println("Failed to renew idempotency key:", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package middlewares
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/aldoramirezmartinez/fiber-api/middlewares"
+
+// NewTracing starts a server span for every request, continuing the trace
+// received in the traceparent header. Handlers must use c.UserContext() so
+// that Mongo commands are recorded as children of the request span.
+func NewTracing() fiber.Handler {
+	tracer := otel.Tracer(tracerName)
+	propagator := otel.GetTextMapPropagator()
+
+	return func(c *fiber.Ctx) error {
+		carrier := propagation.HeaderCarrier{}
+		c.Request().Header.VisitAll(func(key, value []byte) {
+			carrier.Set(string(key), string(value))
+		})
+		ctx := propagator.Extract(c.UserContext(), carrier)
+
+		ctx, span := tracer.Start(ctx, c.Method()+" "+c.Path(),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPMethod(c.Method()),
+				semconv.HTTPTarget(string(c.Request().RequestURI())),
+				semconv.HTTPScheme(c.Protocol()),
+				attribute.String("net.host.name", c.Hostname()),
+			),
+		)
+		defer span.End()
+
+		c.SetUserContext(ctx)
+
+		err := c.Next()
+		if err != nil {
+			span.RecordError(err)
+			// Let the app error handler write the response so the status is known.
+			if handlerErr := c.App().ErrorHandler(c, err); handlerErr != nil {
+				_ = c.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
+
+		route := c.Route().Path
+		span.SetName(c.Method() + " " + route)
+		span.SetAttributes(semconv.HTTPRoute(route))
+
+		status := c.Response().StatusCode()
+		span.SetAttributes(semconv.HTTPStatusCode(status))
+		if status >= fiber.StatusInternalServerError {
+			span.SetStatus(codes.Error, fiber.ErrInternalServerError.Message)
+		}
+
+		return nil
+	}
+}
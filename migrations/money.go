@@ -0,0 +1,177 @@
+// Package migrations rewrites stored documents when their format changes.
+// Migrations are idempotent: running one again only touches documents it
+// has not converted yet.
+package migrations
+
+import (
+	"context"
+	"strings"
+
+	"github.com/aldoramirezmartinez/fiber-api/money"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// moneyField is a stored amount. Path is dotted and goes through arrays, so
+// "item_list.subtotal" is the subtotal of every line. Amounts computed by
+// the API are rounded to the currency; prices are kept as they were typed.
+type moneyField struct {
+	Path  string
+	Round bool
+}
+
+var moneyFields = map[string][]moneyField{
+	"items": {
+		{Path: "price"},
+	},
+	"purchases": {
+		{Path: "total", Round: true},
+		{Path: "item_list.subtotal", Round: true},
+		{Path: "item_list.item.price"},
+	},
+	"purchase_details": {
+		{Path: "total", Round: true},
+	},
+}
+
+var numericTypes = bson.A{"double", "int", "long"}
+
+// MoneyMigration counts the documents converted in each collection.
+type MoneyMigration struct {
+	Collections map[string]int `json:"collections"`
+	Converted   int            `json:"converted"`
+}
+
+// MigrateMoney converts amounts stored as floats or integers to Decimal128.
+// Floats become the shortest decimal that reads back as the same float, so a
+// price stored as 19.99 becomes exactly 19.99, and subtotals and totals that
+// drifted, such as 59.970000000000006, are rounded half up to currency.
+//
+// Each document is only updated if the fields being converted still hold
+// the values that were read, so it is safe to run while the API is serving
+// requests.
+func MigrateMoney(ctx context.Context, db *mongo.Database, currency money.Currency) (MoneyMigration, error) {
+	migration := MoneyMigration{Collections: map[string]int{}}
+
+	for name, fields := range moneyFields {
+		collection := db.Collection(name)
+
+		var stale bson.A
+		for _, field := range fields {
+			stale = append(stale, bson.M{field.Path: bson.M{"$type": numericTypes}})
+		}
+
+		cursor, err := collection.Find(ctx, bson.M{"$or": stale})
+		if err != nil {
+			return migration, err
+		}
+
+		for cursor.Next(ctx) {
+			// bson.D keeps the field order, which the equality filters
+			// on embedded documents below depend on.
+			var document bson.D
+			if err := cursor.Decode(&document); err != nil {
+				cursor.Close(ctx)
+				return migration, err
+			}
+
+			id, _ := lookup(document, "_id")
+			filter := bson.M{"_id": id}
+			set := bson.M{}
+			for _, field := range fields {
+				top := strings.SplitN(field.Path, ".", 2)[0]
+				original, ok := set[top]
+				if !ok {
+					if original, ok = lookup(document, top); !ok {
+						continue
+					}
+					filter[top] = original
+				}
+
+				converted, changed := convertAmounts(original, strings.Split(field.Path, ".")[1:], field.Round, currency)
+				if changed {
+					set[top] = converted
+				}
+			}
+			if len(set) == 0 {
+				continue
+			}
+
+			result, err := collection.UpdateOne(ctx, filter, bson.M{"$set": set})
+			if err != nil {
+				cursor.Close(ctx)
+				return migration, err
+			}
+			if result.ModifiedCount > 0 {
+				migration.Collections[name]++
+				migration.Converted++
+			}
+		}
+		if err := cursor.Err(); err != nil {
+			cursor.Close(ctx)
+			return migration, err
+		}
+		cursor.Close(ctx)
+	}
+
+	return migration, nil
+}
+
+func lookup(document bson.D, key string) (interface{}, bool) {
+	for _, element := range document {
+		if element.Key == key {
+			return element.Value, true
+		}
+	}
+	return nil, false
+}
+
+// convertAmounts converts the numbers found by following path inside value.
+// Documents and arrays are copied rather than changed in place, since the
+// original value is still needed for the update filter.
+func convertAmounts(value interface{}, path []string, round bool, currency money.Currency) (interface{}, bool) {
+	switch v := value.(type) {
+	case bson.A:
+		converted := make(bson.A, len(v))
+		changed := false
+		for i, element := range v {
+			var elementChanged bool
+			converted[i], elementChanged = convertAmounts(element, path, round, currency)
+			changed = changed || elementChanged
+		}
+		return converted, changed
+	case bson.D:
+		if len(path) == 0 {
+			return value, false
+		}
+		converted := make(bson.D, len(v))
+		changed := false
+		for i, element := range v {
+			converted[i] = element
+			if element.Key == path[0] {
+				converted[i].Value, changed = convertAmounts(element.Value, path[1:], round, currency)
+			}
+		}
+		return converted, changed
+	}
+
+	if len(path) > 0 {
+		return value, false
+	}
+
+	var amount money.Amount
+	switch v := value.(type) {
+	case float64:
+		amount = money.FromFloat(v)
+	case int32:
+		amount = money.FromInt(int64(v))
+	case int64:
+		amount = money.FromInt(v)
+	default:
+		return value, false
+	}
+	if round {
+		amount = currency.Round(amount, money.HalfUp)
+	}
+	return amount, true
+}
@@ -0,0 +1,26 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// RevisionMigration counts the purchases numbered as revision 1.
+type RevisionMigration struct {
+	Purchases int64 `json:"purchases"`
+}
+
+// NumberPurchaseRevisions makes every purchase without a revision number
+// revision 1. Purchases that already have one are left alone, so it is safe
+// to run while the API is serving requests.
+func NumberPurchaseRevisions(ctx context.Context, db *mongo.Database) (RevisionMigration, error) {
+	result, err := db.Collection("purchases").UpdateMany(ctx,
+		bson.M{"revision": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revision": 1}})
+	if err != nil {
+		return RevisionMigration{}, err
+	}
+	return RevisionMigration{Purchases: result.ModifiedCount}, nil
+}
@@ -0,0 +1,141 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aldoramirezmartinez/fiber-api/models"
+	"github.com/aldoramirezmartinez/fiber-api/money"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// SnapshotMigration counts the purchase lines given an item snapshot and
+// where it came from. Missing lines reference items that were purged and
+// are left as they are.
+type SnapshotMigration struct {
+	Purchases       int `json:"purchases"`
+	FromStoredItem  int `json:"from_stored_item"`
+	FromCurrentItem int `json:"from_current_item"`
+	Missing         int `json:"missing"`
+}
+
+// legacyPurchase is the part of a purchase the snapshot migration reads.
+// Lines written by earlier versions may embed the item as it was then.
+type legacyPurchase struct {
+	ID       primitive.ObjectID `bson:"_id"`
+	Date     time.Time          `bson:"date"`
+	ItemList []struct {
+		ItemID   primitive.ObjectID   `bson:"item_id"`
+		Item     *models.Item         `bson:"item"`
+		Snapshot *models.ItemSnapshot `bson:"snapshot"`
+	} `bson:"item_list"`
+}
+
+// SnapshotPurchaseItems gives every purchase line without an item snapshot
+// one. An item embedded in the line by earlier versions is the item as it
+// was bought and is preferred; otherwise the item as it is now is used, which
+// is the best that can be done. Items without a currency are in currency.
+//
+// Lines are only updated while they still lack a snapshot, so it is safe to
+// run while the API is serving requests.
+func SnapshotPurchaseItems(ctx context.Context, db *mongo.Database, currency money.Currency) (SnapshotMigration, error) {
+	var migration SnapshotMigration
+	purchases := db.Collection("purchases")
+	items := db.Collection("items")
+	providerNames := map[primitive.ObjectID]string{}
+
+	providerName := func(id primitive.ObjectID) (string, error) {
+		if id.IsZero() {
+			return "", nil
+		}
+		if name, ok := providerNames[id]; ok {
+			return name, nil
+		}
+		var provider models.Provider
+		err := db.Collection("providers").FindOne(ctx, bson.M{"_id": id}).Decode(&provider)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return "", err
+		}
+		providerNames[id] = provider.Name
+		return provider.Name, nil
+	}
+
+	cursor, err := purchases.Find(ctx, bson.M{"item_list": bson.M{"$elemMatch": bson.M{"snapshot": bson.M{"$exists": false}}}})
+	if err != nil {
+		return migration, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var purchase legacyPurchase
+		if err := cursor.Decode(&purchase); err != nil {
+			return migration, err
+		}
+
+		filter := bson.M{"_id": purchase.ID}
+		set := bson.M{}
+		unset := bson.M{}
+		var fromStored, fromCurrent int
+		for i, line := range purchase.ItemList {
+			if line.Snapshot != nil {
+				continue
+			}
+
+			item := line.Item
+			takenAt := purchase.Date
+			if item == nil || item.Name == "" {
+				item = new(models.Item)
+				err := items.FindOne(ctx, bson.M{"_id": line.ItemID}).Decode(item)
+				if err == mongo.ErrNoDocuments {
+					migration.Missing++
+					continue
+				}
+				if err != nil {
+					return migration, err
+				}
+				takenAt = time.Now()
+				fromCurrent++
+			} else {
+				fromStored++
+			}
+
+			name, err := providerName(item.ProviderID)
+			if err != nil {
+				return migration, err
+			}
+
+			path := fmt.Sprintf("item_list.%d", i)
+			filter[path+".item_id"] = line.ItemID
+			filter[path+".snapshot"] = bson.M{"$exists": false}
+			set[path+".snapshot"] = models.ItemSnapshot{
+				Name:         item.Name,
+				Code:         item.Code,
+				UnitMeasure:  item.UnitMeasure,
+				Category:     item.Category,
+				Price:        item.Price,
+				Currency:     item.Currency.Or(currency),
+				ProviderID:   item.ProviderID,
+				ProviderName: name,
+				TakenAt:      takenAt,
+			}
+			unset[path+".item"] = ""
+		}
+		if len(set) == 0 {
+			continue
+		}
+
+		result, err := purchases.UpdateOne(ctx, filter, bson.M{"$set": set, "$unset": unset})
+		if err != nil {
+			return migration, err
+		}
+		if result.ModifiedCount > 0 {
+			migration.Purchases++
+			migration.FromStoredItem += fromStored
+			migration.FromCurrentItem += fromCurrent
+		}
+	}
+	return migration, cursor.Err()
+}
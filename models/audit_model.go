@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AuditEntry is one link of the audit chain. Hash is the SHA-256 of the
+// entry's other fields, which include the previous entry's hash.
+type AuditEntry struct {
+	ID        primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	Sequence  int64               `json:"sequence" bson:"sequence"`
+	PrevHash  string              `json:"prev_hash" bson:"prev_hash"`
+	Entity    string              `json:"entity" bson:"entity"`
+	EntityID  primitive.ObjectID  `json:"entity_id" bson:"entity_id"`
+	Action    string              `json:"action" bson:"action"`
+	Actor     *primitive.ObjectID `json:"actor,omitempty" bson:"actor,omitempty"`
+	RequestID string              `json:"request_id,omitempty" bson:"request_id,omitempty"`
+	Timestamp time.Time           `json:"timestamp" bson:"timestamp"`
+	Changes   []FieldChange       `json:"changes" bson:"changes"`
+	Hash      string              `json:"hash" bson:"hash,omitempty"`
+}
+
+// FieldChange is one changed field in dotted path form, such as
+// item_list.0.quantity. Before is missing for added fields and After for
+// removed ones.
+type FieldChange struct {
+	Field  string      `json:"field" bson:"field"`
+	Before interface{} `json:"before,omitempty" bson:"before,omitempty"`
+	After  interface{} `json:"after,omitempty" bson:"after,omitempty"`
+}
+
+// AuditCheckpoint is a signed statement that the audit chain reached Hash
+// at Sequence.
+type AuditCheckpoint struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Sequence  int64              `json:"sequence" bson:"sequence"`
+	Hash      string             `json:"hash" bson:"hash"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+	Signature string             `json:"signature" bson:"signature"`
+}
+
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"
+	AuditRestore = "restore"
+)
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/aldoramirezmartinez/fiber-api/money"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ExchangeRate says one unit of From buys Rate units of To from
+// EffectiveDate until the next rate for the pair takes effect.
+type ExchangeRate struct {
+	ID            primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	From          money.Currency     `json:"from" bson:"from"`
+	To            money.Currency     `json:"to" bson:"to"`
+	Rate          money.Amount       `json:"rate" bson:"rate"`
+	EffectiveDate time.Time          `json:"effective_date" bson:"effective_date"`
+	CreatedAt     time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt     time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+func (r *ExchangeRate) Validate() error {
+	if r.From == "" || r.To == "" {
+		return errors.New("from and to are required")
+	}
+	if r.From == r.To {
+		return errors.New("from and to must be different currencies")
+	}
+	if r.Rate.Sign() <= 0 {
+		return errors.New("rate must be positive")
+	}
+	if r.EffectiveDate.IsZero() {
+		return errors.New("effective_date is required")
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// IdempotencyKey is the record of a request made with an Idempotency-Key.
+// Token identifies the request holding it while it is processing.
+type IdempotencyKey struct {
+	ID             string    `json:"id" bson:"_id"`
+	Token          string    `json:"-" bson:"token,omitempty"`
+	RequestHash    string    `json:"request_hash" bson:"request_hash"`
+	Status         string    `json:"status" bson:"status"`
+	ResponseStatus int       `json:"response_status,omitempty" bson:"response_status,omitempty"`
+	ContentType    string    `json:"content_type,omitempty" bson:"content_type,omitempty"`
+	ResponseBody   []byte    `json:"response_body,omitempty" bson:"response_body,omitempty"`
+	CreatedAt      time.Time `json:"created_at" bson:"created_at"`
+	ExpiresAt      time.Time `json:"expires_at" bson:"expires_at"`
+}
+
+const (
+	IdempotencyProcessing = "processing"
+	IdempotencyCompleted  = "completed"
+)
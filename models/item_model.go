@@ -1,18 +1,43 @@
 package models
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/aldoramirezmartinez/fiber-api/money"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 type Item struct {
-	ID          primitive.ObjectID `json:"-" bson:"_id,omitempty"`
-	Name        string             `json:"name,omitempty" bson:"name,omitempty"`
-	Code        string             `json:"code,omitempty" bson:"code,omitempty"`
-	UnitMeasure string             `json:"unit_measure,omitempty" bson:"unit_measure,omitempty"`
-	Price       float64            `json:"price,omitempty" bson:"price,omitempty"`
-	Description string             `json:"description,omitempty" bson:"description,omitempty"`
-	ProviderID  primitive.ObjectID `json:"-" bson:"provider_id,omitempty"`
+	ID          primitive.ObjectID  `json:"-" bson:"_id,omitempty"`
+	Name        string              `json:"name,omitempty" bson:"name,omitempty"`
+	Code        string              `json:"code,omitempty" bson:"code,omitempty"`
+	UnitMeasure string              `json:"unit_measure,omitempty" bson:"unit_measure,omitempty"`
+	Price       money.Amount        `json:"price" bson:"price"`
+	Currency    money.Currency      `json:"currency,omitempty" bson:"currency,omitempty"`
+	Description string              `json:"description,omitempty" bson:"description,omitempty"`
+	Category    string              `json:"category,omitempty" bson:"category,omitempty"`
+	ProviderID  primitive.ObjectID  `json:"-" bson:"provider_id,omitempty"`
+	Version     int64               `json:"version,omitempty" bson:"version,omitempty"`
+	DeletedAt   *time.Time          `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
+	DeletedBy   *primitive.ObjectID `json:"deleted_by,omitempty" bson:"deleted_by,omitempty"`
 }
 
 type ItemResponse struct {
 	Item     Item     `json:"item,omitempty" bson:"item,omitempty"`
 	Provider Provider `json:"provider,omitempty" bson:"provider,omitempty"`
 }
+
+func (i *Item) Validate() error {
+	if strings.TrimSpace(i.Name) == "" {
+		return errors.New("name is required")
+	}
+	if strings.TrimSpace(i.Code) == "" {
+		return errors.New("code is required")
+	}
+	if i.Price.IsNegative() {
+		return errors.New("price must not be negative")
+	}
+	return nil
+}
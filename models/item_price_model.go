@@ -0,0 +1,57 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/aldoramirezmartinez/fiber-api/money"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Item price statuses. Scheduled prices are applied by a background job
+// once their effective date arrives.
+const (
+	ItemPriceApplied   = "applied"
+	ItemPriceScheduled = "scheduled"
+	ItemPriceCancelled = "cancelled"
+)
+
+// ItemPrice is an entry in the price history of an item: from
+// EffectiveDate the item cost Price, in Currency. PreviousPrice is unset for
+// the first price of an item and for scheduled prices not applied yet.
+type ItemPrice struct {
+	ID            primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	ItemID        primitive.ObjectID  `json:"item_id" bson:"item_id"`
+	Price         money.Amount        `json:"price" bson:"price"`
+	PreviousPrice *money.Amount       `json:"previous_price,omitempty" bson:"previous_price,omitempty"`
+	Currency      money.Currency      `json:"currency" bson:"currency"`
+	EffectiveDate time.Time           `json:"effective_date" bson:"effective_date"`
+	Reason        string              `json:"reason,omitempty" bson:"reason,omitempty"`
+	Actor         *primitive.ObjectID `json:"actor,omitempty" bson:"actor,omitempty"`
+	Status        string              `json:"status" bson:"status"`
+	CreatedAt     time.Time           `json:"created_at" bson:"created_at"`
+	AppliedAt     *time.Time          `json:"applied_at,omitempty" bson:"applied_at,omitempty"`
+}
+
+// ItemPriceChangeRequest is the body of POST /api/items/:id/prices. An
+// empty effective date means now.
+type ItemPriceChangeRequest struct {
+	Price         money.Amount `json:"price"`
+	EffectiveDate string       `json:"effective_date,omitempty"`
+	Reason        string       `json:"reason,omitempty"`
+}
+
+// ItemPriceAt is the price of an item on a date.
+type ItemPriceAt struct {
+	ItemID   primitive.ObjectID `json:"item_id"`
+	At       time.Time          `json:"at"`
+	Price    money.Amount       `json:"price"`
+	Currency money.Currency     `json:"currency"`
+}
+
+func (r *ItemPriceChangeRequest) Validate() error {
+	if r.Price.IsNegative() {
+		return errors.New("price must not be negative")
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Job is a unit of background work in the queue. Payload is the JSON encoded
+// argument given to the handler of Type.
+type Job struct {
+	ID      primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Type    string             `json:"type" bson:"type"`
+	Payload string             `json:"payload" bson:"payload"`
+	// Recurring jobs go back to pending after every run instead of
+	// finishing; see queue.Schedule.
+	Recurring   bool       `json:"recurring,omitempty" bson:"recurring,omitempty"`
+	Status      string     `json:"status" bson:"status"`
+	RunAt       time.Time  `json:"run_at" bson:"run_at"`
+	Attempts    int        `json:"attempts" bson:"attempts"`
+	LockedBy    string     `json:"locked_by,omitempty" bson:"locked_by,omitempty"`
+	LockedUntil *time.Time `json:"locked_until,omitempty" bson:"locked_until,omitempty"`
+	LastError   string     `json:"last_error,omitempty" bson:"last_error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at" bson:"created_at"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty" bson:"finished_at,omitempty"`
+}
+
+const (
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	// JobDead marks a job that used up its attempts. It stays in the queue
+	// until an admin retries it.
+	JobDead = "dead"
+)
@@ -0,0 +1,23 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// Live update message types.
+const (
+	LiveTypePurchase = "purchase"
+	LiveTypeItem     = "item"
+	LiveTypeReset    = "reset"
+)
+
+// LiveMessage is one change sent over the live update streams. ID resumes
+// the stream right after it. Data is a PurchaseResponsev2 or ItemResponse
+// with the document as it is now; it is left out for deleted documents
+// unless the caller asked for include_deleted. A reset message has no ID and
+// tells the client to reload, since changes were missed.
+type LiveMessage struct {
+	ID         string              `json:"id,omitempty"`
+	Type       string              `json:"type"`
+	Operation  string              `json:"operation,omitempty"`
+	DocumentID *primitive.ObjectID `json:"document_id,omitempty"`
+	Data       interface{}         `json:"data,omitempty"`
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Domain event types written to the outbox.
+const (
+	EventTypePurchaseCreated       = "PurchaseCreated"
+	EventTypePurchaseStatusChanged = "PurchaseStatusChanged"
+	EventTypePurchaseAmended       = "PurchaseAmended"
+	EventTypeItemPriceChanged      = "ItemPriceChanged"
+	EventTypeProviderUpdated       = "ProviderUpdated"
+)
+
+// OutboxEvent is a domain event waiting in the outbox to be relayed. Data is
+// the JSON encoded event payload. DeliveredTo names the sinks that already
+// accepted it, so a retry only goes to the ones that failed.
+type OutboxEvent struct {
+	ID            primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Type          string             `json:"type" bson:"type"`
+	AggregateType string             `json:"aggregate_type" bson:"aggregate_type"`
+	AggregateID   primitive.ObjectID `json:"aggregate_id" bson:"aggregate_id"`
+	OccurredAt    time.Time          `json:"occurred_at" bson:"occurred_at"`
+	Data          string             `json:"data" bson:"data"`
+	PublishedAt   *time.Time         `json:"published_at,omitempty" bson:"published_at,omitempty"`
+	DeliveredTo   []string           `json:"delivered_to" bson:"delivered_to"`
+	Attempts      int                `json:"attempts" bson:"attempts"`
+	NextAttemptAt time.Time          `json:"next_attempt_at" bson:"next_attempt_at"`
+	LockedUntil   *time.Time         `json:"-" bson:"locked_until,omitempty"`
+	LastError     string             `json:"last_error,omitempty" bson:"last_error,omitempty"`
+}
+
+// PurchaseStatusChange is the data of a PurchaseStatusChanged event.
+type PurchaseStatusChange struct {
+	PreviousStatus string             `json:"previous_status"`
+	Status         string             `json:"status"`
+	Purchase       PurchaseResponsev2 `json:"purchase"`
+}
+
+// PurchaseAmendment is the data of a PurchaseAmended event.
+type PurchaseAmendment struct {
+	PreviousRevision int                `json:"previous_revision"`
+	Revision         int                `json:"revision"`
+	Reason           string             `json:"reason,omitempty"`
+	Changes          []FieldChange      `json:"changes"`
+	Purchase         PurchaseResponsev2 `json:"purchase"`
+}
@@ -1,10 +1,29 @@
 package models
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/aldoramirezmartinez/fiber-api/money"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 type Provider struct {
-	ID        primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
-	Name      string             `json:"name,omitempty" bson:"name,omitempty"`
-	Address   string             `json:"address,omitempty" bson:"address,omitempty"`
-	Telephone string             `json:"telephone,omitempty" bson:"telephone,omitempty"`
+	ID        primitive.ObjectID  `json:"id,omitempty" bson:"_id,omitempty"`
+	Name      string              `json:"name,omitempty" bson:"name,omitempty"`
+	Address   string              `json:"address,omitempty" bson:"address,omitempty"`
+	Telephone string              `json:"telephone,omitempty" bson:"telephone,omitempty"`
+	Currency  money.Currency      `json:"currency,omitempty" bson:"currency,omitempty"`
+	TaxType   string              `json:"tax_type,omitempty" bson:"tax_type,omitempty"`
+	Version   int64               `json:"version,omitempty" bson:"version,omitempty"`
+	DeletedAt *time.Time          `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
+	DeletedBy *primitive.ObjectID `json:"deleted_by,omitempty" bson:"deleted_by,omitempty"`
+}
+
+func (p *Provider) Validate() error {
+	if strings.TrimSpace(p.Name) == "" {
+		return errors.New("name is required")
+	}
+	return ValidateProviderTaxType(p.TaxType)
 }
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/aldoramirezmartinez/fiber-api/money"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ProviderPrice is an offer in a provider's price list: the provider sells
+// the item at Price, in Currency, for orders of at least MinQuantity, and
+// delivers in LeadTimeDays. The offer is valid from ValidFrom until, but
+// not including, ValidTo; either end may be open.
+type ProviderPrice struct {
+	ID           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	ItemID       primitive.ObjectID `json:"item_id" bson:"item_id"`
+	ProviderID   primitive.ObjectID `json:"provider_id" bson:"provider_id"`
+	Price        money.Amount       `json:"price" bson:"price"`
+	Currency     money.Currency     `json:"currency" bson:"currency"`
+	MinQuantity  int                `json:"min_quantity" bson:"min_quantity"`
+	LeadTimeDays int                `json:"lead_time_days" bson:"lead_time_days"`
+	ValidFrom    *time.Time         `json:"valid_from,omitempty" bson:"valid_from,omitempty"`
+	ValidTo      *time.Time         `json:"valid_to,omitempty" bson:"valid_to,omitempty"`
+	CreatedAt    time.Time          `json:"created_at,omitempty" bson:"created_at,omitempty"`
+	UpdatedAt    time.Time          `json:"updated_at,omitempty" bson:"updated_at,omitempty"`
+}
+
+// ProviderPriceRequest is the body of POST and PUT /api/provider-prices.
+// The validity dates may be plain YYYY-MM-DD dates, and the currency
+// defaults to the provider's.
+type ProviderPriceRequest struct {
+	ItemID       primitive.ObjectID `json:"item_id"`
+	ProviderID   primitive.ObjectID `json:"provider_id"`
+	Price        money.Amount       `json:"price"`
+	Currency     money.Currency     `json:"currency,omitempty"`
+	MinQuantity  int                `json:"min_quantity,omitempty"`
+	LeadTimeDays int                `json:"lead_time_days,omitempty"`
+	ValidFrom    string             `json:"valid_from,omitempty"`
+	ValidTo      string             `json:"valid_to,omitempty"`
+}
+
+// SourceOption is an offer that can supply a quantity of an item, priced in
+// the currency asked for.
+type SourceOption struct {
+	Offer        ProviderPrice  `json:"offer"`
+	ProviderName string         `json:"provider_name"`
+	UnitPrice    money.Amount   `json:"unit_price"`
+	Total        money.Amount   `json:"total"`
+	Currency     money.Currency `json:"currency"`
+}
+
+// BestSource answers where to buy Quantity of an item on Date: Best is the
+// cheapest offer, and Options lists every valid offer, cheapest first.
+type BestSource struct {
+	ItemID   primitive.ObjectID `json:"item_id"`
+	Quantity int                `json:"quantity"`
+	Date     time.Time          `json:"date"`
+	Best     SourceOption       `json:"best"`
+	Options  []SourceOption     `json:"options"`
+}
+
+func (p *ProviderPrice) Validate() error {
+	if p.ItemID.IsZero() {
+		return errors.New("item_id is required")
+	}
+	if p.ProviderID.IsZero() {
+		return errors.New("provider_id is required")
+	}
+	if p.Price.IsNegative() {
+		return errors.New("price must not be negative")
+	}
+	if p.MinQuantity < 0 {
+		return errors.New("min_quantity must not be negative")
+	}
+	if p.LeadTimeDays < 0 {
+		return errors.New("lead_time_days must not be negative")
+	}
+	if p.ValidFrom != nil && p.ValidTo != nil && !p.ValidTo.After(*p.ValidFrom) {
+		return errors.New("valid_to must be after valid_from")
+	}
+	return nil
+}
+
+// Allows reports whether the offer applies to orders of quantity.
+func (p *ProviderPrice) Allows(quantity int) bool {
+	return quantity >= p.MinQuantity
+}
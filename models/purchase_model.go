@@ -7,12 +7,15 @@ import (
 )
 
 type Purchase struct {
-	ID            primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
-	PurchaseOrder string             `json:"purchase_order,omitempty" bson:"purchase_order,omitempty"`
-	Date          time.Time          `json:"date,omitempty" bson:"date,omitempty"`
-	Status        string             `json:"status,omitempty" bson:"status,omitempty"`
-	UserID        primitive.ObjectID `json:"user_id,omitempty" bson:"user_id,omitempty"`
-	ProviderID    primitive.ObjectID `json:"provider_id,omitempty" bson:"provider_id,omitempty"`
+	ID            primitive.ObjectID  `json:"id,omitempty" bson:"_id,omitempty"`
+	PurchaseOrder string              `json:"purchase_order,omitempty" bson:"purchase_order,omitempty"`
+	Date          time.Time           `json:"date,omitempty" bson:"date,omitempty"`
+	Status        string              `json:"status,omitempty" bson:"status,omitempty"`
+	UserID        primitive.ObjectID  `json:"user_id,omitempty" bson:"user_id,omitempty"`
+	ProviderID    primitive.ObjectID  `json:"provider_id,omitempty" bson:"provider_id,omitempty"`
+	Version       int64               `json:"version,omitempty" bson:"version,omitempty"`
+	DeletedAt     *time.Time          `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
+	DeletedBy     *primitive.ObjectID `json:"deleted_by,omitempty" bson:"deleted_by,omitempty"`
 }
 
 type PurchaseResponse struct {
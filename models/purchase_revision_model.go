@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PurchaseRevision is a revision of a purchase. Earlier revisions are
+// archived when an amendment replaces them, and SupersededAt and
+// SupersededBy say when and by whom; both are empty for the current one.
+type PurchaseRevision struct {
+	ID           primitive.ObjectID  `json:"-" bson:"_id,omitempty"`
+	PurchaseID   primitive.ObjectID  `json:"purchase_id" bson:"purchase_id"`
+	Revision     int                 `json:"revision" bson:"revision"`
+	Current      bool                `json:"current" bson:"-"`
+	Purchase     Purchasev2          `json:"purchase" bson:"purchase"`
+	SupersededAt *time.Time          `json:"superseded_at,omitempty" bson:"superseded_at"`
+	SupersededBy *primitive.ObjectID `json:"superseded_by,omitempty" bson:"superseded_by,omitempty"`
+}
+
+// PurchaseRevisionDiff lists what changed from one revision of a purchase
+// to another.
+type PurchaseRevisionDiff struct {
+	PurchaseID primitive.ObjectID `json:"purchase_id"`
+	From       int                `json:"from"`
+	To         int                `json:"to"`
+	Changes    []FieldChange      `json:"changes"`
+}
@@ -1,13 +1,21 @@
 package models
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"time"
+
+	"github.com/aldoramirezmartinez/fiber-api/money"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 type PurchaseDetail struct {
-	ID         primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
-	Quantity   int                `json:"quantity,omitempty" bson:"quantity,omitempty"`
-	Total      float64            `json:"total,omitempty" bson:"total,omitempty"`
-	ItemID     primitive.ObjectID `json:"item_id,omitempty" bson:"item_id,omitempty"`
-	PurchaseID primitive.ObjectID `json:"purchase_id,omitempty" bson:"purchase_id,omitempty"`
+	ID         primitive.ObjectID  `json:"id,omitempty" bson:"_id,omitempty"`
+	Quantity   int                 `json:"quantity,omitempty" bson:"quantity,omitempty"`
+	Total      money.Amount        `json:"total,omitempty" bson:"total,omitempty"`
+	ItemID     primitive.ObjectID  `json:"item_id,omitempty" bson:"item_id,omitempty"`
+	PurchaseID primitive.ObjectID  `json:"purchase_id,omitempty" bson:"purchase_id,omitempty"`
+	Version    int64               `json:"version,omitempty" bson:"version,omitempty"`
+	DeletedAt  *time.Time          `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
+	DeletedBy  *primitive.ObjectID `json:"deleted_by,omitempty" bson:"deleted_by,omitempty"`
 }
 
 type PurchaseDetailResponse struct {
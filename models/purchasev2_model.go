@@ -1,27 +1,127 @@
 package models
 
 import (
+	"errors"
+	"strings"
 	"time"
 
+	"github.com/aldoramirezmartinez/fiber-api/money"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// PurchaseStatusDraft is the status of purchases created from an RFQ quote
+// or from requisitions, which still have to be reviewed before they are
+// approved.
+const PurchaseStatusDraft = "draft"
+
+// Purchasev2 totals: each line's Subtotal less the purchase DiscountAmount,
+// plus ChargesTotal, is Total, before taxes; GrandTotal is Total plus Tax
+// minus Withholding.
+// RequisitionIDs names the requisitions a purchase was converted from; it
+// is set by the server only. Revision starts at 1 and goes up each time a
+// purchase that left draft is amended; RevisionReason and RevisedAt describe
+// the latest amendment.
 type Purchasev2 struct {
-	ID            primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
-	PurchaseOrder string             `json:"purchase_order,omitempty" bson:"purchase_order,omitempty"`
-	Date          time.Time          `json:"date,omitempty" bson:"date,omitempty"`
-	Status        string             `json:"status,omitempty" bson:"status,omitempty"`
-	ItemList      []PurchaseDetailv2 `json:"item_list,omitempty" bson:"item_list,omitempty"`
-	Total         float64            `json:"total,omitempty" bson:"total,omitempty"`
-	UserID        primitive.ObjectID `json:"-" bson:"user_id,omitempty"`
-	ProviderID    primitive.ObjectID `json:"-" bson:"provider_id,omitempty"`
+	ID             primitive.ObjectID   `json:"id,omitempty" bson:"_id,omitempty"`
+	PurchaseOrder  string               `json:"purchase_order,omitempty" bson:"purchase_order,omitempty"`
+	Date           time.Time            `json:"date,omitempty" bson:"date,omitempty"`
+	Status         string               `json:"status,omitempty" bson:"status,omitempty"`
+	ItemList       []PurchaseDetailv2   `json:"item_list,omitempty" bson:"item_list,omitempty"`
+	Discount       *Discount            `json:"discount,omitempty" bson:"discount,omitempty"`
+	DiscountAmount money.Amount         `json:"discount_amount" bson:"discount_amount"`
+	Charges        []Charge             `json:"charges,omitempty" bson:"charges,omitempty"`
+	ChargesTotal   money.Amount         `json:"charges_total" bson:"charges_total"`
+	Total          money.Amount         `json:"total" bson:"total"`
+	Tax            money.Amount         `json:"tax" bson:"tax"`
+	Withholding    money.Amount         `json:"withholding" bson:"withholding"`
+	GrandTotal     money.Amount         `json:"grand_total" bson:"grand_total"`
+	Currency       money.Currency       `json:"currency,omitempty" bson:"currency,omitempty"`
+	BaseCurrency   money.Currency       `json:"base_currency,omitempty" bson:"base_currency,omitempty"`
+	ExchangeRate   money.Amount         `json:"exchange_rate" bson:"exchange_rate"`
+	BaseTotal      money.Amount         `json:"base_total" bson:"base_total"`
+	UserID         primitive.ObjectID   `json:"-" bson:"user_id,omitempty"`
+	ProviderID     primitive.ObjectID   `json:"-" bson:"provider_id,omitempty"`
+	RequisitionIDs []primitive.ObjectID `json:"requisition_ids,omitempty" bson:"requisition_ids,omitempty"`
+	Revision       int                  `json:"revision" bson:"revision,omitempty"`
+	RevisionReason string               `json:"revision_reason,omitempty" bson:"revision_reason,omitempty"`
+	RevisedAt      *time.Time           `json:"revised_at,omitempty" bson:"revised_at,omitempty"`
+	Version        int64                `json:"version,omitempty" bson:"version,omitempty"`
+	DeletedAt      *time.Time           `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
+	DeletedBy      *primitive.ObjectID  `json:"deleted_by,omitempty" bson:"deleted_by,omitempty"`
 }
 
+// PurchaseDetailv2 keeps a Snapshot of the item as it was when the line was
+// added, which later item edits do not change; CurrentItem is only filled
+// in when a request asks for it. GrossAmount is UnitPrice × Quantity and
+// Subtotal is GrossAmount less DiscountAmount. OrderDiscount and LandedCost
+// are the line's share of the purchase discount and charges, and
+// LandedUnitCost is what one unit cost once both are spread over the line.
 type PurchaseDetailv2 struct {
-	ItemID   primitive.ObjectID `json:"item_id,omitempty" bson:"item_id,omitempty"`
-	Item     Item               `json:"item,omitempty" bson:"item,omitempty"`
-	Quantity int                `json:"quantity,omitempty" bson:"quantity,omitempty"`
-	Subtotal float64            `json:"subtotal,omitempty" bson:"subtotal,omitempty"`
+	ItemID         primitive.ObjectID `json:"item_id,omitempty" bson:"item_id,omitempty"`
+	Snapshot       *ItemSnapshot      `json:"snapshot,omitempty" bson:"snapshot,omitempty"`
+	CurrentItem    *Item              `json:"current_item,omitempty" bson:"-"`
+	Quantity       int                `json:"quantity,omitempty" bson:"quantity,omitempty"`
+	UnitPrice      money.Amount       `json:"unit_price" bson:"unit_price"`
+	Discount       *Discount          `json:"discount,omitempty" bson:"discount,omitempty"`
+	GrossAmount    money.Amount       `json:"gross_amount" bson:"gross_amount"`
+	DiscountAmount money.Amount       `json:"discount_amount" bson:"discount_amount"`
+	Subtotal       money.Amount       `json:"subtotal" bson:"subtotal"`
+	OrderDiscount  money.Amount       `json:"order_discount" bson:"order_discount"`
+	LandedCost     money.Amount       `json:"landed_cost" bson:"landed_cost"`
+	LandedUnitCost money.Amount       `json:"landed_unit_cost" bson:"landed_unit_cost"`
+	Tax            *LineTax           `json:"tax,omitempty" bson:"tax,omitempty"`
+}
+
+// ItemSnapshot is an item as it was bought. Price is in Currency, the
+// currency of the item or of the provider offer it came from, before any
+// conversion to the purchase currency. ProviderPriceID and MinQuantity are
+// only set for prices taken from a provider price list, and QuoteID for
+// prices taken from an RFQ quote.
+type ItemSnapshot struct {
+	Name            string              `json:"name" bson:"name"`
+	Code            string              `json:"code" bson:"code"`
+	UnitMeasure     string              `json:"unit_measure,omitempty" bson:"unit_measure,omitempty"`
+	Category        string              `json:"category,omitempty" bson:"category,omitempty"`
+	Price           money.Amount        `json:"price" bson:"price"`
+	Currency        money.Currency      `json:"currency" bson:"currency"`
+	ProviderID      primitive.ObjectID  `json:"provider_id,omitempty" bson:"provider_id,omitempty"`
+	ProviderName    string              `json:"provider_name,omitempty" bson:"provider_name,omitempty"`
+	ProviderPriceID *primitive.ObjectID `json:"provider_price_id,omitempty" bson:"provider_price_id,omitempty"`
+	MinQuantity     int                 `json:"min_quantity,omitempty" bson:"min_quantity,omitempty"`
+	LeadTimeDays    int                 `json:"lead_time_days,omitempty" bson:"lead_time_days,omitempty"`
+	QuoteID         *primitive.ObjectID `json:"quote_id,omitempty" bson:"quote_id,omitempty"`
+	TakenAt         time.Time           `json:"taken_at" bson:"taken_at"`
+}
+
+// Discount types. Percent values are percentages, so 10 is 10% off; amount
+// values come off the whole line or purchase.
+const (
+	DiscountPercent = "percent"
+	DiscountAmount  = "amount"
+)
+
+type Discount struct {
+	Type  string       `json:"type" bson:"type"`
+	Value money.Amount `json:"value" bson:"value"`
+}
+
+// Charge types and how a charge is spread over the lines for landed cost.
+const (
+	ChargeShipping  = "shipping"
+	ChargeHandling  = "handling"
+	ChargeSurcharge = "surcharge"
+
+	AllocateByValue    = "value"
+	AllocateByQuantity = "quantity"
+)
+
+// Charge is an amount added to a purchase, such as freight. Allocation
+// defaults to by value.
+type Charge struct {
+	Type        string       `json:"type" bson:"type"`
+	Description string       `json:"description,omitempty" bson:"description,omitempty"`
+	Amount      money.Amount `json:"amount" bson:"amount"`
+	Allocation  string       `json:"allocation,omitempty" bson:"allocation,omitempty"`
 }
 
 type PurchaseResponsev2 struct {
@@ -29,3 +129,73 @@ type PurchaseResponsev2 struct {
 	User     User       `json:"user,omitempty" bson:"user,omitempty"`
 	Provider Provider   `json:"provider,omitempty" bson:"provider,omitempty"`
 }
+
+func (p *Purchasev2) Validate() error {
+	if strings.TrimSpace(p.PurchaseOrder) == "" {
+		return errors.New("purchase_order is required")
+	}
+	if len(p.ItemList) == 0 {
+		return errors.New("item_list must not be empty")
+	}
+	for _, detail := range p.ItemList {
+		if detail.Quantity <= 0 {
+			return errors.New("item quantity must be positive")
+		}
+	}
+	return p.ValidateAdjustments()
+}
+
+// ValidateAdjustments checks the discounts and charges of a purchase.
+func (p *Purchasev2) ValidateAdjustments() error {
+	if err := p.Discount.Validate(); err != nil {
+		return err
+	}
+	for _, detail := range p.ItemList {
+		if err := detail.Discount.Validate(); err != nil {
+			return err
+		}
+	}
+	for _, charge := range p.Charges {
+		if err := charge.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate accepts a nil discount.
+func (d *Discount) Validate() error {
+	if d == nil {
+		return nil
+	}
+	switch d.Type {
+	case DiscountPercent:
+		if d.Value.IsNegative() || d.Value.Cmp(money.FromInt(100)) > 0 {
+			return errors.New("percent discounts must be between 0 and 100")
+		}
+	case DiscountAmount:
+		if d.Value.IsNegative() {
+			return errors.New("discount amounts must not be negative")
+		}
+	default:
+		return errors.New("discount type must be percent or amount")
+	}
+	return nil
+}
+
+func (c *Charge) Validate() error {
+	switch c.Type {
+	case ChargeShipping, ChargeHandling, ChargeSurcharge:
+	default:
+		return errors.New("charge type must be shipping, handling or surcharge")
+	}
+	switch c.Allocation {
+	case "", AllocateByValue, AllocateByQuantity:
+	default:
+		return errors.New("charge allocation must be value or quantity")
+	}
+	if c.Amount.IsNegative() {
+		return errors.New("charge amounts must not be negative")
+	}
+	return nil
+}
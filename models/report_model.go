@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/aldoramirezmartinez/fiber-api/money"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PurchaseReport totals the purchases dated in [From, To) in Currency.
+// Purchases lists each purchase counted, with the revision it was counted
+// at.
+type PurchaseReport struct {
+	Currency  money.Currency        `json:"currency"`
+	From      *time.Time            `json:"from,omitempty"`
+	To        *time.Time            `json:"to,omitempty"`
+	Count     int                   `json:"count"`
+	Total     money.Amount          `json:"total"`
+	Providers []ProviderPurchaseSum `json:"providers"`
+	Purchases []ReportedPurchase    `json:"purchases"`
+}
+
+type ProviderPurchaseSum struct {
+	ProviderID primitive.ObjectID `json:"provider_id"`
+	Count      int                `json:"count"`
+	Total      money.Amount       `json:"total"`
+}
+
+type ReportedPurchase struct {
+	ID            primitive.ObjectID `json:"id"`
+	PurchaseOrder string             `json:"purchase_order,omitempty"`
+	ProviderID    primitive.ObjectID `json:"provider_id"`
+	Date          time.Time          `json:"date"`
+	Revision      int                `json:"revision"`
+	Total         money.Amount       `json:"total"`
+}
@@ -0,0 +1,108 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Requisition statuses. Pending requisitions wait for an admin to approve
+// or reject them; approved ones are converted into draft purchases, and
+// become converted once every line is on a purchase.
+const (
+	RequisitionPending   = "pending"
+	RequisitionApproved  = "approved"
+	RequisitionRejected  = "rejected"
+	RequisitionConverted = "converted"
+	RequisitionCancelled = "cancelled"
+)
+
+// Requisition is an employee's request for items, which buyers turn into
+// purchases once it is approved.
+type Requisition struct {
+	ID              primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	RequesterID     primitive.ObjectID  `json:"requester_id" bson:"requester_id"`
+	Lines           []RequisitionLine   `json:"lines" bson:"lines"`
+	NeededBy        *time.Time          `json:"needed_by,omitempty" bson:"needed_by,omitempty"`
+	Justification   string              `json:"justification" bson:"justification"`
+	Status          string              `json:"status" bson:"status"`
+	ReviewedBy      *primitive.ObjectID `json:"reviewed_by,omitempty" bson:"reviewed_by,omitempty"`
+	ReviewedAt      *time.Time          `json:"reviewed_at,omitempty" bson:"reviewed_at,omitempty"`
+	RejectionReason string              `json:"rejection_reason,omitempty" bson:"rejection_reason,omitempty"`
+	Version         int64               `json:"version" bson:"version"`
+	CreatedAt       time.Time           `json:"created_at" bson:"created_at"`
+	UpdatedAt       time.Time           `json:"updated_at" bson:"updated_at"`
+}
+
+// RequisitionLine is an item and quantity requested. PurchaseID is set
+// once the line is on a purchase. Name and Code are copied from the item
+// when the requisition is created.
+type RequisitionLine struct {
+	ItemID     primitive.ObjectID  `json:"item_id" bson:"item_id"`
+	Quantity   int                 `json:"quantity" bson:"quantity"`
+	Name       string              `json:"name,omitempty" bson:"name,omitempty"`
+	Code       string              `json:"code,omitempty" bson:"code,omitempty"`
+	PurchaseID *primitive.ObjectID `json:"purchase_id,omitempty" bson:"purchase_id,omitempty"`
+}
+
+// RequisitionRequest is the body of POST /api/requisitions. The requester
+// is the caller; needed_by may be a plain YYYY-MM-DD date.
+type RequisitionRequest struct {
+	Lines         []RequisitionLine `json:"lines"`
+	NeededBy      string            `json:"needed_by,omitempty"`
+	Justification string            `json:"justification"`
+}
+
+// RequisitionReview is the body of the approve and reject endpoints. A
+// reason is required to reject.
+type RequisitionReview struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+// RequisitionConversionRequest is the optional body of
+// POST /api/requisitions/convert. Without requisition IDs every approved
+// requisition is converted. The user of the purchases defaults to the
+// caller.
+type RequisitionConversionRequest struct {
+	RequisitionIDs []primitive.ObjectID `json:"requisition_ids,omitempty"`
+	UserID         *primitive.ObjectID  `json:"user_id,omitempty"`
+}
+
+// RequisitionConversion reports the draft purchases a conversion created,
+// one per provider, and the lines it could not place on any.
+type RequisitionConversion struct {
+	Purchases []PurchaseResponsev2 `json:"purchases"`
+	Skipped   []SkippedRequisition `json:"skipped"`
+}
+
+// SkippedRequisition is a requisition line left unconverted, and why.
+type SkippedRequisition struct {
+	RequisitionID primitive.ObjectID `json:"requisition_id"`
+	ItemID        primitive.ObjectID `json:"item_id"`
+	Reason        string             `json:"reason"`
+}
+
+func (r *RequisitionRequest) Validate() error {
+	if len(r.Lines) == 0 {
+		return errors.New("lines must not be empty")
+	}
+	items := map[primitive.ObjectID]bool{}
+	for _, line := range r.Lines {
+		if line.ItemID.IsZero() {
+			return errors.New("every line needs an item_id")
+		}
+		if line.Quantity <= 0 {
+			return errors.New("line quantity must be positive")
+		}
+		if items[line.ItemID] {
+			return errors.New("an item can only appear on one line")
+		}
+		items[line.ItemID] = true
+	}
+	if strings.TrimSpace(r.Justification) == "" {
+		return errors.New("justification is required")
+	}
+	return nil
+}
@@ -0,0 +1,238 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/aldoramirezmartinez/fiber-api/money"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RFQ statuses. An RFQ takes quotes while it is open, and is awarded when
+// one of its quotes is converted into a purchase.
+const (
+	RFQStatusOpen      = "open"
+	RFQStatusAwarded   = "awarded"
+	RFQStatusCancelled = "cancelled"
+)
+
+// RFQ is a request for quotation sent to several providers for the same
+// items and quantities. Each provider answers with at most one Quote; a new
+// quote from a provider replaces its previous one. Currency is the currency
+// quotes are compared in.
+type RFQ struct {
+	ID             primitive.ObjectID   `json:"id" bson:"_id,omitempty"`
+	Reference      string               `json:"reference" bson:"reference"`
+	Description    string               `json:"description,omitempty" bson:"description,omitempty"`
+	Currency       money.Currency       `json:"currency" bson:"currency"`
+	Lines          []RFQLine            `json:"lines" bson:"lines"`
+	ProviderIDs    []primitive.ObjectID `json:"provider_ids" bson:"provider_ids"`
+	DueDate        *time.Time           `json:"due_date,omitempty" bson:"due_date,omitempty"`
+	Status         string               `json:"status" bson:"status"`
+	Quotes         []Quote              `json:"quotes" bson:"quotes"`
+	AwardedQuoteID *primitive.ObjectID  `json:"awarded_quote_id,omitempty" bson:"awarded_quote_id,omitempty"`
+	PurchaseID     *primitive.ObjectID  `json:"purchase_id,omitempty" bson:"purchase_id,omitempty"`
+	CreatedBy      *primitive.ObjectID  `json:"created_by,omitempty" bson:"created_by,omitempty"`
+	Version        int64                `json:"version" bson:"version"`
+	CreatedAt      time.Time            `json:"created_at" bson:"created_at"`
+	UpdatedAt      time.Time            `json:"updated_at" bson:"updated_at"`
+}
+
+// RFQLine is an item and quantity providers are asked to quote. Name and
+// Code are copied from the item when the RFQ is created.
+type RFQLine struct {
+	ItemID   primitive.ObjectID `json:"item_id" bson:"item_id"`
+	Quantity int                `json:"quantity" bson:"quantity"`
+	Name     string             `json:"name,omitempty" bson:"name,omitempty"`
+	Code     string             `json:"code,omitempty" bson:"code,omitempty"`
+}
+
+// Quote is a provider's answer to an RFQ. It may leave out lines the
+// provider cannot supply.
+type Quote struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id"`
+	ProviderID  primitive.ObjectID `json:"provider_id" bson:"provider_id"`
+	Currency    money.Currency     `json:"currency" bson:"currency"`
+	Lines       []QuoteLine        `json:"lines" bson:"lines"`
+	ValidUntil  *time.Time         `json:"valid_until,omitempty" bson:"valid_until,omitempty"`
+	Notes       string             `json:"notes,omitempty" bson:"notes,omitempty"`
+	SubmittedAt time.Time          `json:"submitted_at" bson:"submitted_at"`
+}
+
+// QuoteLine is the price a provider quotes for one RFQ line, per unit and
+// in the quote currency.
+type QuoteLine struct {
+	ItemID       primitive.ObjectID `json:"item_id" bson:"item_id"`
+	UnitPrice    money.Amount       `json:"unit_price" bson:"unit_price"`
+	LeadTimeDays int                `json:"lead_time_days" bson:"lead_time_days"`
+}
+
+// RFQRequest is the body of POST /api/rfqs. The due date may be a plain
+// YYYY-MM-DD date and the currency defaults to BASE_CURRENCY.
+type RFQRequest struct {
+	Reference   string               `json:"reference"`
+	Description string               `json:"description,omitempty"`
+	Currency    money.Currency       `json:"currency,omitempty"`
+	Lines       []RFQLine            `json:"lines"`
+	ProviderIDs []primitive.ObjectID `json:"provider_ids"`
+	DueDate     string               `json:"due_date,omitempty"`
+}
+
+// QuoteRequest is the body of POST /api/rfqs/:id/quotes. The currency
+// defaults to the provider's.
+type QuoteRequest struct {
+	ProviderID primitive.ObjectID `json:"provider_id"`
+	Currency   money.Currency     `json:"currency,omitempty"`
+	Lines      []QuoteLine        `json:"lines"`
+	ValidUntil string             `json:"valid_until,omitempty"`
+	Notes      string             `json:"notes,omitempty"`
+}
+
+// ConvertQuoteRequest is the optional body of
+// POST /api/rfqs/:id/quotes/:quote_id/convert. The purchase order defaults
+// to the RFQ reference and the user to the caller.
+type ConvertQuoteRequest struct {
+	PurchaseOrder string              `json:"purchase_order,omitempty"`
+	UserID        *primitive.ObjectID `json:"user_id,omitempty"`
+}
+
+// RFQComparison sets the quotes of an RFQ side by side, with every amount
+// converted to the RFQ currency at today's rates.
+type RFQComparison struct {
+	RFQID    primitive.ObjectID  `json:"rfq_id"`
+	Currency money.Currency      `json:"currency"`
+	Quotes   []QuoteSummary      `json:"quotes"`
+	Lines    []RFQLineComparison `json:"lines"`
+}
+
+// QuoteSummary totals one quote. Complete quotes price every line; Best
+// marks the cheapest complete quote that has not expired.
+type QuoteSummary struct {
+	QuoteID      primitive.ObjectID `json:"quote_id"`
+	ProviderID   primitive.ObjectID `json:"provider_id"`
+	ProviderName string             `json:"provider_name"`
+	QuoteTotal   money.Amount       `json:"quote_total"`
+	Currency     money.Currency     `json:"currency"`
+	Total        money.Amount       `json:"total"`
+	LinesQuoted  int                `json:"lines_quoted"`
+	Complete     bool               `json:"complete"`
+	LeadTimeDays int                `json:"lead_time_days"`
+	ValidUntil   *time.Time         `json:"valid_until,omitempty"`
+	Expired      bool               `json:"expired"`
+	Best         bool               `json:"best"`
+}
+
+// RFQLineComparison lists the bids for one RFQ line, cheapest first.
+type RFQLineComparison struct {
+	ItemID   primitive.ObjectID `json:"item_id"`
+	Name     string             `json:"name,omitempty"`
+	Code     string             `json:"code,omitempty"`
+	Quantity int                `json:"quantity"`
+	Bids     []LineBid          `json:"bids"`
+}
+
+// LineBid is one provider's price for an RFQ line, in the RFQ currency.
+type LineBid struct {
+	QuoteID      primitive.ObjectID `json:"quote_id"`
+	ProviderID   primitive.ObjectID `json:"provider_id"`
+	ProviderName string             `json:"provider_name"`
+	UnitPrice    money.Amount       `json:"unit_price"`
+	Total        money.Amount       `json:"total"`
+	LeadTimeDays int                `json:"lead_time_days"`
+	Best         bool               `json:"best"`
+}
+
+func (r *RFQRequest) Validate() error {
+	if strings.TrimSpace(r.Reference) == "" {
+		return errors.New("reference is required")
+	}
+	if len(r.Lines) == 0 {
+		return errors.New("lines must not be empty")
+	}
+	items := map[primitive.ObjectID]bool{}
+	for _, line := range r.Lines {
+		if line.ItemID.IsZero() {
+			return errors.New("every line needs an item_id")
+		}
+		if line.Quantity <= 0 {
+			return errors.New("line quantity must be positive")
+		}
+		if items[line.ItemID] {
+			return errors.New("an item can only appear on one line")
+		}
+		items[line.ItemID] = true
+	}
+	if len(r.ProviderIDs) == 0 {
+		return errors.New("provider_ids must not be empty")
+	}
+	providers := map[primitive.ObjectID]bool{}
+	for _, providerID := range r.ProviderIDs {
+		if providers[providerID] {
+			return errors.New("provider_ids must not repeat a provider")
+		}
+		providers[providerID] = true
+	}
+	return nil
+}
+
+// Validate checks quote lines against the lines of rfq.
+func (q *QuoteRequest) Validate(rfq *RFQ) error {
+	if q.ProviderID.IsZero() {
+		return errors.New("provider_id is required")
+	}
+	invited := false
+	for _, providerID := range rfq.ProviderIDs {
+		if providerID == q.ProviderID {
+			invited = true
+		}
+	}
+	if !invited {
+		return errors.New("the provider was not asked to quote")
+	}
+	if len(q.Lines) == 0 {
+		return errors.New("lines must not be empty")
+	}
+	quoted := map[primitive.ObjectID]bool{}
+	for _, line := range q.Lines {
+		if rfq.Line(line.ItemID) == nil {
+			return errors.New("quote lines must be for items on the RFQ")
+		}
+		if quoted[line.ItemID] {
+			return errors.New("an item can only be quoted once")
+		}
+		quoted[line.ItemID] = true
+		if line.UnitPrice.IsNegative() {
+			return errors.New("unit_price must not be negative")
+		}
+		if line.LeadTimeDays < 0 {
+			return errors.New("lead_time_days must not be negative")
+		}
+	}
+	return nil
+}
+
+// Line returns the line for itemID, or nil.
+func (r *RFQ) Line(itemID primitive.ObjectID) *RFQLine {
+	for i := range r.Lines {
+		if r.Lines[i].ItemID == itemID {
+			return &r.Lines[i]
+		}
+	}
+	return nil
+}
+
+// Quote returns the quote with id quoteID, or nil.
+func (r *RFQ) Quote(quoteID primitive.ObjectID) *Quote {
+	for i := range r.Quotes {
+		if r.Quotes[i].ID == quoteID {
+			return &r.Quotes[i]
+		}
+	}
+	return nil
+}
+
+// Expired reports whether the quote is no longer valid at t.
+func (q *Quote) Expired(t time.Time) bool {
+	return q.ValidUntil != nil && !t.Before(*q.ValidUntil)
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/aldoramirezmartinez/fiber-api/money"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Provider tax types. Withholdings usually apply to individuals only.
+const (
+	ProviderTaxCompany    = "company"
+	ProviderTaxIndividual = "individual"
+)
+
+// TaxRule sets the taxes of the purchase lines it matches. Empty Category,
+// ProviderType and ProviderID match anything; the most specific matching
+// rule wins. Rates are fractions, so 16% IVA is 0.16. VATIncluded says the
+// prices of the lines the rule matches already include their IVA.
+type TaxRule struct {
+	ID                 primitive.ObjectID  `json:"id,omitempty" bson:"_id,omitempty"`
+	Name               string              `json:"name,omitempty" bson:"name,omitempty"`
+	Category           string              `json:"category,omitempty" bson:"category,omitempty"`
+	ProviderType       string              `json:"provider_type,omitempty" bson:"provider_type,omitempty"`
+	ProviderID         *primitive.ObjectID `json:"provider_id,omitempty" bson:"provider_id,omitempty"`
+	Exempt             bool                `json:"exempt" bson:"exempt"`
+	VATIncluded        bool                `json:"vat_included" bson:"vat_included"`
+	VATRate            money.Amount        `json:"vat_rate" bson:"vat_rate"`
+	ISRWithholdingRate money.Amount        `json:"isr_withholding_rate" bson:"isr_withholding_rate"`
+	VATWithholdingRate money.Amount        `json:"vat_withholding_rate" bson:"vat_withholding_rate"`
+	CreatedAt          time.Time           `json:"created_at,omitempty" bson:"created_at,omitempty"`
+	UpdatedAt          time.Time           `json:"updated_at,omitempty" bson:"updated_at,omitempty"`
+}
+
+// LineTax is the tax breakdown of one purchase line. Exempt lines carry no
+// IVA, which is not the same as a 0% rate on an invoice. Base is the amount
+// taxes are worked out on: the line amount, less its IVA when VATIncluded.
+type LineTax struct {
+	RuleID             *primitive.ObjectID `json:"rule_id,omitempty" bson:"rule_id,omitempty"`
+	Exempt             bool                `json:"exempt" bson:"exempt"`
+	VATIncluded        bool                `json:"vat_included" bson:"vat_included"`
+	Base               money.Amount        `json:"base" bson:"base"`
+	VATRate            money.Amount        `json:"vat_rate" bson:"vat_rate"`
+	VAT                money.Amount        `json:"vat" bson:"vat"`
+	ISRWithholdingRate money.Amount        `json:"isr_withholding_rate" bson:"isr_withholding_rate"`
+	ISRWithheld        money.Amount        `json:"isr_withheld" bson:"isr_withheld"`
+	VATWithholdingRate money.Amount        `json:"vat_withholding_rate" bson:"vat_withholding_rate"`
+	VATWithheld        money.Amount        `json:"vat_withheld" bson:"vat_withheld"`
+}
+
+func (r *TaxRule) Validate() error {
+	if strings.TrimSpace(r.Name) == "" {
+		return errors.New("name is required")
+	}
+	if err := ValidateProviderTaxType(r.ProviderType); err != nil {
+		return err
+	}
+	for name, rate := range map[string]money.Amount{
+		"vat_rate":             r.VATRate,
+		"isr_withholding_rate": r.ISRWithholdingRate,
+		"vat_withholding_rate": r.VATWithholdingRate,
+	} {
+		if rate.IsNegative() || rate.Cmp(money.FromInt(1)) > 0 {
+			return errors.New(name + " must be between 0 and 1")
+		}
+	}
+	if r.Exempt && (!r.VATRate.IsZero() || !r.VATWithholdingRate.IsZero()) {
+		return errors.New("exempt rules cannot have a vat_rate or vat_withholding_rate")
+	}
+	if r.Exempt && r.VATIncluded {
+		return errors.New("exempt rules cannot include IVA in prices")
+	}
+	if r.VATWithholdingRate.Cmp(r.VATRate) > 0 {
+		return errors.New("vat_withholding_rate cannot exceed vat_rate")
+	}
+	return nil
+}
+
+// ValidateProviderTaxType accepts the provider tax types and the empty
+// string.
+func ValidateProviderTaxType(taxType string) error {
+	switch taxType {
+	case "", ProviderTaxCompany, ProviderTaxIndividual:
+		return nil
+	}
+	return errors.New("tax type must be company or individual")
+}
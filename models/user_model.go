@@ -1,17 +1,36 @@
 package models
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 type User struct {
-	ID        primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
-	Name      string             `json:"name,omitempty" bson:"name,omitempty"`
-	Email     string             `json:"email,omitempty" bson:"email,omitempty"`
-	Password  string             `json:"-" bson:"password,omitempty"`
-	Address   string             `json:"address,omitempty" bson:"address,omitempty"`
-	Telephone string             `json:"telephone,omitempty" bson:"telephone,omitempty"`
-	Role      Role               `json:"role,omitempty" bson:"role,omitempty"`
+	ID        primitive.ObjectID  `json:"id,omitempty" bson:"_id,omitempty"`
+	Name      string              `json:"name,omitempty" bson:"name,omitempty"`
+	Email     string              `json:"email,omitempty" bson:"email,omitempty"`
+	Password  string              `json:"-" bson:"password,omitempty"`
+	Address   string              `json:"address,omitempty" bson:"address,omitempty"`
+	Telephone string              `json:"telephone,omitempty" bson:"telephone,omitempty"`
+	Role      Role                `json:"role,omitempty" bson:"role,omitempty"`
+	Version   int64               `json:"version,omitempty" bson:"version,omitempty"`
+	DeletedAt *time.Time          `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
+	DeletedBy *primitive.ObjectID `json:"deleted_by,omitempty" bson:"deleted_by,omitempty"`
 }
 
 type Role struct {
 	Name string `json:"name,omitempty" bson:"name,omitempty"`
 }
+
+func (u *User) Validate() error {
+	if strings.TrimSpace(u.Name) == "" {
+		return errors.New("name is required")
+	}
+	if !strings.Contains(u.Email, "@") {
+		return errors.New("email is invalid")
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package models
+
+import (
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/aldoramirezmartinez/fiber-api/money"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	EventPurchaseCreated  = "purchase.created"
+	EventPurchaseApproved = "purchase.approved"
+	EventPurchaseReceived = "purchase.received"
+	EventPurchaseAmended  = "purchase.amended"
+	EventItemPriceChanged = "item.price_changed"
+	EventPing             = "ping"
+)
+
+// WebhookEvents lists the event types subscriptions can ask for.
+var WebhookEvents = []string{EventPurchaseCreated, EventPurchaseApproved, EventPurchaseReceived, EventPurchaseAmended, EventItemPriceChanged}
+
+// Purchase statuses that trigger webhook events.
+const (
+	PurchaseStatusApproved = "approved"
+	PurchaseStatusReceived = "received"
+)
+
+type WebhookSubscription struct {
+	ID        primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	URL       string             `json:"url" bson:"url"`
+	Events    []string           `json:"events" bson:"events"`
+	Secret    string             `json:"secret,omitempty" bson:"secret"`
+	Active    bool               `json:"active" bson:"active"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+}
+
+func (s *WebhookSubscription) Validate() error {
+	target, err := url.Parse(s.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return errors.New("url must be an absolute http or https URL")
+	}
+	if s.Secret == "" {
+		return errors.New("secret is required")
+	}
+	if len(s.Events) == 0 {
+		return errors.New("events must not be empty")
+	}
+	for _, event := range s.Events {
+		if !isWebhookEvent(event) {
+			return errors.New("unknown event " + event)
+		}
+	}
+	return nil
+}
+
+func isWebhookEvent(event string) bool {
+	for _, known := range WebhookEvents {
+		if event == known {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookEvent is the JSON body posted to subscribers.
+type WebhookEvent struct {
+	ID        primitive.ObjectID `json:"id" bson:"id"`
+	Type      string             `json:"type" bson:"type"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+	Data      interface{}        `json:"data" bson:"data"`
+}
+
+// ItemPriceChange is the data of an item.price_changed event. EffectiveDate
+// is only set for backdated changes, which correct the price history without
+// changing the current price.
+type ItemPriceChange struct {
+	ItemID        primitive.ObjectID `json:"item_id"`
+	PreviousPrice money.Amount       `json:"previous_price"`
+	Price         money.Amount       `json:"price"`
+	EffectiveDate *time.Time         `json:"effective_date,omitempty"`
+}
+
+// WebhookDelivery is one event queued for one subscription. Payload holds the
+// exact bytes that are signed and posted on every attempt.
+type WebhookDelivery struct {
+	ID             primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	SubscriptionID primitive.ObjectID `json:"subscription_id" bson:"subscription_id"`
+	EventID        primitive.ObjectID `json:"event_id" bson:"event_id"`
+	Event          string             `json:"event" bson:"event"`
+	Payload        string             `json:"payload" bson:"payload"`
+	Status         string             `json:"status" bson:"status"`
+	Attempts       int                `json:"attempts" bson:"attempts"`
+	NextAttemptAt  time.Time          `json:"next_attempt_at" bson:"next_attempt_at"`
+	LockedUntil    *time.Time         `json:"-" bson:"locked_until,omitempty"`
+	CreatedAt      time.Time          `json:"created_at" bson:"created_at"`
+	Log            []DeliveryAttempt  `json:"log" bson:"log"`
+}
+
+type DeliveryAttempt struct {
+	At         time.Time `json:"at" bson:"at"`
+	StatusCode int       `json:"status_code,omitempty" bson:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty" bson:"error,omitempty"`
+	DurationMS int64     `json:"duration_ms" bson:"duration_ms"`
+}
+
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
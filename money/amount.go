@@ -0,0 +1,262 @@
+// Package money provides exact decimal amounts for prices, subtotals and
+// totals. Amounts are stored in MongoDB as Decimal128 and encoded in JSON as
+// strings, so neither side ever sees a binary float.
+package money
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+// RoundingMode says which way Round breaks ties and drops digits.
+type RoundingMode int
+
+const (
+	// HalfUp rounds to the nearest value and ties away from zero, the usual
+	// commercial rounding: 2.345 → 2.35, -2.345 → -2.35.
+	HalfUp RoundingMode = iota
+	// HalfEven rounds to the nearest value and ties to the even digit:
+	// 2.345 → 2.34, 2.355 → 2.36.
+	HalfEven
+	// Up rounds away from zero.
+	Up
+	// Down rounds toward zero, dropping the extra digits.
+	Down
+	// Ceiling rounds toward positive infinity.
+	Ceiling
+	// Floor rounds toward negative infinity.
+	Floor
+)
+
+// Amount is an exact decimal amount of money. The zero value is 0.
+// Arithmetic never rounds except where a method says so, and keeps the
+// number of decimal places of its operands: "12.50" stays "12.50".
+type Amount struct {
+	value decimal.Decimal
+}
+
+// Zero is the amount 0.
+var Zero = Amount{}
+
+// New returns value × 10^exp, so New(1250, -2) is 12.50.
+func New(value int64, exp int32) Amount {
+	return Amount{value: decimal.New(value, exp)}
+}
+
+func FromInt(value int64) Amount {
+	return Amount{value: decimal.NewFromInt(value)}
+}
+
+// FromFloat converts a float to the shortest decimal that reads back as the
+// same float, so 0.1 becomes "0.1". Use it only for values that were stored
+// as floats.
+func FromFloat(value float64) Amount {
+	return Amount{value: decimal.NewFromFloat(value)}
+}
+
+// Parse reads a decimal such as "1250.50" or "-3".
+func Parse(s string) (Amount, error) {
+	value, err := decimal.NewFromString(s)
+	if err != nil {
+		return Zero, fmt.Errorf("invalid amount %q", s)
+	}
+	return Amount{value: value}, nil
+}
+
+func MustParse(s string) Amount {
+	amount, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return amount
+}
+
+// Sum adds amounts, returning Zero for none.
+func Sum(amounts ...Amount) Amount {
+	total := Zero
+	for _, amount := range amounts {
+		total = total.Add(amount)
+	}
+	return total
+}
+
+func (a Amount) Add(b Amount) Amount {
+	return Amount{value: a.value.Add(b.value)}
+}
+
+func (a Amount) Sub(b Amount) Amount {
+	return Amount{value: a.value.Sub(b.value)}
+}
+
+// Mul multiplies exactly; the result has the decimal places of both
+// operands added up.
+func (a Amount) Mul(b Amount) Amount {
+	return Amount{value: a.value.Mul(b.value)}
+}
+
+func (a Amount) MulInt(n int64) Amount {
+	return Amount{value: a.value.Mul(decimal.NewFromInt(n))}
+}
+
+// Div returns a / b rounded to places decimal places with mode. b must not
+// be zero.
+func (a Amount) Div(b Amount, places int32, mode RoundingMode) Amount {
+	// Keep enough extra digits that rounding the quotient once more gives
+	// the same result as rounding the exact one.
+	quotient := a.value.DivRound(b.value, places+16)
+	return Amount{value: quotient}.Round(places, mode)
+}
+
+func (a Amount) Neg() Amount {
+	return Amount{value: a.value.Neg()}
+}
+
+func (a Amount) Abs() Amount {
+	return Amount{value: a.value.Abs()}
+}
+
+// Round returns a rounded to places decimal places with mode, always with
+// exactly that many places: Round(2, HalfUp) of 12.5 is "12.50".
+func (a Amount) Round(places int32, mode RoundingMode) Amount {
+	var rounded decimal.Decimal
+	switch mode {
+	case HalfEven:
+		rounded = a.value.RoundBank(places)
+	case Up:
+		rounded = a.value.RoundUp(places)
+	case Down:
+		rounded = a.value.RoundDown(places)
+	case Ceiling:
+		rounded = a.value.RoundCeil(places)
+	case Floor:
+		rounded = a.value.RoundFloor(places)
+	default:
+		rounded = a.value.Round(places)
+	}
+	// Round never changes an already rounded value and pads it to places.
+	return Amount{value: rounded.Round(places)}
+}
+
+// Cmp returns -1, 0 or 1 when a is less than, equal to or greater than b.
+func (a Amount) Cmp(b Amount) int {
+	return a.value.Cmp(b.value)
+}
+
+// Equal compares values, so "12.5" equals "12.50".
+func (a Amount) Equal(b Amount) bool {
+	return a.value.Equal(b.value)
+}
+
+func (a Amount) Sign() int {
+	return a.value.Sign()
+}
+
+func (a Amount) IsZero() bool {
+	return a.value.IsZero()
+}
+
+func (a Amount) IsNegative() bool {
+	return a.value.IsNegative()
+}
+
+// Places returns the number of decimal places a is written with.
+func (a Amount) Places() int32 {
+	if exp := a.value.Exponent(); exp < 0 {
+		return -exp
+	}
+	return 0
+}
+
+// String writes a in plain notation with its decimal places, such as
+// "1250.50".
+func (a Amount) String() string {
+	return a.value.StringFixed(a.Places())
+}
+
+// Float64 returns the nearest float, for display and statistics only.
+func (a Amount) Float64() float64 {
+	value, _ := a.value.Float64()
+	return value
+}
+
+// MarshalJSON writes a as a JSON string.
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.String())
+}
+
+// UnmarshalJSON reads a JSON string or number. Numbers are read from their
+// text, so 19.99 is exactly 19.99.
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		*a = Zero
+		return nil
+	}
+
+	text := string(data)
+	if len(data) > 0 && data[0] == '"' {
+		if err := json.Unmarshal(data, &text); err != nil {
+			return err
+		}
+	}
+
+	amount, err := Parse(text)
+	if err != nil {
+		return err
+	}
+	*a = amount
+	return nil
+}
+
+// MarshalBSONValue stores a as a Decimal128.
+func (a Amount) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	value, ok := primitive.ParseDecimal128FromBigInt(a.value.Coefficient(), int(a.value.Exponent()))
+	if !ok {
+		return 0, nil, fmt.Errorf("amount %s does not fit in a Decimal128", a)
+	}
+	return bson.MarshalValue(value)
+}
+
+// UnmarshalBSONValue reads a Decimal128. Doubles, integers and strings are
+// accepted too so documents written before amounts were decimals still load.
+func (a *Amount) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	value := bsoncore.Value{Type: t, Data: data}
+
+	switch t {
+	case bsontype.Decimal128:
+		d128 := value.Decimal128()
+		if d128.IsNaN() || d128.IsInf() != 0 {
+			return errors.New("amount is not a finite number")
+		}
+		coefficient, exp, err := d128.BigInt()
+		if err != nil {
+			return err
+		}
+		*a = Amount{value: decimal.NewFromBigInt(coefficient, int32(exp))}
+	case bsontype.Double:
+		*a = FromFloat(value.Double())
+	case bsontype.Int32:
+		*a = FromInt(int64(value.Int32()))
+	case bsontype.Int64:
+		*a = FromInt(value.Int64())
+	case bsontype.String:
+		amount, err := Parse(value.StringValue())
+		if err != nil {
+			return err
+		}
+		*a = amount
+	case bsontype.Null, bsontype.Undefined:
+		*a = Zero
+	default:
+		return fmt.Errorf("cannot decode %s into an amount", t)
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package money
+
+import (
+	"encoding/json"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		value  string
+		places int32
+		mode   RoundingMode
+		want   string
+	}{
+		{"2.345", 2, HalfUp, "2.35"},
+		{"2.345", 2, HalfEven, "2.34"},
+		{"2.355", 2, HalfUp, "2.36"},
+		{"2.355", 2, HalfEven, "2.36"},
+		{"2.3451", 2, HalfEven, "2.35"},
+		{"-2.345", 2, HalfUp, "-2.35"},
+		{"-2.345", 2, HalfEven, "-2.34"},
+		{"2.341", 2, Up, "2.35"},
+		{"-2.341", 2, Up, "-2.35"},
+		{"2.349", 2, Down, "2.34"},
+		{"-2.349", 2, Down, "-2.34"},
+		{"2.341", 2, Ceiling, "2.35"},
+		{"-2.349", 2, Ceiling, "-2.34"},
+		{"2.349", 2, Floor, "2.34"},
+		{"-2.341", 2, Floor, "-2.35"},
+		{"12.5", 2, HalfUp, "12.50"},
+		{"12", 2, HalfEven, "12.00"},
+		{"0.5", 0, HalfUp, "1"},
+		{"0.5", 0, HalfEven, "0"},
+		{"1.5", 0, HalfEven, "2"},
+		{"-0.5", 0, HalfUp, "-1"},
+		{"0", 2, HalfUp, "0.00"},
+	}
+
+	for _, tt := range tests {
+		got := MustParse(tt.value).Round(tt.places, tt.mode).String()
+		if got != tt.want {
+			t.Errorf("Round(%s, %d, %d) = %s, want %s", tt.value, tt.places, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestDiv(t *testing.T) {
+	tests := []struct {
+		a, b   string
+		places int32
+		mode   RoundingMode
+		want   string
+	}{
+		{"10", "3", 2, HalfUp, "3.33"},
+		{"2", "3", 2, HalfUp, "0.67"},
+		{"2", "3", 2, Down, "0.66"},
+		{"1", "8", 2, HalfUp, "0.13"},
+		{"1", "8", 2, HalfEven, "0.12"},
+		{"3", "8", 2, HalfEven, "0.38"},
+		{"-1", "8", 2, HalfUp, "-0.13"},
+		{"-10", "4", 0, HalfEven, "-2"},
+		{"100", "7", 4, HalfUp, "14.2857"},
+		{"6", "2", 2, HalfUp, "3.00"},
+		// The quotient is exact at places+16, so it is rounded only once.
+		{"2.344999999999999999", "1", 2, HalfUp, "2.34"},
+		{"0.01", "3", 2, Up, "0.01"},
+	}
+
+	for _, tt := range tests {
+		got := MustParse(tt.a).Div(MustParse(tt.b), tt.places, tt.mode).String()
+		if got != tt.want {
+			t.Errorf("%s / %s to %d places with mode %d = %s, want %s", tt.a, tt.b, tt.places, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestArithmeticKeepsPlaces(t *testing.T) {
+	tests := []struct {
+		name string
+		got  Amount
+		want string
+	}{
+		{"add", MustParse("12.50").Add(MustParse("0.5")), "13.00"},
+		{"sub below zero", MustParse("1.25").Sub(MustParse("3")), "-1.75"},
+		{"mul", MustParse("19.99").Mul(MustParse("3")), "59.97"},
+		{"mul negative", MustParse("-2.5").MulInt(3), "-7.5"},
+		{"neg", MustParse("4.10").Neg(), "-4.10"},
+		{"abs", MustParse("-4.10").Abs(), "4.10"},
+		{"sum of none", Sum(), "0"},
+		{"sum", Sum(MustParse("0.1"), MustParse("0.2")), "0.3"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.got.String(); got != tt.want {
+			t.Errorf("%s = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	negative := MustParse("-0.01")
+	if !negative.IsNegative() || negative.Sign() != -1 || negative.IsZero() {
+		t.Errorf("%s is not negative", negative)
+	}
+	if !MustParse("0.00").IsZero() || !MustParse("-0").IsZero() {
+		t.Error("zero amounts are not zero")
+	}
+}
+
+func TestJSON(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		out  string
+	}{
+		{`"1250.50"`, "1250.50", `"1250.50"`},
+		{`19.99`, "19.99", `"19.99"`},
+		{`"-3.10"`, "-3.10", `"-3.10"`},
+		{`-0.5`, "-0.5", `"-0.5"`},
+		{`0`, "0", `"0"`},
+		{`"0"`, "0", `"0"`},
+		{`null`, "0", `"0"`},
+		{`0.1`, "0.1", `"0.1"`},
+	}
+
+	for _, tt := range tests {
+		var amount Amount
+		if err := json.Unmarshal([]byte(tt.in), &amount); err != nil {
+			t.Errorf("decoding %s: %v", tt.in, err)
+			continue
+		}
+		if amount.String() != tt.want {
+			t.Errorf("decoding %s = %s, want %s", tt.in, amount, tt.want)
+		}
+		out, err := json.Marshal(amount)
+		if err != nil {
+			t.Errorf("encoding %s: %v", amount, err)
+			continue
+		}
+		if string(out) != tt.out {
+			t.Errorf("encoding %s = %s, want %s", amount, out, tt.out)
+		}
+	}
+
+	for _, in := range []string{`"abc"`, `"1,5"`, `true`, `""`} {
+		var amount Amount
+		if err := json.Unmarshal([]byte(in), &amount); err == nil {
+			t.Errorf("decoding %s = %s, want an error", in, amount)
+		}
+	}
+}
+
+func TestBSON(t *testing.T) {
+	type document struct {
+		Amount Amount `bson:"amount"`
+	}
+
+	for _, value := range []string{"1250.50", "0", "0.00", "-3.10", "-0.001", "99999999999999.99"} {
+		data, err := bson.Marshal(document{Amount: MustParse(value)})
+		if err != nil {
+			t.Errorf("encoding %s: %v", value, err)
+			continue
+		}
+		if typ := bson.Raw(data).Lookup("amount").Type; typ != bsontype.Decimal128 {
+			t.Errorf("%s is stored as %s, want a Decimal128", value, typ)
+		}
+
+		var decoded document
+		if err := bson.Unmarshal(data, &decoded); err != nil {
+			t.Errorf("decoding %s: %v", value, err)
+			continue
+		}
+		if decoded.Amount.String() != value {
+			t.Errorf("%s reads back as %s", value, decoded.Amount)
+		}
+	}
+}
+
+func TestBSONLegacyValues(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{"double", 19.99, "19.99"},
+		{"negative double", -0.5, "-0.5"},
+		{"int32", int32(7), "7"},
+		{"int64", int64(-12), "-12"},
+		{"zero", int32(0), "0"},
+		{"string", "12.50", "12.50"},
+		{"null", nil, "0"},
+	}
+
+	for _, tt := range tests {
+		data, err := bson.Marshal(bson.M{"amount": tt.value})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded struct {
+			Amount Amount `bson:"amount"`
+		}
+		if err := bson.Unmarshal(data, &decoded); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if decoded.Amount.String() != tt.want {
+			t.Errorf("%s reads back as %s, want %s", tt.name, decoded.Amount, tt.want)
+		}
+	}
+
+	data, err := bson.Marshal(bson.M{"amount": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Amount Amount `bson:"amount"`
+	}
+	if err := bson.Unmarshal(data, &decoded); err == nil {
+		t.Errorf("a boolean reads back as %s, want an error", decoded.Amount)
+	}
+}
@@ -0,0 +1,118 @@
+package money
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Currency is an ISO 4217 currency code such as "MXN".
+type Currency string
+
+// minorUnits lists the decimal places of the currencies we deal with.
+// Codes that are not listed use two.
+var minorUnits = map[Currency]int32{
+	"MXN": 2,
+	"USD": 2,
+	"CAD": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"CNY": 2,
+	"COP": 2,
+	"BRL": 2,
+	"JPY": 0,
+	"KRW": 0,
+	"CLP": 0,
+}
+
+// ParseCurrency upper-cases code and checks that it looks like an ISO 4217
+// code.
+func ParseCurrency(code string) (Currency, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) != 3 {
+		return "", fmt.Errorf("invalid currency code %q", code)
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return "", fmt.Errorf("invalid currency code %q", code)
+		}
+	}
+	return Currency(code), nil
+}
+
+// Digits returns the number of decimal places amounts in c are rounded to.
+func (c Currency) Digits() int32 {
+	if digits, ok := minorUnits[c]; ok {
+		return digits
+	}
+	return 2
+}
+
+// Round rounds amount to the minor unit of c with mode.
+func (c Currency) Round(amount Amount, mode RoundingMode) Amount {
+	return amount.Round(c.Digits(), mode)
+}
+
+func (c Currency) String() string {
+	return string(c)
+}
+
+// UnmarshalJSON reads a currency code, rejecting malformed ones. An empty
+// code is kept empty so the caller can apply its default.
+func (c *Currency) UnmarshalJSON(data []byte) error {
+	var code string
+	if err := json.Unmarshal(data, &code); err != nil {
+		return err
+	}
+	if code == "" {
+		*c = ""
+		return nil
+	}
+
+	currency, err := ParseCurrency(code)
+	if err != nil {
+		return err
+	}
+	*c = currency
+	return nil
+}
+
+// Or returns c, or fallback when c is empty.
+func (c Currency) Or(fallback Currency) Currency {
+	if c == "" {
+		return fallback
+	}
+	return c
+}
+
+// Allocate splits amount across shares proportionally to weights, each
+// share rounded to the minor unit of c. The rounding difference goes to the
+// share with the largest weight, so the shares always add up to amount.
+// When every weight is zero the amount is split evenly.
+func (c Currency) Allocate(amount Amount, weights []Amount) []Amount {
+	shares := make([]Amount, len(weights))
+	if len(weights) == 0 {
+		return shares
+	}
+
+	total := Sum(weights...)
+	if total.IsZero() {
+		weights = make([]Amount, len(weights))
+		for i := range weights {
+			weights[i] = FromInt(1)
+		}
+		total = FromInt(int64(len(weights)))
+	}
+
+	allocated := Zero
+	largest := 0
+	for i, weight := range weights {
+		shares[i] = c.Round(amount.Mul(weight).Div(total, c.Digits()+4, HalfEven), HalfUp)
+		allocated = allocated.Add(shares[i])
+		if weight.Cmp(weights[largest]) > 0 {
+			largest = i
+		}
+	}
+	shares[largest] = shares[largest].Add(amount.Sub(allocated))
+	return shares
+}
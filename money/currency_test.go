@@ -0,0 +1,46 @@
+package money
+
+import "testing"
+
+func TestAllocate(t *testing.T) {
+	tests := []struct {
+		name     string
+		currency Currency
+		amount   string
+		weights  []string
+		want     []string
+	}{
+		{"exact", "MXN", "1.00", []string{"1", "1", "2"}, []string{"0.25", "0.25", "0.50"}},
+		{"remainder to the only largest weight", "MXN", "1.00", []string{"1", "5", "2"}, []string{"0.13", "0.62", "0.25"}},
+		{"remainder to the first of equal weights", "MXN", "100", []string{"1", "1", "1"}, []string{"33.34", "33.33", "33.33"}},
+		{"shares rounded up give back the excess", "MXN", "0.05", []string{"1", "1", "1"}, []string{"0.01", "0.02", "0.02"}},
+		{"by value", "MXN", "10", []string{"300.00", "100.00"}, []string{"7.50", "2.50"}},
+		{"negative", "MXN", "-10", []string{"1", "1", "1"}, []string{"-3.34", "-3.33", "-3.33"}},
+		{"no decimals", "JPY", "100", []string{"1", "1", "1"}, []string{"34", "33", "33"}},
+		{"zero weights split evenly", "MXN", "10", []string{"0", "0"}, []string{"5.00", "5.00"}},
+		{"zero amount", "MXN", "0", []string{"1", "2"}, []string{"0.00", "0.00"}},
+		{"no weights", "MXN", "10", nil, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			weights := make([]Amount, len(tt.weights))
+			for i, weight := range tt.weights {
+				weights[i] = MustParse(weight)
+			}
+
+			shares := tt.currency.Allocate(MustParse(tt.amount), weights)
+			if len(shares) != len(tt.want) {
+				t.Fatalf("got %d shares, want %d", len(shares), len(tt.want))
+			}
+			for i, share := range shares {
+				if share.String() != tt.want[i] {
+					t.Errorf("share %d = %s, want %s", i, share, tt.want[i])
+				}
+			}
+			if len(shares) > 0 && !Sum(shares...).Equal(MustParse(tt.amount)) {
+				t.Errorf("shares add up to %s, want %s", Sum(shares...), tt.amount)
+			}
+		})
+	}
+}
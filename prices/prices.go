@@ -0,0 +1,251 @@
+// Package prices keeps the price history of items, schedules future price
+// changes and answers what an item cost on a given date.
+package prices
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aldoramirezmartinez/fiber-api/audit"
+	"github.com/aldoramirezmartinez/fiber-api/events"
+	"github.com/aldoramirezmartinez/fiber-api/models"
+	"github.com/aldoramirezmartinez/fiber-api/money"
+	"github.com/aldoramirezmartinez/fiber-api/queue"
+	"github.com/aldoramirezmartinez/fiber-api/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const CollectionName = "item_prices"
+
+// ApplyJobType is the job that applies a scheduled price.
+const ApplyJobType = "item.apply_price"
+
+// ErrNotScheduled is returned by Cancel for prices that are not waiting to
+// be applied.
+var ErrNotScheduled = errors.New("only scheduled prices can be cancelled")
+
+// ApplyJob is the payload of ApplyJobType.
+type ApplyJob struct {
+	PriceID primitive.ObjectID `json:"price_id"`
+}
+
+// Record stores a price that is already in effect. A zero EffectiveDate
+// means now.
+func Record(ctx context.Context, db *mongo.Database, price models.ItemPrice) (models.ItemPrice, error) {
+	now := time.Now()
+	if price.EffectiveDate.IsZero() {
+		price.EffectiveDate = now
+	}
+	price.ID = primitive.NewObjectID()
+	price.Status = models.ItemPriceApplied
+	price.CreatedAt = now
+	price.AppliedAt = &now
+
+	_, err := db.Collection(CollectionName).InsertOne(ctx, price)
+	return price, err
+}
+
+// Backdate records change, a price whose EffectiveDate is earlier than the
+// latest applied price of item, so it only corrects the history: its
+// previous price is what item cost at that date, and the next later price,
+// returned as corrected when there is one, now follows it instead. Inside
+// UnitOfWork.Do pass the transaction context.
+func Backdate(ctx context.Context, db *mongo.Database, item models.Item, change models.ItemPrice) (saved models.ItemPrice, corrected *models.ItemPrice, err error) {
+	previous, err := At(ctx, db, item, change.EffectiveDate)
+	if err != nil {
+		return saved, nil, err
+	}
+	change.PreviousPrice = &previous
+	saved, err = Record(ctx, db, change)
+	if err != nil {
+		return saved, nil, err
+	}
+
+	var next models.ItemPrice
+	err = db.Collection(CollectionName).FindOneAndUpdate(ctx,
+		bson.M{"item_id": item.ID, "status": models.ItemPriceApplied, "effective_date": bson.M{"$gt": change.EffectiveDate}},
+		bson.M{"$set": bson.M{"previous_price": change.Price}},
+		options.FindOneAndUpdate().SetSort(bson.D{{Key: "effective_date", Value: 1}, {Key: "_id", Value: 1}}),
+	).Decode(&next)
+	if err == mongo.ErrNoDocuments {
+		return saved, nil, nil
+	}
+	if err != nil {
+		return saved, nil, err
+	}
+	return saved, &next, nil
+}
+
+// Schedule stores a price to be applied at its EffectiveDate and queues the
+// job that applies it. Inside UnitOfWork.Do pass the transaction context.
+func Schedule(ctx context.Context, db *mongo.Database, price models.ItemPrice) (models.ItemPrice, error) {
+	price.ID = primitive.NewObjectID()
+	price.Status = models.ItemPriceScheduled
+	price.PreviousPrice = nil
+	price.CreatedAt = time.Now()
+
+	if _, err := db.Collection(CollectionName).InsertOne(ctx, price); err != nil {
+		return price, err
+	}
+	_, err := queue.Enqueue(ctx, db, ApplyJobType, ApplyJob{PriceID: price.ID}, price.EffectiveDate)
+	return price, err
+}
+
+// Cancel stops a scheduled price from being applied. It returns
+// mongo.ErrNoDocuments for unknown prices and ErrNotScheduled for prices
+// that were applied or cancelled already.
+func Cancel(ctx context.Context, db *mongo.Database, itemID primitive.ObjectID, priceID primitive.ObjectID) (models.ItemPrice, error) {
+	collection := db.Collection(CollectionName)
+
+	var price models.ItemPrice
+	err := collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": priceID, "item_id": itemID, "status": models.ItemPriceScheduled},
+		bson.M{"$set": bson.M{"status": models.ItemPriceCancelled}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&price)
+	if err != mongo.ErrNoDocuments {
+		return price, err
+	}
+
+	if err := collection.FindOne(ctx, bson.M{"_id": priceID, "item_id": itemID}).Decode(&price); err != nil {
+		return price, err
+	}
+	return price, ErrNotScheduled
+}
+
+// Apply sets the price of an item to a scheduled price. It runs as the
+// ApplyJobType job and does nothing for prices that were applied or
+// cancelled already, so it is safe to run twice. Prices of purged items are
+// cancelled.
+func Apply(ctx context.Context, db *mongo.Database, priceID primitive.ObjectID) error {
+	return utils.NewUnitOfWork(db).Do(ctx, func(ctx context.Context) error {
+		collection := db.Collection(CollectionName)
+		items := db.Collection("items")
+
+		var price models.ItemPrice
+		err := collection.FindOne(ctx, bson.M{"_id": priceID, "status": models.ItemPriceScheduled}).Decode(&price)
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var item models.Item
+		err = items.FindOne(ctx, bson.M{"_id": price.ItemID}).Decode(&item)
+		if err == mongo.ErrNoDocuments {
+			_, err = collection.UpdateOne(ctx, bson.M{"_id": priceID}, bson.M{"$set": bson.M{"status": models.ItemPriceCancelled}})
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		result, err := items.UpdateOne(ctx, utils.VersionFilter(item.ID, item.Version), bson.M{
+			"$set": bson.M{"price": price.Price, "version": item.Version + 1},
+		})
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return utils.ErrVersionConflict
+		}
+
+		now := time.Now()
+		_, err = collection.UpdateOne(ctx, bson.M{"_id": priceID}, bson.M{"$set": bson.M{
+			"status":         models.ItemPriceApplied,
+			"previous_price": item.Price,
+			"applied_at":     now,
+		}})
+		if err != nil {
+			return err
+		}
+
+		if err := audit.Record(ctx, nil, db, models.AuditUpdate, "items", item.ID, item); err != nil {
+			return err
+		}
+		if price.Price.Equal(item.Price) {
+			return nil
+		}
+		return events.Emit(ctx, db, models.EventTypeItemPriceChanged, "item", item.ID, models.ItemPriceChange{
+			ItemID:        item.ID,
+			PreviousPrice: item.Price,
+			Price:         price.Price,
+		})
+	})
+}
+
+// History lists the prices of an item, latest effective date first.
+// status filters by status when not empty.
+func History(ctx context.Context, db *mongo.Database, itemID primitive.ObjectID, status string) ([]models.ItemPrice, error) {
+	filter := bson.M{"item_id": itemID}
+	if status != "" {
+		filter["status"] = status
+	}
+	findOptions := options.Find().SetSort(bson.D{{Key: "effective_date", Value: -1}, {Key: "_id", Value: -1}})
+
+	cursor, err := db.Collection(CollectionName).Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	history := []models.ItemPrice{}
+	err = cursor.All(ctx, &history)
+	return history, err
+}
+
+// LatestApplied returns the applied price of an item with the latest
+// effective date, or mongo.ErrNoDocuments when it has no history.
+func LatestApplied(ctx context.Context, db *mongo.Database, itemID primitive.ObjectID) (models.ItemPrice, error) {
+	var price models.ItemPrice
+	err := db.Collection(CollectionName).FindOne(ctx,
+		bson.M{"item_id": itemID, "status": models.ItemPriceApplied},
+		options.FindOne().SetSort(bson.D{{Key: "effective_date", Value: -1}, {Key: "_id", Value: -1}}),
+	).Decode(&price)
+	return price, err
+}
+
+// At returns what item cost at at: the applied price with the latest
+// effective date not after at. Before its first recorded change an item
+// cost the previous price of that change; items without history, or with
+// nothing known that early, cost their current price.
+func At(ctx context.Context, db *mongo.Database, item models.Item, at time.Time) (money.Amount, error) {
+	collection := db.Collection(CollectionName)
+
+	var price models.ItemPrice
+	err := collection.FindOne(ctx,
+		bson.M{"item_id": item.ID, "status": models.ItemPriceApplied, "effective_date": bson.M{"$lte": at}},
+		options.FindOne().SetSort(bson.D{{Key: "effective_date", Value: -1}, {Key: "_id", Value: -1}}),
+	).Decode(&price)
+	if err == nil {
+		return price.Price, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return money.Zero, err
+	}
+
+	err = collection.FindOne(ctx,
+		bson.M{"item_id": item.ID, "status": models.ItemPriceApplied, "effective_date": bson.M{"$gt": at}},
+		options.FindOne().SetSort(bson.D{{Key: "effective_date", Value: 1}, {Key: "_id", Value: 1}}),
+	).Decode(&price)
+	if err == nil && price.PreviousPrice != nil {
+		return *price.PreviousPrice, nil
+	}
+	if err != nil && err != mongo.ErrNoDocuments {
+		return money.Zero, err
+	}
+	return item.Price, nil
+}
+
+// EnsureIndexes creates the index price lookups go through.
+func EnsureIndexes(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection(CollectionName).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "item_id", Value: 1}, {Key: "status", Value: 1}, {Key: "effective_date", Value: -1}},
+	})
+	return err
+}
@@ -0,0 +1,182 @@
+// Package pricing works out the amounts of a purchase from the unit prices
+// and quantities of its lines: discounts, charges, landed costs, taxes and
+// totals. It reads nothing from the database; finding the unit prices and
+// the tax rules is up to the caller.
+package pricing
+
+import (
+	"errors"
+
+	"github.com/aldoramirezmartinez/fiber-api/models"
+	"github.com/aldoramirezmartinez/fiber-api/money"
+)
+
+// ErrDiscountTooLarge is returned when an amount discount is more than what
+// it applies to.
+var ErrDiscountTooLarge = errors.New("discount exceeds the amount it applies to")
+
+// TaxFunc returns the taxes of line on taxable, its subtotal less its share
+// of the purchase discount.
+type TaxFunc func(line models.PurchaseDetailv2, taxable money.Amount) *models.LineTax
+
+// Price sets every amount of purchase in currency from the UnitPrice,
+// Quantity and Discount of its lines, its Discount and its Charges, taxing
+// each line with taxes.
+func Price(purchase *models.Purchasev2, currency money.Currency, taxes TaxFunc) error {
+	subtotal, err := Lines(purchase.ItemList, currency)
+	if err != nil {
+		return err
+	}
+	if err := OrderDiscount(purchase, subtotal, currency); err != nil {
+		return err
+	}
+	Charges(purchase, currency)
+	includedVAT := Taxes(purchase, taxes)
+	LandedCosts(purchase.ItemList, currency)
+
+	// IVA already included in line prices is taken out of the total, which
+	// is always before taxes.
+	purchase.Total = subtotal.Sub(purchase.DiscountAmount).Add(purchase.ChargesTotal).Sub(includedVAT)
+	purchase.GrandTotal = purchase.Total.Add(purchase.Tax).Sub(purchase.Withholding)
+	return nil
+}
+
+// Lines sets the gross amount, discount and subtotal of each line and
+// returns the sum of the subtotals.
+func Lines(lines []models.PurchaseDetailv2, currency money.Currency) (money.Amount, error) {
+	subtotal := money.Zero
+	for i := range lines {
+		line := &lines[i]
+		line.GrossAmount = Gross(line.UnitPrice, line.Quantity, currency)
+
+		discount, err := Discount(line.Discount, line.GrossAmount, currency)
+		if err != nil {
+			return money.Zero, err
+		}
+		line.DiscountAmount = discount
+		line.Subtotal = line.GrossAmount.Sub(discount)
+		subtotal = subtotal.Add(line.Subtotal)
+	}
+	return subtotal, nil
+}
+
+// OrderDiscount sets the discount of purchase on subtotal, the sum of its
+// line subtotals, and spreads it over the lines by subtotal.
+func OrderDiscount(purchase *models.Purchasev2, subtotal money.Amount, currency money.Currency) error {
+	discount, err := Discount(purchase.Discount, subtotal, currency)
+	if err != nil {
+		return err
+	}
+	purchase.DiscountAmount = discount
+
+	shares := currency.Allocate(discount, subtotals(purchase.ItemList))
+	for i := range purchase.ItemList {
+		purchase.ItemList[i].OrderDiscount = shares[i]
+	}
+	return nil
+}
+
+// Charges rounds the charges of purchase, adds them up and spreads each
+// over the lines by subtotal or by quantity, as its Allocation says, into
+// their LandedCost.
+func Charges(purchase *models.Purchasev2, currency money.Currency) {
+	values := subtotals(purchase.ItemList)
+	quantities := make([]money.Amount, len(purchase.ItemList))
+	for i, line := range purchase.ItemList {
+		quantities[i] = money.FromInt(int64(line.Quantity))
+	}
+
+	landedCosts := make([]money.Amount, len(purchase.ItemList))
+	purchase.ChargesTotal = money.Zero
+	for i := range purchase.Charges {
+		charge := &purchase.Charges[i]
+		charge.Amount = currency.Round(charge.Amount, money.HalfUp)
+		purchase.ChargesTotal = purchase.ChargesTotal.Add(charge.Amount)
+
+		weights := values
+		if charge.Allocation == models.AllocateByQuantity {
+			weights = quantities
+		}
+		for j, share := range currency.Allocate(charge.Amount, weights) {
+			landedCosts[j] = landedCosts[j].Add(share)
+		}
+	}
+
+	for i := range purchase.ItemList {
+		purchase.ItemList[i].LandedCost = landedCosts[i]
+	}
+}
+
+// Taxes sets the taxes of each line with taxes and adds them up into the
+// Tax and Withholding of purchase. It returns the IVA that was already
+// included in line prices.
+func Taxes(purchase *models.Purchasev2, taxes TaxFunc) money.Amount {
+	purchase.Tax = money.Zero
+	purchase.Withholding = money.Zero
+	includedVAT := money.Zero
+	for i := range purchase.ItemList {
+		line := &purchase.ItemList[i]
+		line.Tax = taxes(*line, line.Subtotal.Sub(line.OrderDiscount))
+
+		purchase.Tax = purchase.Tax.Add(line.Tax.VAT)
+		purchase.Withholding = purchase.Withholding.Add(line.Tax.ISRWithheld).Add(line.Tax.VATWithheld)
+		if line.Tax.VATIncluded {
+			includedVAT = includedVAT.Add(line.Tax.VAT)
+		}
+	}
+	return includedVAT
+}
+
+// LandedCosts sets what one unit of each line cost once its discounts,
+// charges and included IVA are accounted for. Call it after Taxes.
+func LandedCosts(lines []models.PurchaseDetailv2, currency money.Currency) {
+	for i := range lines {
+		line := &lines[i]
+		line.LandedUnitCost = money.Zero
+		if line.Quantity <= 0 {
+			continue
+		}
+
+		landed := line.Subtotal.Sub(line.OrderDiscount)
+		if line.Tax != nil {
+			landed = line.Tax.Base
+		}
+		landed = landed.Add(line.LandedCost)
+		line.LandedUnitCost = landed.Div(money.FromInt(int64(line.Quantity)), currency.Digits()+2, money.HalfUp)
+	}
+}
+
+// Discount returns how much discount takes off base, rounded half up to the
+// minor unit of currency. It returns ErrDiscountTooLarge when an amount
+// discount is more than base.
+func Discount(discount *models.Discount, base money.Amount, currency money.Currency) (money.Amount, error) {
+	if discount == nil {
+		return money.Zero, nil
+	}
+
+	var amount money.Amount
+	if discount.Type == models.DiscountPercent {
+		amount = base.Mul(discount.Value).Div(money.FromInt(100), currency.Digits(), money.HalfUp)
+	} else {
+		amount = currency.Round(discount.Value, money.HalfUp)
+	}
+	if amount.Cmp(base) > 0 {
+		return money.Zero, ErrDiscountTooLarge
+	}
+	return amount, nil
+}
+
+// Gross returns price × quantity rounded half up to the minor unit of
+// currency. Totals add up rounded amounts so they always match the lines
+// shown on the order.
+func Gross(price money.Amount, quantity int, currency money.Currency) money.Amount {
+	return currency.Round(price.MulInt(int64(quantity)), money.HalfUp)
+}
+
+func subtotals(lines []models.PurchaseDetailv2) []money.Amount {
+	values := make([]money.Amount, len(lines))
+	for i, line := range lines {
+		values[i] = line.Subtotal
+	}
+	return values
+}
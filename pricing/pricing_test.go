@@ -0,0 +1,261 @@
+package pricing
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aldoramirezmartinez/fiber-api/models"
+	"github.com/aldoramirezmartinez/fiber-api/money"
+	"github.com/aldoramirezmartinez/fiber-api/tax"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	vat16    = models.LineTax{VATRate: money.MustParse("0.16")}
+	included = models.LineTax{VATIncluded: true, VATRate: money.MustParse("0.16")}
+	fees     = models.LineTax{VATRate: money.MustParse("0.16"), ISRWithholdingRate: money.MustParse("0.10"), VATWithholdingRate: money.MustParse("0.106667")}
+)
+
+func line(price string, quantity int, discount *models.Discount) models.PurchaseDetailv2 {
+	return models.PurchaseDetailv2{
+		ItemID:    primitive.NewObjectID(),
+		UnitPrice: money.MustParse(price),
+		Quantity:  quantity,
+		Discount:  discount,
+		Tax:       &vat16,
+	}
+}
+
+func percent(value string) *models.Discount {
+	return &models.Discount{Type: models.DiscountPercent, Value: money.MustParse(value)}
+}
+
+func fixed(value string) *models.Discount {
+	return &models.Discount{Type: models.DiscountAmount, Value: money.MustParse(value)}
+}
+
+// reapply taxes each line at the rates of the Tax it starts with, the way
+// lines saved earlier are taxed.
+func reapply(rates map[primitive.ObjectID]models.LineTax) TaxFunc {
+	return func(line models.PurchaseDetailv2, taxable money.Amount) *models.LineTax {
+		return tax.Reapply(rates[line.ItemID], taxable, "MXN")
+	}
+}
+
+func price(t *testing.T, purchase *models.Purchasev2) {
+	t.Helper()
+	rates := map[primitive.ObjectID]models.LineTax{}
+	for _, line := range purchase.ItemList {
+		rates[line.ItemID] = *line.Tax
+	}
+	if err := Price(purchase, "MXN", reapply(rates)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func check(t *testing.T, name string, got money.Amount, want string) {
+	t.Helper()
+	if got.String() != want {
+		t.Errorf("%s = %s, want %s", name, got, want)
+	}
+}
+
+func TestGross(t *testing.T) {
+	tests := []struct {
+		price    string
+		quantity int
+		currency money.Currency
+		want     string
+	}{
+		{"19.99", 3, "MXN", "59.97"},
+		{"0.333", 3, "MXN", "1.00"},
+		{"0.125", 1, "MXN", "0.13"},
+		{"12.5", 3, "JPY", "38"},
+		{"10.00", 0, "MXN", "0.00"},
+	}
+
+	for _, tt := range tests {
+		check(t, fmt.Sprintf("%s × %d %s", tt.price, tt.quantity, tt.currency), Gross(money.MustParse(tt.price), tt.quantity, tt.currency), tt.want)
+	}
+}
+
+func TestDiscount(t *testing.T) {
+	tests := []struct {
+		name     string
+		discount *models.Discount
+		base     string
+		currency money.Currency
+		want     string
+		err      error
+	}{
+		{"none", nil, "30.00", "MXN", "0", nil},
+		{"percent", percent("12.5"), "30.00", "MXN", "3.75", nil},
+		{"percent rounded", percent("10"), "33.33", "MXN", "3.33", nil},
+		{"percent rounded half up", percent("10"), "33.35", "MXN", "3.34", nil},
+		{"whole percent", percent("100"), "30.00", "MXN", "30.00", nil},
+		{"percent without decimals", percent("10"), "1005", "JPY", "101", nil},
+		{"fixed", fixed("5"), "30.00", "MXN", "5.00", nil},
+		{"fixed rounded", fixed("5.555"), "30.00", "MXN", "5.56", nil},
+		{"fixed for the whole base", fixed("30"), "30.00", "MXN", "30.00", nil},
+		{"fixed above the base", fixed("30.01"), "30.00", "MXN", "0", ErrDiscountTooLarge},
+	}
+
+	for _, tt := range tests {
+		got, err := Discount(tt.discount, money.MustParse(tt.base), tt.currency)
+		if err != tt.err {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.err)
+			continue
+		}
+		check(t, tt.name, got, tt.want)
+	}
+}
+
+func TestPriceLineDiscounts(t *testing.T) {
+	purchase := &models.Purchasev2{
+		ItemList: []models.PurchaseDetailv2{
+			line("50.00", 2, percent("10")),
+			line("10.00", 1, fixed("1")),
+			line("7.00", 1, nil),
+		},
+		Discount: percent("5"),
+	}
+	price(t, purchase)
+
+	lines := purchase.ItemList
+	check(t, "gross 1", lines[0].GrossAmount, "100.00")
+	check(t, "discount 1", lines[0].DiscountAmount, "10.00")
+	check(t, "subtotal 1", lines[0].Subtotal, "90.00")
+	check(t, "discount 2", lines[1].DiscountAmount, "1.00")
+	check(t, "subtotal 2", lines[1].Subtotal, "9.00")
+	check(t, "discount 3", lines[2].DiscountAmount, "0")
+	check(t, "subtotal 3", lines[2].Subtotal, "7.00")
+
+	// 5% of 106.00, spread by subtotal.
+	check(t, "order discount", purchase.DiscountAmount, "5.30")
+	check(t, "order discount 1", lines[0].OrderDiscount, "4.50")
+	check(t, "order discount 2", lines[1].OrderDiscount, "0.45")
+	check(t, "order discount 3", lines[2].OrderDiscount, "0.35")
+	check(t, "total", purchase.Total, "100.70")
+	check(t, "tax", purchase.Tax, "16.11")
+	check(t, "grand total", purchase.GrandTotal, "116.81")
+}
+
+func TestPriceOrderDiscountResidue(t *testing.T) {
+	purchase := &models.Purchasev2{
+		ItemList: []models.PurchaseDetailv2{
+			line("10.00", 1, nil),
+			line("10.00", 1, nil),
+			line("10.00", 1, nil),
+		},
+		Discount: fixed("10"),
+	}
+	price(t, purchase)
+
+	// 10.00 does not split evenly in three: the extra cent lands on one line
+	// only and the shares add up to the discount.
+	lines := purchase.ItemList
+	check(t, "order discount 1", lines[0].OrderDiscount, "3.34")
+	check(t, "order discount 2", lines[1].OrderDiscount, "3.33")
+	check(t, "order discount 3", lines[2].OrderDiscount, "3.33")
+	check(t, "discount", purchase.DiscountAmount, "10.00")
+	check(t, "total", purchase.Total, "20.00")
+	check(t, "tax 1", lines[0].Tax.VAT, "1.07")
+	check(t, "tax", purchase.Tax, "3.21")
+	check(t, "grand total", purchase.GrandTotal, "23.21")
+}
+
+func TestPriceCharges(t *testing.T) {
+	purchase := &models.Purchasev2{
+		ItemList: []models.PurchaseDetailv2{
+			line("100.00", 3, nil),
+			line("25.00", 4, nil),
+		},
+		Charges: []models.Charge{
+			{Type: models.ChargeShipping, Amount: money.MustParse("100")},
+			{Type: models.ChargeHandling, Amount: money.MustParse("14"), Allocation: models.AllocateByQuantity},
+			{Type: models.ChargeSurcharge, Amount: money.MustParse("0.005"), Allocation: models.AllocateByValue},
+		},
+	}
+	price(t, purchase)
+
+	lines := purchase.ItemList
+	check(t, "charge 3", purchase.Charges[2].Amount, "0.01")
+	check(t, "charges total", purchase.ChargesTotal, "114.01")
+	// Shipping 75.00 + 25.00 by value, handling 6.00 + 8.00 by quantity and
+	// the rounded surcharge on the larger line.
+	check(t, "landed cost 1", lines[0].LandedCost, "81.01")
+	check(t, "landed cost 2", lines[1].LandedCost, "33.00")
+	check(t, "landed unit cost 1", lines[0].LandedUnitCost, "127.0033")
+	check(t, "landed unit cost 2", lines[1].LandedUnitCost, "33.2500")
+	check(t, "total", purchase.Total, "514.01")
+	// Charges are not taxed.
+	check(t, "tax", purchase.Tax, "64.00")
+	check(t, "grand total", purchase.GrandTotal, "578.01")
+}
+
+func TestPriceChargeResidue(t *testing.T) {
+	purchase := &models.Purchasev2{
+		ItemList: []models.PurchaseDetailv2{
+			line("1.00", 1, nil),
+			line("1.00", 1, nil),
+			line("1.00", 1, nil),
+		},
+		Charges: []models.Charge{{Type: models.ChargeShipping, Amount: money.MustParse("0.05")}},
+	}
+	price(t, purchase)
+
+	// Each third rounds up to 0.02, so one line gives the extra cent back.
+	lines := purchase.ItemList
+	check(t, "landed cost 1", lines[0].LandedCost, "0.01")
+	check(t, "landed cost 2", lines[1].LandedCost, "0.02")
+	check(t, "landed cost 3", lines[2].LandedCost, "0.02")
+	check(t, "charges total", purchase.ChargesTotal, "0.05")
+}
+
+func TestPriceTaxes(t *testing.T) {
+	inclusive := line("116.00", 1, nil)
+	inclusive.Tax = &included
+	withheld := line("1000.00", 1, nil)
+	withheld.Tax = &fees
+
+	purchase := &models.Purchasev2{
+		ItemList: []models.PurchaseDetailv2{
+			line("100.00", 1, nil),
+			inclusive,
+			withheld,
+		},
+	}
+	price(t, purchase)
+
+	lines := purchase.ItemList
+	check(t, "exclusive base", lines[0].Tax.Base, "100.00")
+	check(t, "exclusive VAT", lines[0].Tax.VAT, "16.00")
+	check(t, "inclusive base", lines[1].Tax.Base, "100.00")
+	check(t, "inclusive VAT", lines[1].Tax.VAT, "16.00")
+	check(t, "inclusive landed unit cost", lines[1].LandedUnitCost, "100.0000")
+	check(t, "ISR withheld", lines[2].Tax.ISRWithheld, "100.00")
+	check(t, "VAT withheld", lines[2].Tax.VATWithheld, "106.67")
+
+	// The included IVA is taken out of the total, which is before taxes.
+	check(t, "total", purchase.Total, "1200.00")
+	check(t, "tax", purchase.Tax, "192.00")
+	check(t, "withholding", purchase.Withholding, "206.67")
+	check(t, "grand total", purchase.GrandTotal, "1185.33")
+}
+
+func TestPriceDiscountTooLarge(t *testing.T) {
+	tests := []struct {
+		name     string
+		purchase models.Purchasev2
+	}{
+		{"line", models.Purchasev2{ItemList: []models.PurchaseDetailv2{line("10.00", 1, fixed("11"))}}},
+		{"purchase", models.Purchasev2{ItemList: []models.PurchaseDetailv2{line("10.00", 1, nil)}, Discount: fixed("10.01")}},
+	}
+
+	for _, tt := range tests {
+		err := Price(&tt.purchase, "MXN", reapply(nil))
+		if err != ErrDiscountTooLarge {
+			t.Errorf("%s: error = %v, want ErrDiscountTooLarge", tt.name, err)
+		}
+	}
+}
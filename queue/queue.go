@@ -0,0 +1,93 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/aldoramirezmartinez/fiber-api/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const CollectionName = "jobs"
+
+// ErrNotRetryable is returned by Retry for jobs that are running or already
+// succeeded.
+var ErrNotRetryable = errors.New("only pending and dead jobs can be retried")
+
+// Enqueue adds a job of jobType to run at runAt, or right away when runAt is
+// zero. Inside UnitOfWork.Do pass the transaction context so the job is only
+// queued when the change that asked for it commits.
+func Enqueue(ctx context.Context, db *mongo.Database, jobType string, payload interface{}, runAt time.Time) (models.Job, error) {
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return models.Job{}, err
+	}
+
+	now := time.Now()
+	if runAt.IsZero() {
+		runAt = now
+	}
+	job := models.Job{
+		ID:        primitive.NewObjectID(),
+		Type:      jobType,
+		Payload:   string(encoded),
+		Status:    models.JobPending,
+		RunAt:     runAt,
+		CreatedAt: now,
+	}
+
+	_, err = db.Collection(CollectionName).InsertOne(ctx, job)
+	return job, err
+}
+
+// Retry puts a dead job back in the queue with a fresh set of attempts, or
+// makes a pending one run now. It returns mongo.ErrNoDocuments for unknown
+// IDs.
+func Retry(ctx context.Context, db *mongo.Database, jobID primitive.ObjectID) (models.Job, error) {
+	filter := bson.M{"_id": jobID, "status": bson.M{"$in": bson.A{models.JobPending, models.JobDead}}}
+	update := bson.M{
+		"$set": bson.M{
+			"status":   models.JobPending,
+			"attempts": 0,
+			"run_at":   time.Now(),
+		},
+		"$unset": bson.M{"finished_at": ""},
+	}
+
+	var job models.Job
+	err := db.Collection(CollectionName).FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&job)
+	if err == mongo.ErrNoDocuments {
+		count, countErr := db.Collection(CollectionName).CountDocuments(ctx, bson.M{"_id": jobID})
+		if countErr != nil {
+			return job, countErr
+		}
+		if count > 0 {
+			return job, ErrNotRetryable
+		}
+	}
+	return job, err
+}
+
+// EnsureIndexes creates the indexes workers claim jobs with, the one that
+// keeps a single job per recurring type and the one that removes succeeded
+// jobs after retention.
+func EnsureIndexes(ctx context.Context, db *mongo.Database, retention time.Duration) error {
+	_, err := db.Collection(CollectionName).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "type", Value: 1}, {Key: "status", Value: 1}, {Key: "run_at", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "locked_until", Value: 1}}},
+		{
+			Keys:    bson.D{{Key: "type", Value: 1}},
+			Options: options.Index().SetUnique(true).SetName("recurring_type_unique").SetPartialFilterExpression(bson.M{"recurring": true}),
+		},
+		{
+			Keys:    bson.D{{Key: "finished_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(retention.Seconds())).SetName("finished_at_ttl").SetPartialFilterExpression(bson.M{"status": models.JobSucceeded}),
+		},
+	})
+	return err
+}
@@ -0,0 +1,341 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aldoramirezmartinez/fiber-api/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	jobLease          = time.Minute
+	baseRetryDelay    = 10 * time.Second
+	maxRetryDelay     = time.Hour
+	finishTimeout     = 10 * time.Second
+	defaultAttempts   = 5
+	defaultConcurrent = 1
+)
+
+// HandlerOptions tune how a Worker runs the jobs of one type.
+type HandlerOptions struct {
+	// Concurrency is how many jobs of the type one Worker runs at a time
+	// (default 1). Every API instance runs its own Worker.
+	Concurrency int
+	// MaxAttempts is how many times a job is tried before it is marked
+	// dead (default 5).
+	MaxAttempts int
+	// Timeout bounds a single attempt. Zero means no limit.
+	Timeout time.Duration
+}
+
+type handler struct {
+	options HandlerOptions
+	// every is the interval of recurring job types, zero for the others.
+	every time.Duration
+	run   func(ctx context.Context, payload string) error
+}
+
+// Worker claims jobs from the queue and runs them with the handlers
+// registered for their type. Claimed jobs are leased and the lease is
+// renewed while they run, so several instances can share the queue and a
+// job left behind by a crashed instance is picked up again once its lease
+// expires.
+type Worker struct {
+	db       *mongo.Database
+	id       string
+	handlers map[string]handler
+	wg       sync.WaitGroup
+}
+
+func NewWorker(db *mongo.Database) *Worker {
+	hostname, _ := os.Hostname()
+	return &Worker{
+		db:       db,
+		id:       fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), primitive.NewObjectID().Hex()[18:]),
+		handlers: make(map[string]handler),
+	}
+}
+
+// Register sets the handler for jobs of jobType, decoding their payload into
+// T. Register every handler before calling Start.
+func Register[T any](w *Worker, jobType string, options HandlerOptions, handle func(ctx context.Context, payload T) error) {
+	if options.Concurrency <= 0 {
+		options.Concurrency = defaultConcurrent
+	}
+	if options.MaxAttempts <= 0 {
+		options.MaxAttempts = defaultAttempts
+	}
+
+	w.handlers[jobType] = handler{
+		options: options,
+		run: func(ctx context.Context, payload string) error {
+			var value T
+			if err := json.Unmarshal([]byte(payload), &value); err != nil {
+				return fmt.Errorf("decoding payload: %w", err)
+			}
+			return handle(ctx, value)
+		},
+	}
+}
+
+// Schedule registers run as a recurring job of jobType that runs every
+// interval. All instances share a single job of the type, so run is never
+// executed by two of them at once. A failed run is retried like any other
+// job; once it succeeds or uses up its attempts the job waits for the next
+// interval instead of finishing.
+func Schedule(w *Worker, jobType string, interval time.Duration, options HandlerOptions, run func(ctx context.Context) error) {
+	Register(w, jobType, options, func(ctx context.Context, _ struct{}) error {
+		return run(ctx)
+	})
+	h := w.handlers[jobType]
+	h.every = interval
+	w.handlers[jobType] = h
+}
+
+// Start polls the queue every interval for each registered job type until
+// ctx is cancelled, after making sure each recurring job type has its job.
+// Jobs still running then are put back in the queue without using up an
+// attempt; Wait returns once they have stopped.
+func (w *Worker) Start(ctx context.Context, interval time.Duration) {
+	for jobType, h := range w.handlers {
+		if h.every > 0 {
+			if err := w.ensureRecurring(ctx, jobType); err != nil {
+				fmt.Printf("Failed to schedule %s job: %v\n", jobType, err)
+			}
+		}
+	}
+
+	for jobType, h := range w.handlers {
+		w.wg.Add(1)
+		go w.poll(ctx, jobType, h, interval)
+	}
+}
+
+// Wait blocks until every job started before ctx was cancelled finished.
+func (w *Worker) Wait() {
+	w.wg.Wait()
+}
+
+func (w *Worker) poll(ctx context.Context, jobType string, h handler, interval time.Duration) {
+	defer w.wg.Done()
+
+	slots := make(chan struct{}, h.options.Concurrency)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case slots <- struct{}{}:
+		}
+
+		job, err := w.claim(ctx, jobType)
+		if err != nil {
+			<-slots
+			if err != mongo.ErrNoDocuments && ctx.Err() == nil {
+				fmt.Printf("Failed to claim %s job: %v\n", jobType, err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(interval):
+			}
+			continue
+		}
+
+		w.wg.Add(1)
+		go func() {
+			defer w.wg.Done()
+			defer func() { <-slots }()
+			w.run(ctx, job, h)
+		}()
+	}
+}
+
+// ensureRecurring inserts the job of a recurring jobType unless an instance
+// already did. The unique index on recurring types makes concurrent inserts
+// from several instances leave a single job.
+func (w *Worker) ensureRecurring(ctx context.Context, jobType string) error {
+	now := time.Now()
+	_, err := w.db.Collection(CollectionName).UpdateOne(ctx, bson.M{"type": jobType, "recurring": true}, bson.M{
+		"$setOnInsert": bson.M{
+			"payload":    "{}",
+			"status":     models.JobPending,
+			"run_at":     now,
+			"attempts":   0,
+			"created_at": now,
+		},
+	}, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	return err
+}
+
+// claim leases the next due job of jobType, or one whose lease expired.
+func (w *Worker) claim(ctx context.Context, jobType string) (models.Job, error) {
+	now := time.Now()
+	filter := bson.M{
+		"type": jobType,
+		"$or": bson.A{
+			bson.M{"status": models.JobPending, "run_at": bson.M{"$lte": now}},
+			bson.M{"status": models.JobRunning, "locked_until": bson.M{"$lte": now}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":       models.JobRunning,
+			"locked_by":    w.id,
+			"locked_until": now.Add(jobLease),
+		},
+		"$inc": bson.M{"attempts": 1},
+	}
+	findOptions := options.FindOneAndUpdate().
+		SetSort(bson.M{"run_at": 1}).
+		SetReturnDocument(options.After)
+
+	var job models.Job
+	err := w.db.Collection(CollectionName).FindOneAndUpdate(ctx, filter, update, findOptions).Decode(&job)
+	return job, err
+}
+
+func (w *Worker) run(ctx context.Context, job models.Job, h handler) {
+	// A job whose lease ran out on its last attempt was most likely
+	// crashing its worker; don't run it again.
+	if job.Attempts > h.options.MaxAttempts {
+		if job.Recurring {
+			w.finish(job, nextRun(h, "lease expired during the last attempt"), nil)
+			return
+		}
+		w.finish(job, bson.M{
+			"status":      models.JobDead,
+			"last_error":  "lease expired during the last attempt",
+			"finished_at": time.Now(),
+		}, nil)
+		return
+	}
+
+	var jobCtx context.Context
+	var cancel context.CancelFunc
+	if h.options.Timeout > 0 {
+		jobCtx, cancel = context.WithTimeout(ctx, h.options.Timeout)
+	} else {
+		jobCtx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+		w.renewLease(jobCtx, cancel, job)
+	}()
+
+	err := runHandler(jobCtx, h, job.Payload)
+	cancel()
+	<-renewed
+
+	switch {
+	case err == nil && job.Recurring:
+		w.finish(job, nextRun(h, ""), bson.M{"last_error": ""})
+	case err == nil:
+		w.finish(job, bson.M{"status": models.JobSucceeded, "finished_at": time.Now()}, bson.M{"last_error": ""})
+	case ctx.Err() != nil:
+		// Shutting down: give the attempt back.
+		w.finish(job, bson.M{"status": models.JobPending, "run_at": time.Now(), "attempts": job.Attempts - 1}, nil)
+	case job.Attempts >= h.options.MaxAttempts && job.Recurring:
+		w.finish(job, nextRun(h, err.Error()), nil)
+	case job.Attempts >= h.options.MaxAttempts:
+		w.finish(job, bson.M{"status": models.JobDead, "last_error": err.Error(), "finished_at": time.Now()}, nil)
+	default:
+		w.finish(job, bson.M{"status": models.JobPending, "last_error": err.Error(), "run_at": time.Now().Add(retryDelay(job.Attempts))}, nil)
+	}
+}
+
+// nextRun puts a recurring job back in the queue for its next interval with
+// a fresh set of attempts.
+func nextRun(h handler, lastError string) bson.M {
+	set := bson.M{"status": models.JobPending, "attempts": 0, "run_at": time.Now().Add(h.every)}
+	if lastError != "" {
+		set["last_error"] = lastError
+	}
+	return set
+}
+
+// runHandler turns a panicking handler into a failed attempt.
+func runHandler(ctx context.Context, h handler, payload string) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("panic: %v", recovered)
+		}
+	}()
+	return h.run(ctx, payload)
+}
+
+// renewLease extends the lease of job until ctx is done. If another worker
+// took the job over, the handler is cancelled.
+func (w *Worker) renewLease(ctx context.Context, cancel context.CancelFunc, job models.Job) {
+	ticker := time.NewTicker(jobLease / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		result, err := w.db.Collection(CollectionName).UpdateOne(ctx, leaseFilter(job, w.id), bson.M{
+			"$set": bson.M{"locked_until": time.Now().Add(jobLease)},
+		})
+		if err != nil {
+			continue
+		}
+		if result.MatchedCount == 0 {
+			fmt.Printf("Lost the lease of %s job %s\n", job.Type, job.ID.Hex())
+			cancel()
+			return
+		}
+	}
+}
+
+// finish records the outcome of an attempt and releases the lease, unless
+// another worker took the job over meanwhile.
+func (w *Worker) finish(job models.Job, set bson.M, unset bson.M) {
+	ctx, cancel := context.WithTimeout(context.Background(), finishTimeout)
+	defer cancel()
+
+	if unset == nil {
+		unset = bson.M{}
+	}
+	unset["locked_by"] = ""
+	unset["locked_until"] = ""
+
+	_, err := w.db.Collection(CollectionName).UpdateOne(ctx, leaseFilter(job, w.id), bson.M{"$set": set, "$unset": unset})
+	if err != nil {
+		fmt.Printf("Failed to update %s job %s: %v\n", job.Type, job.ID.Hex(), err)
+	}
+}
+
+func leaseFilter(job models.Job, workerID string) bson.M {
+	return bson.M{"_id": job.ID, "locked_by": workerID, "attempts": job.Attempts}
+}
+
+// retryDelay doubles from baseRetryDelay after every failed attempt, up to
+// maxRetryDelay.
+func retryDelay(attempts int) time.Duration {
+	delay := baseRetryDelay
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		return maxRetryDelay
+	}
+	return delay
+}
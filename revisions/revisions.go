@@ -0,0 +1,134 @@
+// Package revisions archives the earlier revisions of purchases and
+// compares them.
+package revisions
+
+import (
+	"context"
+	"time"
+
+	"github.com/aldoramirezmartinez/fiber-api/audit"
+	"github.com/aldoramirezmartinez/fiber-api/models"
+	"github.com/aldoramirezmartinez/fiber-api/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const CollectionName = "purchase_revisions"
+
+// ignoredFields describe the revision itself or change without amending the
+// purchase, so diffs skip them.
+var ignoredFields = map[string]bool{
+	"revision":        true,
+	"revision_reason": true,
+	"revised_at":      true,
+	"deleted_at":      true,
+	"deleted_by":      true,
+}
+
+// Number returns the revision of purchase. Purchases written before
+// revisions were tracked are revision 1.
+func Number(purchase models.Purchasev2) int {
+	if purchase.Revision < 1 {
+		return 1
+	}
+	return purchase.Revision
+}
+
+// Amends reports whether changes, as listed by Diff, amend previous and so
+// start a new revision. Drafts can change freely, and so can the status of a
+// purchase; anything else that changes once a purchase left draft is an
+// amendment.
+func Amends(previous models.Purchasev2, changes []models.FieldChange) bool {
+	if previous.Status == models.PurchaseStatusDraft {
+		return false
+	}
+	for _, change := range changes {
+		if change.Field != "status" {
+			return true
+		}
+	}
+	return false
+}
+
+// Diff lists the fields that differ between two revisions of a purchase,
+// sorted by path. Paths use the stored field names, so they include
+// provider_id and user_id.
+func Diff(from models.Purchasev2, to models.Purchasev2) ([]models.FieldChange, error) {
+	before, err := bson.Marshal(from)
+	if err != nil {
+		return nil, err
+	}
+	after, err := bson.Marshal(to)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []models.FieldChange{}
+	for _, change := range audit.Diff(before, after) {
+		if !ignoredFields[change.Field] {
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
+
+// Archive stores previous as an earlier revision of its purchase, superseded
+// by actor. Call it inside the transaction that writes the amendment.
+func Archive(ctx context.Context, db *mongo.Database, previous models.Purchasev2, actor *primitive.ObjectID) error {
+	now := time.Now()
+	_, err := db.Collection(CollectionName).InsertOne(ctx, models.PurchaseRevision{
+		PurchaseID:   previous.ID,
+		Revision:     Number(previous),
+		Purchase:     previous,
+		SupersededAt: &now,
+		SupersededBy: actor,
+	})
+	return err
+}
+
+// List returns the archived revisions of purchase purchaseID, oldest first.
+func List(ctx context.Context, db *mongo.Database, purchaseID primitive.ObjectID) ([]models.PurchaseRevision, error) {
+	cursor, err := db.Collection(CollectionName).Find(ctx,
+		bson.M{"purchase_id": purchaseID},
+		options.Find().SetSort(bson.D{{Key: "revision", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	archived := []models.PurchaseRevision{}
+	if err := cursor.All(ctx, &archived); err != nil {
+		return nil, err
+	}
+	return archived, nil
+}
+
+// Get returns archived revision number of purchase purchaseID, or
+// mongo.ErrNoDocuments.
+func Get(ctx context.Context, db *mongo.Database, purchaseID primitive.ObjectID, number int) (models.PurchaseRevision, error) {
+	var archived models.PurchaseRevision
+	err := db.Collection(CollectionName).FindOne(ctx, bson.M{"purchase_id": purchaseID, "revision": number}).Decode(&archived)
+	return archived, err
+}
+
+// Restore brings back the revisions of purchase purchaseID that were soft
+// deleted along with it. Call it inside the transaction that restores the
+// purchase.
+func Restore(ctx context.Context, db *mongo.Database, purchaseID primitive.ObjectID) error {
+	_, err := db.Collection(CollectionName).UpdateMany(ctx,
+		utils.OnlyDeleted(bson.M{"purchase_id": purchaseID}),
+		bson.M{"$unset": bson.M{"deleted_at": "", "deleted_by": ""}})
+	return err
+}
+
+// EnsureIndexes creates the index that lists the revisions of a purchase and
+// keeps a revision from being archived twice.
+func EnsureIndexes(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection(CollectionName).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "purchase_id", Value: 1}, {Key: "revision", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"github.com/aldoramirezmartinez/fiber-api/controllers"
+	"github.com/aldoramirezmartinez/fiber-api/middlewares"
+	"github.com/gofiber/fiber/v2"
+)
+
+type AdminRoutes struct {
+	router              fiber.Router
+	integrityController *controllers.IntegrityController
+}
+
+func NewAdminRoutes(router fiber.Router, integrityController *controllers.IntegrityController) *AdminRoutes {
+	return &AdminRoutes{
+		router:              router,
+		integrityController: integrityController,
+	}
+}
+
+func (ar *AdminRoutes) SetupRoutes() {
+	adminRouter := ar.router.Group("/api/admin", middlewares.NewRequireAdmin())
+
+	adminRouter.Get("/integrity", ar.integrityController.CheckIntegrity)
+	adminRouter.Post("/integrity/repair", ar.integrityController.RepairIntegrity)
+}
@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"github.com/aldoramirezmartinez/fiber-api/controllers"
+	"github.com/aldoramirezmartinez/fiber-api/middlewares"
+	"github.com/gofiber/fiber/v2"
+)
+
+type AuditRoutes struct {
+	router          fiber.Router
+	auditController *controllers.AuditController
+}
+
+func NewAuditRoutes(router fiber.Router, auditController *controllers.AuditController) *AuditRoutes {
+	return &AuditRoutes{
+		router:          router,
+		auditController: auditController,
+	}
+}
+
+func (ar *AuditRoutes) SetupRoutes() {
+	auditRouter := ar.router.Group("/api/audit", middlewares.NewRequireAdmin())
+
+	auditRouter.Get("/", ar.auditController.GetAuditEntries)
+	auditRouter.Get("/verify", ar.auditController.VerifyAuditLog)
+}
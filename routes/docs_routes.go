@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"github.com/aldoramirezmartinez/fiber-api/controllers"
+	"github.com/gofiber/fiber/v2"
+)
+
+type DocsRoutes struct {
+	router         fiber.Router
+	docsController *controllers.DocsController
+}
+
+func NewDocsRoutes(router fiber.Router, docsController *controllers.DocsController) *DocsRoutes {
+	return &DocsRoutes{
+		router:         router,
+		docsController: docsController,
+	}
+}
+
+func (dr *DocsRoutes) SetupRoutes() {
+	dr.router.Get("/openapi.json", dr.docsController.GetOpenAPI)
+	dr.router.Get("/docs", dr.docsController.GetSwaggerUI)
+}
@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"github.com/aldoramirezmartinez/fiber-api/controllers"
+	"github.com/aldoramirezmartinez/fiber-api/middlewares"
+	"github.com/gofiber/fiber/v2"
+)
+
+type ExchangeRateRoutes struct {
+	router                 fiber.Router
+	exchangeRateController *controllers.ExchangeRateController
+}
+
+func NewExchangeRateRoutes(router fiber.Router, exchangeRateController *controllers.ExchangeRateController) *ExchangeRateRoutes {
+	return &ExchangeRateRoutes{
+		router:                 router,
+		exchangeRateController: exchangeRateController,
+	}
+}
+
+func (er *ExchangeRateRoutes) SetupRoutes() {
+	exchangeRateRouter := er.router.Group("/api/exchange-rates")
+
+	exchangeRateRouter.Get("/", er.exchangeRateController.GetExchangeRates)
+	exchangeRateRouter.Post("/", middlewares.NewRequireAdmin(), er.exchangeRateController.CreateExchangeRates)
+}
@@ -24,6 +24,12 @@ func (ir *ItemRoutes) SetupRoutes() {
 	itemRouter.Get("/:id", ir.itemController.GetItem)
 	itemRouter.Post("/", ir.itemController.CreateItem)
 	itemRouter.Put("/:id", ir.itemController.UpdateItem)
+	itemRouter.Patch("/:id", ir.itemController.PatchItem)
+	itemRouter.Post("/:id/restore", ir.itemController.RestoreItem)
 	itemRouter.Delete("/:id", ir.itemController.DeleteItem)
-
+	itemRouter.Get("/:id/prices", ir.itemController.GetItemPrices)
+	itemRouter.Post("/:id/prices", ir.itemController.ChangeItemPrice)
+	itemRouter.Delete("/:id/prices/:price_id", ir.itemController.CancelItemPrice)
+	itemRouter.Get("/:id/price", ir.itemController.GetItemPriceAt)
+	itemRouter.Get("/:id/best-source", ir.itemController.GetBestSource)
 }
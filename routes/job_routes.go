@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"github.com/aldoramirezmartinez/fiber-api/controllers"
+	"github.com/aldoramirezmartinez/fiber-api/middlewares"
+	"github.com/gofiber/fiber/v2"
+)
+
+type JobRoutes struct {
+	router        fiber.Router
+	jobController *controllers.JobController
+}
+
+func NewJobRoutes(router fiber.Router, jobController *controllers.JobController) *JobRoutes {
+	return &JobRoutes{
+		router:        router,
+		jobController: jobController,
+	}
+}
+
+func (jr *JobRoutes) SetupRoutes() {
+	jobRouter := jr.router.Group("/api/admin/jobs", middlewares.NewRequireAdmin())
+
+	jobRouter.Get("/", jr.jobController.GetJobs)
+	jobRouter.Get("/:id", jr.jobController.GetJob)
+	jobRouter.Post("/:id/retry", jr.jobController.RetryJob)
+}
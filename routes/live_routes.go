@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"github.com/aldoramirezmartinez/fiber-api/controllers"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/websocket/v2"
+)
+
+type LiveRoutes struct {
+	router         fiber.Router
+	liveController *controllers.LiveController
+}
+
+func NewLiveRoutes(router fiber.Router, liveController *controllers.LiveController) *LiveRoutes {
+	return &LiveRoutes{
+		router:         router,
+		liveController: liveController,
+	}
+}
+
+func (lr *LiveRoutes) SetupRoutes() {
+	liveRouter := lr.router.Group("/api/live")
+
+	liveRouter.Get("/events", lr.liveController.StreamEvents)
+	liveRouter.Get("/ws", lr.liveController.UpgradeWebSocket, websocket.New(lr.liveController.StreamWebSocket))
+}
@@ -24,5 +24,7 @@ func (pr *ProviderRoutes) SetupRoutes() {
 	providerRouter.Get("/:id", pr.providerController.GetProvider)
 	providerRouter.Post("/", pr.providerController.CreateProvider)
 	providerRouter.Put("/:id", pr.providerController.UpdateProvider)
+	providerRouter.Patch("/:id", pr.providerController.PatchProvider)
+	providerRouter.Post("/:id/restore", pr.providerController.RestoreProvider)
 	providerRouter.Delete("/:id", pr.providerController.DeleteProvider)
 }
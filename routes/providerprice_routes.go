@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"github.com/aldoramirezmartinez/fiber-api/controllers"
+	"github.com/gofiber/fiber/v2"
+)
+
+type ProviderPriceRoutes struct {
+	router                  fiber.Router
+	providerPriceController *controllers.ProviderPriceController
+}
+
+func NewProviderPriceRoutes(router fiber.Router, providerPriceController *controllers.ProviderPriceController) *ProviderPriceRoutes {
+	return &ProviderPriceRoutes{
+		router:                  router,
+		providerPriceController: providerPriceController,
+	}
+}
+
+func (pr *ProviderPriceRoutes) SetupRoutes() {
+	providerPriceRouter := pr.router.Group("/api/provider-prices")
+
+	providerPriceRouter.Get("/", pr.providerPriceController.GetAllProviderPrices)
+	providerPriceRouter.Get("/:id", pr.providerPriceController.GetProviderPrice)
+	providerPriceRouter.Post("/", pr.providerPriceController.CreateProviderPrice)
+	providerPriceRouter.Put("/:id", pr.providerPriceController.UpdateProviderPrice)
+	providerPriceRouter.Delete("/:id", pr.providerPriceController.DeleteProviderPrice)
+}
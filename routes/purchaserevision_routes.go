@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"github.com/aldoramirezmartinez/fiber-api/controllers"
+	"github.com/gofiber/fiber/v2"
+)
+
+type PurchaseRevisionRoutes struct {
+	router                     fiber.Router
+	purchaseRevisionController *controllers.PurchaseRevisionController
+}
+
+func NewPurchaseRevisionRoutes(router fiber.Router, purchaseRevisionController *controllers.PurchaseRevisionController) *PurchaseRevisionRoutes {
+	return &PurchaseRevisionRoutes{
+		router:                     router,
+		purchaseRevisionController: purchaseRevisionController,
+	}
+}
+
+func (rr *PurchaseRevisionRoutes) SetupRoutes() {
+	revisionRouter := rr.router.Group("/api/purchases/:id/revisions")
+
+	revisionRouter.Get("/", rr.purchaseRevisionController.GetPurchaseRevisions)
+	// diff is registered before /:revision so it is not taken for a number.
+	revisionRouter.Get("/diff", rr.purchaseRevisionController.DiffPurchaseRevisions)
+	revisionRouter.Get("/:revision", rr.purchaseRevisionController.GetPurchaseRevision)
+}
@@ -23,4 +23,8 @@ func (pr *PurchaseV2Routes) SetupRoutes() {
 	purchasev2Router.Get("/", pr.PurchaseV2Controller.GetAllPurchasesV2)
 	purchasev2Router.Get("/:purchase_order", pr.PurchaseV2Controller.GetPurchaseV2)
 	purchasev2Router.Post("/", pr.PurchaseV2Controller.CreatePurchaseV2)
+	purchasev2Router.Put("/:id", pr.PurchaseV2Controller.UpdatePurchaseV2)
+	purchasev2Router.Patch("/:id", pr.PurchaseV2Controller.PatchPurchaseV2)
+	purchasev2Router.Delete("/:id", pr.PurchaseV2Controller.DeletePurchaseV2)
+	purchasev2Router.Post("/:id/restore", pr.PurchaseV2Controller.RestorePurchaseV2)
 }
@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"github.com/aldoramirezmartinez/fiber-api/controllers"
+	"github.com/gofiber/fiber/v2"
+)
+
+type ReportRoutes struct {
+	router           fiber.Router
+	reportController *controllers.ReportController
+}
+
+func NewReportRoutes(router fiber.Router, reportController *controllers.ReportController) *ReportRoutes {
+	return &ReportRoutes{
+		router:           router,
+		reportController: reportController,
+	}
+}
+
+func (rr *ReportRoutes) SetupRoutes() {
+	reportRouter := rr.router.Group("/api/reports")
+
+	reportRouter.Get("/purchases", rr.reportController.GetPurchaseReport)
+}
@@ -0,0 +1,31 @@
+package routes
+
+import (
+	"github.com/aldoramirezmartinez/fiber-api/controllers"
+	"github.com/aldoramirezmartinez/fiber-api/middlewares"
+	"github.com/gofiber/fiber/v2"
+)
+
+type RequisitionRoutes struct {
+	router                fiber.Router
+	requisitionController *controllers.RequisitionController
+}
+
+func NewRequisitionRoutes(router fiber.Router, requisitionController *controllers.RequisitionController) *RequisitionRoutes {
+	return &RequisitionRoutes{
+		router:                router,
+		requisitionController: requisitionController,
+	}
+}
+
+func (rr *RequisitionRoutes) SetupRoutes() {
+	requisitionRouter := rr.router.Group("/api/requisitions")
+
+	requisitionRouter.Get("/", rr.requisitionController.GetAllRequisitions)
+	requisitionRouter.Get("/:id", rr.requisitionController.GetRequisition)
+	requisitionRouter.Post("/", rr.requisitionController.CreateRequisition)
+	requisitionRouter.Post("/convert", rr.requisitionController.ConvertRequisitions)
+	requisitionRouter.Post("/:id/approve", middlewares.NewRequireAdmin(), rr.requisitionController.ApproveRequisition)
+	requisitionRouter.Post("/:id/reject", middlewares.NewRequireAdmin(), rr.requisitionController.RejectRequisition)
+	requisitionRouter.Post("/:id/cancel", rr.requisitionController.CancelRequisition)
+}
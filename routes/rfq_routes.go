@@ -0,0 +1,30 @@
+package routes
+
+import (
+	"github.com/aldoramirezmartinez/fiber-api/controllers"
+	"github.com/gofiber/fiber/v2"
+)
+
+type RFQRoutes struct {
+	router        fiber.Router
+	rfqController *controllers.RFQController
+}
+
+func NewRFQRoutes(router fiber.Router, rfqController *controllers.RFQController) *RFQRoutes {
+	return &RFQRoutes{
+		router:        router,
+		rfqController: rfqController,
+	}
+}
+
+func (rr *RFQRoutes) SetupRoutes() {
+	rfqRouter := rr.router.Group("/api/rfqs")
+
+	rfqRouter.Get("/", rr.rfqController.GetAllRFQs)
+	rfqRouter.Get("/:id", rr.rfqController.GetRFQ)
+	rfqRouter.Post("/", rr.rfqController.CreateRFQ)
+	rfqRouter.Post("/:id/cancel", rr.rfqController.CancelRFQ)
+	rfqRouter.Post("/:id/quotes", rr.rfqController.SubmitQuote)
+	rfqRouter.Get("/:id/comparison", rr.rfqController.CompareQuotes)
+	rfqRouter.Post("/:id/quotes/:quote_id/convert", rr.rfqController.ConvertQuote)
+}
@@ -0,0 +1,48 @@
+package routes
+
+import (
+	"github.com/aldoramirezmartinez/fiber-api/controllers"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Controllers holds the controllers whose routes Setup registers.
+type Controllers struct {
+	User             *controllers.UserController
+	Provider         *controllers.ProviderController
+	Item             *controllers.ItemController
+	PurchaseV2       *controllers.PurchaseV2Controller
+	Docs             *controllers.DocsController
+	Integrity        *controllers.IntegrityController
+	Audit            *controllers.AuditController
+	Webhook          *controllers.WebhookController
+	Live             *controllers.LiveController
+	Job              *controllers.JobController
+	ExchangeRate     *controllers.ExchangeRateController
+	Report           *controllers.ReportController
+	TaxRule          *controllers.TaxRuleController
+	ProviderPrice    *controllers.ProviderPriceController
+	RFQ              *controllers.RFQController
+	Requisition      *controllers.RequisitionController
+	PurchaseRevision *controllers.PurchaseRevisionController
+}
+
+// Setup registers the routes of every API group.
+func Setup(router fiber.Router, c Controllers) {
+	NewUserRoutes(router, c.User).SetupRoutes()
+	NewProviderRoutes(router, c.Provider).SetupRoutes()
+	NewItemRoutes(router, c.Item).SetupRoutes()
+	NewPurchaseV2Routes(router, c.PurchaseV2).SetupRoutes()
+	NewDocsRoutes(router, c.Docs).SetupRoutes()
+	NewAdminRoutes(router, c.Integrity).SetupRoutes()
+	NewAuditRoutes(router, c.Audit).SetupRoutes()
+	NewWebhookRoutes(router, c.Webhook).SetupRoutes()
+	NewLiveRoutes(router, c.Live).SetupRoutes()
+	NewJobRoutes(router, c.Job).SetupRoutes()
+	NewExchangeRateRoutes(router, c.ExchangeRate).SetupRoutes()
+	NewReportRoutes(router, c.Report).SetupRoutes()
+	NewTaxRuleRoutes(router, c.TaxRule).SetupRoutes()
+	NewProviderPriceRoutes(router, c.ProviderPrice).SetupRoutes()
+	NewRFQRoutes(router, c.RFQ).SetupRoutes()
+	NewRequisitionRoutes(router, c.Requisition).SetupRoutes()
+	NewPurchaseRevisionRoutes(router, c.PurchaseRevision).SetupRoutes()
+}
@@ -0,0 +1,29 @@
+package routes
+
+import (
+	"github.com/aldoramirezmartinez/fiber-api/controllers"
+	"github.com/aldoramirezmartinez/fiber-api/middlewares"
+	"github.com/gofiber/fiber/v2"
+)
+
+type TaxRuleRoutes struct {
+	router            fiber.Router
+	taxRuleController *controllers.TaxRuleController
+}
+
+func NewTaxRuleRoutes(router fiber.Router, taxRuleController *controllers.TaxRuleController) *TaxRuleRoutes {
+	return &TaxRuleRoutes{
+		router:            router,
+		taxRuleController: taxRuleController,
+	}
+}
+
+func (tr *TaxRuleRoutes) SetupRoutes() {
+	taxRuleRouter := tr.router.Group("/api/tax-rules")
+
+	taxRuleRouter.Get("/", tr.taxRuleController.GetAllTaxRules)
+	taxRuleRouter.Get("/:id", tr.taxRuleController.GetTaxRule)
+	taxRuleRouter.Post("/", middlewares.NewRequireAdmin(), tr.taxRuleController.CreateTaxRule)
+	taxRuleRouter.Put("/:id", middlewares.NewRequireAdmin(), tr.taxRuleController.UpdateTaxRule)
+	taxRuleRouter.Delete("/:id", middlewares.NewRequireAdmin(), tr.taxRuleController.DeleteTaxRule)
+}
@@ -24,5 +24,7 @@ func (ur *UserRoutes) SetupRoutes() {
 	userRouter.Get("/:id", ur.userController.GetUser)
 	userRouter.Post("/", ur.userController.CreateUser)
 	userRouter.Put("/:id", ur.userController.UpdateUser)
+	userRouter.Patch("/:id", ur.userController.PatchUser)
+	userRouter.Post("/:id/restore", ur.userController.RestoreUser)
 	userRouter.Delete("/:id", ur.userController.DeleteUser)
 }
@@ -0,0 +1,32 @@
+package routes
+
+import (
+	"github.com/aldoramirezmartinez/fiber-api/controllers"
+	"github.com/aldoramirezmartinez/fiber-api/middlewares"
+	"github.com/gofiber/fiber/v2"
+)
+
+type WebhookRoutes struct {
+	router            fiber.Router
+	webhookController *controllers.WebhookController
+}
+
+func NewWebhookRoutes(router fiber.Router, webhookController *controllers.WebhookController) *WebhookRoutes {
+	return &WebhookRoutes{
+		router:            router,
+		webhookController: webhookController,
+	}
+}
+
+func (wr *WebhookRoutes) SetupRoutes() {
+	webhookRouter := wr.router.Group("/api/webhooks", middlewares.NewRequireAdmin())
+
+	webhookRouter.Get("/", wr.webhookController.GetAllWebhooks)
+	webhookRouter.Get("/:id", wr.webhookController.GetWebhook)
+	webhookRouter.Post("/", wr.webhookController.CreateWebhook)
+	webhookRouter.Put("/:id", wr.webhookController.UpdateWebhook)
+	webhookRouter.Delete("/:id", wr.webhookController.DeleteWebhook)
+	webhookRouter.Get("/:id/deliveries", wr.webhookController.GetWebhookDeliveries)
+	webhookRouter.Post("/:id/ping", wr.webhookController.PingWebhook)
+	webhookRouter.Post("/deliveries/:id/redeliver", wr.webhookController.RedeliverWebhook)
+}
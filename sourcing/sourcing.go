@@ -0,0 +1,138 @@
+// Package sourcing keeps the price lists of providers and picks where to
+// buy an item from.
+package sourcing
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/aldoramirezmartinez/fiber-api/exchange"
+	"github.com/aldoramirezmartinez/fiber-api/models"
+	"github.com/aldoramirezmartinez/fiber-api/money"
+	"github.com/aldoramirezmartinez/fiber-api/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const CollectionName = "provider_prices"
+
+// ErrBelowMinimum is returned by Options when valid offers exist but all of
+// them are for larger orders.
+var ErrBelowMinimum = errors.New("quantity is below the minimum order quantity of every offer")
+
+// ValidAt returns a filter for the offers valid at at.
+func ValidAt(filter bson.M, at time.Time) bson.M {
+	filter["$and"] = bson.A{
+		bson.M{"$or": bson.A{bson.M{"valid_from": nil}, bson.M{"valid_from": bson.M{"$lte": at}}}},
+		bson.M{"$or": bson.A{bson.M{"valid_to": nil}, bson.M{"valid_to": bson.M{"$gt": at}}}},
+	}
+	return filter
+}
+
+// Options returns the offers that can supply quantity of item itemID at at,
+// priced in currency at the exchange rates of that date, cheapest first.
+// Ties go to the shorter lead time and then to the older offer. A non-zero
+// providerID only considers that provider's offers, and offers of deleted
+// providers are never considered. It returns no options and no error when
+// no offer is valid at at, and ErrBelowMinimum when none allows quantity.
+func Options(ctx context.Context, db *mongo.Database, itemID primitive.ObjectID, providerID primitive.ObjectID, quantity int, currency money.Currency, at time.Time) ([]models.SourceOption, error) {
+	filter := bson.M{"item_id": itemID}
+	if !providerID.IsZero() {
+		filter["provider_id"] = providerID
+	}
+
+	cursor, err := db.Collection(CollectionName).Find(ctx, ValidAt(filter, at))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var offers []models.ProviderPrice
+	if err := cursor.All(ctx, &offers); err != nil {
+		return nil, err
+	}
+
+	providers, err := loadProviders(ctx, db, offers)
+	if err != nil {
+		return nil, err
+	}
+
+	valid := false
+	options := []models.SourceOption{}
+	for _, offer := range offers {
+		provider, ok := providers[offer.ProviderID]
+		if !ok {
+			continue
+		}
+		valid = true
+		if !offer.Allows(quantity) {
+			continue
+		}
+
+		unitPrice, err := exchange.Convert(ctx, db, offer.Price, offer.Currency, currency, at)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, models.SourceOption{
+			Offer:        offer,
+			ProviderName: provider.Name,
+			UnitPrice:    unitPrice,
+			Total:        currency.Round(unitPrice.MulInt(int64(quantity)), money.HalfUp),
+			Currency:     currency,
+		})
+	}
+	if valid && len(options) == 0 {
+		return nil, ErrBelowMinimum
+	}
+
+	sort.SliceStable(options, func(i, j int) bool {
+		a, b := options[i], options[j]
+		if cmp := a.Total.Cmp(b.Total); cmp != 0 {
+			return cmp < 0
+		}
+		if a.Offer.LeadTimeDays != b.Offer.LeadTimeDays {
+			return a.Offer.LeadTimeDays < b.Offer.LeadTimeDays
+		}
+		return a.Offer.ID.Timestamp().Before(b.Offer.ID.Timestamp())
+	})
+	return options, nil
+}
+
+// loadProviders returns the providers of offers that are not deleted.
+func loadProviders(ctx context.Context, db *mongo.Database, offers []models.ProviderPrice) (map[primitive.ObjectID]models.Provider, error) {
+	providers := map[primitive.ObjectID]models.Provider{}
+	if len(offers) == 0 {
+		return providers, nil
+	}
+
+	ids := bson.A{}
+	for _, offer := range offers {
+		ids = append(ids, offer.ProviderID)
+	}
+
+	cursor, err := db.Collection("providers").Find(ctx, utils.NotDeleted(bson.M{"_id": bson.M{"$in": ids}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var found []models.Provider
+	if err := cursor.All(ctx, &found); err != nil {
+		return nil, err
+	}
+	for _, provider := range found {
+		providers[provider.ID] = provider
+	}
+	return providers, nil
+}
+
+// EnsureIndexes creates the index offers are looked up by.
+func EnsureIndexes(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection(CollectionName).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "item_id", Value: 1}, {Key: "provider_id", Value: 1}},
+	})
+	return err
+}
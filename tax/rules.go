@@ -0,0 +1,113 @@
+// Package tax works out the IVA and withholdings of purchase lines from the
+// tax rules configured per item category and provider.
+package tax
+
+import (
+	"context"
+
+	"github.com/aldoramirezmartinez/fiber-api/models"
+	"github.com/aldoramirezmartinez/fiber-api/money"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const CollectionName = "tax_rules"
+
+// Rules are the tax rules in effect, loaded once per purchase.
+type Rules struct {
+	rules      []models.TaxRule
+	defaultVAT money.Amount
+}
+
+// Load reads every tax rule. Lines no rule matches pay defaultVAT and have
+// nothing withheld.
+func Load(ctx context.Context, db *mongo.Database, defaultVAT money.Amount) (*Rules, error) {
+	cursor, err := db.Collection(CollectionName).Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	rules := &Rules{defaultVAT: defaultVAT}
+	if err := cursor.All(ctx, &rules.rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// Match returns the most specific rule for an item category bought from
+// provider: a rule for the provider beats one for its tax type, and either
+// beats one for any provider; within each, a rule for the category beats a
+// rule for any category. Ties go to the rule created first. ok is false
+// when no rule matches.
+func (r *Rules) Match(category string, provider models.Provider) (rule models.TaxRule, ok bool) {
+	best := -1
+	for _, candidate := range r.rules {
+		score := 0
+		switch {
+		case candidate.ProviderID != nil:
+			if *candidate.ProviderID != provider.ID {
+				continue
+			}
+			score += 4
+		case candidate.ProviderType != "":
+			if candidate.ProviderType != provider.TaxType {
+				continue
+			}
+			score += 2
+		}
+		if candidate.Category != "" {
+			if candidate.Category != category {
+				continue
+			}
+			score++
+		}
+
+		if score > best || (score == best && candidate.ID.Timestamp().Before(rule.ID.Timestamp())) {
+			best = score
+			rule = candidate
+		}
+	}
+	return rule, best >= 0
+}
+
+// Line returns the taxes of a line for amount, its subtotal less its share
+// of the purchase discount, each rounded half up to the minor unit of
+// currency.
+func (r *Rules) Line(amount money.Amount, category string, provider models.Provider, currency money.Currency) *models.LineTax {
+	rule, ok := r.Match(category, provider)
+	if !ok {
+		rule = models.TaxRule{VATRate: r.defaultVAT}
+	}
+
+	lineTax := models.LineTax{
+		Exempt:             rule.Exempt,
+		VATIncluded:        rule.VATIncluded,
+		VATRate:            rule.VATRate,
+		ISRWithholdingRate: rule.ISRWithholdingRate,
+		VATWithholdingRate: rule.VATWithholdingRate,
+	}
+	if ok {
+		id := rule.ID
+		lineTax.RuleID = &id
+	}
+	return Reapply(lineTax, amount, currency)
+}
+
+// Reapply returns the taxes of a line for amount at the rates of applied,
+// the breakdown the line was saved with, so that edits to a purchase do not
+// pick up rules changed since. When applied.VATIncluded the IVA is taken out
+// of amount instead of added to it, and withholdings apply to what is left.
+// Each amount is rounded half up to the minor unit of currency.
+func Reapply(applied models.LineTax, amount money.Amount, currency money.Currency) *models.LineTax {
+	if applied.VATIncluded {
+		applied.Base = amount.Div(money.FromInt(1).Add(applied.VATRate), currency.Digits(), money.HalfUp)
+		applied.VAT = amount.Sub(applied.Base)
+	} else {
+		applied.Base = currency.Round(amount, money.HalfUp)
+		applied.VAT = currency.Round(amount.Mul(applied.VATRate), money.HalfUp)
+	}
+	applied.ISRWithheld = currency.Round(applied.Base.Mul(applied.ISRWithholdingRate), money.HalfUp)
+	applied.VATWithheld = currency.Round(applied.Base.Mul(applied.VATWithholdingRate), money.HalfUp)
+	return &applied
+}
@@ -0,0 +1,188 @@
+package tax
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aldoramirezmartinez/fiber-api/models"
+	"github.com/aldoramirezmartinez/fiber-api/money"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func rule(name string, created int, r models.TaxRule) models.TaxRule {
+	r.ID = primitive.NewObjectIDFromTimestamp(time.Unix(int64(1700000000+created), 0))
+	r.Name = name
+	return r
+}
+
+func TestMatch(t *testing.T) {
+	company := models.Provider{ID: primitive.NewObjectID(), TaxType: models.ProviderTaxCompany}
+	individual := models.Provider{ID: primitive.NewObjectID(), TaxType: models.ProviderTaxIndividual}
+	other := models.Provider{ID: primitive.NewObjectID(), TaxType: models.ProviderTaxIndividual}
+
+	rules := &Rules{rules: []models.TaxRule{
+		rule("any", 0, models.TaxRule{}),
+		rule("food", 1, models.TaxRule{Category: "food"}),
+		rule("individuals", 2, models.TaxRule{ProviderType: models.ProviderTaxIndividual}),
+		rule("individual food", 3, models.TaxRule{ProviderType: models.ProviderTaxIndividual, Category: "food"}),
+		rule("one provider", 4, models.TaxRule{ProviderID: &individual.ID}),
+		rule("books", 5, models.TaxRule{Category: "books"}),
+		rule("books again", 6, models.TaxRule{Category: "books"}),
+	}}
+
+	tests := []struct {
+		category string
+		provider models.Provider
+		want     string
+	}{
+		{"tools", company, "any"},
+		{"food", company, "food"},
+		{"tools", other, "individuals"},
+		{"food", other, "individual food"},
+		{"food", individual, "one provider"},
+		{"books", company, "books"},
+	}
+
+	for _, tt := range tests {
+		got, ok := rules.Match(tt.category, tt.provider)
+		if !ok || got.Name != tt.want {
+			t.Errorf("Match(%q, %s) = %q, want %q", tt.category, tt.provider.TaxType, got.Name, tt.want)
+		}
+	}
+
+	if _, ok := (&Rules{}).Match("food", company); ok {
+		t.Error("Match without rules found one")
+	}
+}
+
+func TestLine(t *testing.T) {
+	provider := models.Provider{ID: primitive.NewObjectID(), TaxType: models.ProviderTaxIndividual}
+
+	tests := []struct {
+		name     string
+		rule     *models.TaxRule
+		amount   string
+		currency money.Currency
+		want     models.LineTax
+	}{
+		{
+			name:   "default rate",
+			amount: "100.00",
+			want:   models.LineTax{Base: money.MustParse("100.00"), VAT: money.MustParse("16.00")},
+		},
+		{
+			name:   "exclusive",
+			rule:   &models.TaxRule{VATRate: money.MustParse("0.08")},
+			amount: "100.00",
+			want:   models.LineTax{Base: money.MustParse("100.00"), VAT: money.MustParse("8.00")},
+		},
+		{
+			name:   "inclusive",
+			rule:   &models.TaxRule{VATIncluded: true, VATRate: money.MustParse("0.16")},
+			amount: "116.00",
+			want:   models.LineTax{VATIncluded: true, Base: money.MustParse("100.00"), VAT: money.MustParse("16.00")},
+		},
+		{
+			name:   "inclusive with rounding",
+			rule:   &models.TaxRule{VATIncluded: true, VATRate: money.MustParse("0.16")},
+			amount: "100.00",
+			want:   models.LineTax{VATIncluded: true, Base: money.MustParse("86.21"), VAT: money.MustParse("13.79")},
+		},
+		{
+			name:   "exempt",
+			rule:   &models.TaxRule{Exempt: true},
+			amount: "100.00",
+			want:   models.LineTax{Exempt: true, Base: money.MustParse("100.00"), VAT: money.Zero},
+		},
+		{
+			name:   "zero rate",
+			rule:   &models.TaxRule{VATRate: money.Zero},
+			amount: "100.00",
+			want:   models.LineTax{Base: money.MustParse("100.00"), VAT: money.Zero},
+		},
+		{
+			name:   "withholdings",
+			rule:   &models.TaxRule{VATRate: money.MustParse("0.16"), ISRWithholdingRate: money.MustParse("0.10"), VATWithholdingRate: money.MustParse("0.106667")},
+			amount: "1000.00",
+			want: models.LineTax{
+				Base:        money.MustParse("1000.00"),
+				VAT:         money.MustParse("160.00"),
+				ISRWithheld: money.MustParse("100.00"),
+				VATWithheld: money.MustParse("106.67"),
+			},
+		},
+		{
+			name:   "withholdings on an inclusive price",
+			rule:   &models.TaxRule{VATIncluded: true, VATRate: money.MustParse("0.16"), ISRWithholdingRate: money.MustParse("0.10"), VATWithholdingRate: money.MustParse("0.106667")},
+			amount: "1160.00",
+			want: models.LineTax{
+				VATIncluded: true,
+				Base:        money.MustParse("1000.00"),
+				VAT:         money.MustParse("160.00"),
+				ISRWithheld: money.MustParse("100.00"),
+				VATWithheld: money.MustParse("106.67"),
+			},
+		},
+		{
+			name:     "currency without decimals",
+			rule:     &models.TaxRule{VATRate: money.MustParse("0.16")},
+			amount:   "1001",
+			currency: "JPY",
+			want:     models.LineTax{Base: money.MustParse("1001"), VAT: money.MustParse("160")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := &Rules{defaultVAT: money.MustParse("0.16")}
+			if tt.rule != nil {
+				rules.rules = []models.TaxRule{rule(tt.name, 0, *tt.rule)}
+			}
+			currency := tt.currency
+			if currency == "" {
+				currency = "MXN"
+			}
+
+			got := rules.Line(money.MustParse(tt.amount), "tools", provider, currency)
+			if (got.RuleID != nil) != (tt.rule != nil) {
+				t.Errorf("rule ID = %v, want one: %v", got.RuleID, tt.rule != nil)
+			}
+			if got.Exempt != tt.want.Exempt || got.VATIncluded != tt.want.VATIncluded {
+				t.Errorf("exempt, included = %v, %v, want %v, %v", got.Exempt, got.VATIncluded, tt.want.Exempt, tt.want.VATIncluded)
+			}
+			for _, amount := range []struct {
+				name      string
+				got, want money.Amount
+			}{
+				{"base", got.Base, tt.want.Base},
+				{"vat", got.VAT, tt.want.VAT},
+				{"isr_withheld", got.ISRWithheld, tt.want.ISRWithheld},
+				{"vat_withheld", got.VATWithheld, tt.want.VATWithheld},
+			} {
+				if !amount.got.Equal(amount.want) {
+					t.Errorf("%s = %s, want %s", amount.name, amount.got, amount.want)
+				}
+			}
+		})
+	}
+}
+
+// TestReapply checks that a saved breakdown keeps its rates when the rules
+// change.
+func TestReapply(t *testing.T) {
+	provider := models.Provider{ID: primitive.NewObjectID()}
+	saved := (&Rules{defaultVAT: money.MustParse("0.16")}).Line(money.MustParse("100.00"), "tools", provider, "MXN")
+
+	changed := &Rules{rules: []models.TaxRule{rule("new rate", 0, models.TaxRule{VATRate: money.MustParse("0.08")})}}
+	if got := changed.Line(money.MustParse("200.00"), "tools", provider, "MXN"); got.VAT.String() != "16.00" {
+		t.Fatalf("new rule VAT = %s, want 16.00", got.VAT)
+	}
+
+	got := Reapply(*saved, money.MustParse("200.00"), "MXN")
+	if got.VAT.String() != "32.00" || got.Base.String() != "200.00" {
+		t.Fatalf("reapplied VAT, base = %s, %s, want 32.00, 200.00", got.VAT, got.Base)
+	}
+	if saved.VAT.String() != "16.00" {
+		t.Fatalf("Reapply changed the saved breakdown to %s", saved.VAT)
+	}
+}
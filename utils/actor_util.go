@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"github.com/aldoramirezmartinez/fiber-api/models"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	HeaderUserID      = "X-User-ID"
+	actorLocalKey     = "actor"
+	requestIDLocalKey = "requestid"
+	AdminRole         = "admin"
+)
+
+func SetActor(c *fiber.Ctx, user models.User) {
+	c.Locals(actorLocalKey, user)
+}
+
+// Actor returns the user making the request, if the X-User-ID header named one.
+func Actor(c *fiber.Ctx) (models.User, bool) {
+	user, ok := c.Locals(actorLocalKey).(models.User)
+	return user, ok
+}
+
+// ActorID returns the ID of the user making the request, or nil when
+// anonymous or when c is nil, as it is for background jobs.
+func ActorID(c *fiber.Ctx) *primitive.ObjectID {
+	if c == nil {
+		return nil
+	}
+	user, ok := Actor(c)
+	if !ok {
+		return nil
+	}
+	return &user.ID
+}
+
+func IsAdmin(c *fiber.Ctx) bool {
+	user, ok := Actor(c)
+	return ok && user.Role.Name == AdminRole
+}
+
+// CanSetRole reports whether the caller may change a user's role from one
+// role to another. Only admins can change roles, their own included.
+func CanSetRole(c *fiber.Ctx, from, to models.Role) bool {
+	return from == to || IsAdmin(c)
+}
+
+// RequestID returns the X-Request-ID of the request, set by the requestid
+// middleware, or "" when c is nil.
+func RequestID(c *fiber.Ctx) string {
+	if c == nil {
+		return ""
+	}
+	requestID, _ := c.Locals(requestIDLocalKey).(string)
+	return requestID
+}
@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/aldoramirezmartinez/fiber-api/models"
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+)
+
+func TestCanSetRole(t *testing.T) {
+	admin := models.Role{Name: AdminRole}
+	buyer := models.Role{Name: "buyer"}
+
+	tests := []struct {
+		name     string
+		actor    *models.User
+		from, to models.Role
+		want     bool
+	}{
+		{name: "non-admin makes itself admin", actor: &models.User{Role: buyer}, from: buyer, to: admin, want: false},
+		{name: "user without role makes itself admin", actor: &models.User{}, from: models.Role{}, to: admin, want: false},
+		{name: "anonymous sets a role", from: models.Role{}, to: buyer, want: false},
+		{name: "non-admin drops an admin role", actor: &models.User{Role: buyer}, from: admin, to: models.Role{}, want: false},
+		{name: "non-admin keeps its role", actor: &models.User{Role: buyer}, from: buyer, to: buyer, want: true},
+		{name: "anonymous without role", from: models.Role{}, to: models.Role{}, want: true},
+		{name: "admin promotes a user", actor: &models.User{Role: admin}, from: buyer, to: admin, want: true},
+		{name: "admin demotes a user", actor: &models.User{Role: admin}, from: admin, to: buyer, want: true},
+	}
+
+	app := fiber.New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := app.AcquireCtx(&fasthttp.RequestCtx{})
+			defer app.ReleaseCtx(c)
+			if tt.actor != nil {
+				SetActor(c, *tt.actor)
+			}
+			if got := CanSetRole(c, tt.from, tt.to); got != tt.want {
+				t.Fatalf("CanSetRole(%q, %q) = %v, want %v", tt.from.Name, tt.to.Name, got, tt.want)
+			}
+		})
+	}
+}
@@ -8,13 +8,26 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func CheckDocumentExists(collection *mongo.Collection, documentID primitive.ObjectID) (bool, error) {
-	ctx := context.TODO()
-
-	count, err := collection.CountDocuments(ctx, bson.M{"_id": documentID})
+func CheckDocumentExists(ctx context.Context, collection *mongo.Collection, documentID primitive.ObjectID) (bool, error) {
+	count, err := collection.CountDocuments(ctx, NotDeleted(bson.M{"_id": documentID}))
 	if err != nil {
 		return false, err
 	}
 
 	return count > 0, nil
 }
+
+// LockDocument reports whether the document exists and is not deleted, and
+// writes to it so that a concurrent transaction deleting it conflicts with
+// the caller's transaction instead of both committing. Use it inside
+// UnitOfWork.Do in place of CheckDocumentExists.
+func LockDocument(ctx context.Context, collection *mongo.Collection, documentID primitive.ObjectID) (bool, error) {
+	result, err := collection.UpdateOne(ctx, NotDeleted(bson.M{"_id": documentID}), bson.M{
+		"$set": bson.M{"lock": primitive.NewObjectID()},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return result.MatchedCount > 0, nil
+}
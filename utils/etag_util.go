@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrVersionConflict reports that a document changed between being read and
+// being written.
+var ErrVersionConflict = errors.New("document has been modified")
+
+func ETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+func SetETag(c *fiber.Ctx, version int64) {
+	c.Set(fiber.HeaderETag, ETag(version))
+}
+
+// IfMatch reports whether the If-Match header allows modifying a document at
+// the given version. A missing header always matches. If-Match uses strong
+// comparison, so weak ETags never match.
+func IfMatch(c *fiber.Ctx, version int64) bool {
+	header := c.Get(fiber.HeaderIfMatch)
+	if header == "" {
+		return true
+	}
+	return etagListContains(header, version, false)
+}
+
+// IfNoneMatch reports whether the client already holds the given version, in
+// which case the handler should answer 304 Not Modified.
+func IfNoneMatch(c *fiber.Ctx, version int64) bool {
+	header := c.Get(fiber.HeaderIfNoneMatch)
+	if header == "" {
+		return false
+	}
+	return etagListContains(header, version, true)
+}
+
+// etagListContains reports whether header lists the ETag of version or is
+// "*". weak allows W/ ETags to match, as weak comparison does.
+func etagListContains(header string, version int64, weak bool) bool {
+	etag := ETag(version)
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// VersionFilter matches a document by ID only while it is still at the given
+// version. Documents written before versioning have no version field and are
+// treated as version 0.
+func VersionFilter(documentID primitive.ObjectID, version int64) bson.M {
+	if version == 0 {
+		return bson.M{
+			"_id":     documentID,
+			"version": bson.M{"$in": bson.A{0, nil}},
+		}
+	}
+	return bson.M{
+		"_id":     documentID,
+		"version": version,
+	}
+}
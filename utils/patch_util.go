@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"mime"
+	"reflect"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	MIMEMergePatch = "application/merge-patch+json"
+	MIMEJSONPatch  = "application/json-patch+json"
+)
+
+var ErrUnsupportedPatch = errors.New("unsupported patch content type")
+
+// ApplyPatch applies the request body to the JSON form of current and decodes
+// the result into target. The body is a JSON Merge Patch (RFC 7396) or a JSON
+// Patch (RFC 6902) depending on its content type; plain application/json is
+// treated as a merge patch.
+func ApplyPatch(c *fiber.Ctx, current interface{}, target interface{}) error {
+	mediaType, _, err := mime.ParseMediaType(c.Get(fiber.HeaderContentType))
+	if err != nil {
+		return ErrUnsupportedPatch
+	}
+
+	document, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+
+	var patched []byte
+	switch mediaType {
+	case MIMEMergePatch, fiber.MIMEApplicationJSON:
+		patched, err = jsonpatch.MergePatch(document, c.Body())
+	case MIMEJSONPatch:
+		var patch jsonpatch.Patch
+		patch, err = jsonpatch.DecodePatch(c.Body())
+		if err == nil {
+			patched, err = patch.Apply(document)
+		}
+	default:
+		return ErrUnsupportedPatch
+	}
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(patched, target)
+}
+
+// PatchUpdate compares the BSON form of two versions of a document and builds
+// an update that only $sets the changed fields and $unsets the removed ones.
+func PatchUpdate(before interface{}, after interface{}) (bson.M, error) {
+	beforeDoc, err := toBSONMap(before)
+	if err != nil {
+		return nil, err
+	}
+	afterDoc, err := toBSONMap(after)
+	if err != nil {
+		return nil, err
+	}
+
+	set := bson.M{}
+	unset := bson.M{}
+	for key, value := range afterDoc {
+		if key == "_id" {
+			continue
+		}
+		if previous, ok := beforeDoc[key]; !ok || !reflect.DeepEqual(previous, value) {
+			set[key] = value
+		}
+	}
+	for key := range beforeDoc {
+		if _, ok := afterDoc[key]; !ok && key != "_id" {
+			unset[key] = ""
+		}
+	}
+
+	update := bson.M{}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	return update, nil
+}
+
+func toBSONMap(document interface{}) (bson.M, error) {
+	data, err := bson.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+
+	var m bson.M
+	if err := bson.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/aldoramirezmartinez/fiber-api/models"
+	"github.com/aldoramirezmartinez/fiber-api/money"
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TestPatchUpdateZeroPrice checks that patching a price to zero sets it to
+// zero instead of dropping the field.
+func TestPatchUpdateZeroPrice(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{"merge patch", MIMEMergePatch, `{"price":"0"}`},
+		{"merge patch with a number", fiber.MIMEApplicationJSON, `{"price":0}`},
+		{"JSON patch", MIMEJSONPatch, `[{"op":"replace","path":"/price","value":"0.00"}]`},
+	}
+
+	existing := models.Item{
+		ID:    primitive.NewObjectID(),
+		Name:  "Tornillo",
+		Code:  "T-1",
+		Price: money.MustParse("12.50"),
+	}
+
+	app := fiber.New()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := app.AcquireCtx(&fasthttp.RequestCtx{})
+			defer app.ReleaseCtx(c)
+			c.Request().Header.SetContentType(tt.contentType)
+			c.Request().SetBodyString(tt.body)
+
+			var patched models.Item
+			if err := ApplyPatch(c, existing, &patched); err != nil {
+				t.Fatal(err)
+			}
+			if !patched.Price.IsZero() {
+				t.Fatalf("patched price = %s, want 0", patched.Price)
+			}
+
+			update, err := PatchUpdate(existing, patched)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if unset, ok := update["$unset"].(bson.M); ok {
+				if _, ok := unset["price"]; ok {
+					t.Fatalf("update unsets the price: %v", update)
+				}
+			}
+			set, _ := update["$set"].(bson.M)
+			price, ok := set["price"].(primitive.Decimal128)
+			if !ok {
+				t.Fatalf("update does not set the price to a Decimal128: %v", update)
+			}
+			if price.String() != "0" && price.String() != "0.00" {
+				t.Fatalf("update sets the price to %s, want 0", price)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SoftDeleteCollections lists the collections whose deletes only set
+// deleted_at, in the order the purge job removes them.
+var SoftDeleteCollections = []string{"purchase_details", "purchase_revisions", "purchases", "items", "providers", "users"}
+
+// SoftDeletes reports whether collection is one of SoftDeleteCollections.
+func SoftDeletes(collection string) bool {
+	for _, name := range SoftDeleteCollections {
+		if name == collection {
+			return true
+		}
+	}
+	return false
+}
+
+func IncludeDeleted(c *fiber.Ctx) bool {
+	return c.QueryBool("include_deleted")
+}
+
+// NotDeleted restricts filter to documents that have not been soft deleted.
+func NotDeleted(filter bson.M) bson.M {
+	filter["deleted_at"] = nil
+	return filter
+}
+
+// OnlyDeleted restricts filter to soft-deleted documents.
+func OnlyDeleted(filter bson.M) bson.M {
+	filter["deleted_at"] = bson.M{"$ne": nil}
+	return filter
+}
+
+// ScopeDeleted excludes soft-deleted documents unless the request asked for
+// ?include_deleted=true.
+func ScopeDeleted(c *fiber.Ctx, filter bson.M) bson.M {
+	if IncludeDeleted(c) {
+		return filter
+	}
+	return NotDeleted(filter)
+}
+
+// SoftDeleteUpdate marks a document as deleted by actor and bumps its version.
+func SoftDeleteUpdate(actor *primitive.ObjectID) bson.M {
+	set := bson.M{"deleted_at": time.Now()}
+	if actor != nil {
+		set["deleted_by"] = *actor
+	}
+	return bson.M{
+		"$set": set,
+		"$inc": bson.M{"version": 1},
+	}
+}
+
+// RestoreUpdate clears the soft delete markers and bumps the version.
+func RestoreUpdate() bson.M {
+	return bson.M{
+		"$unset": bson.M{"deleted_at": "", "deleted_by": ""},
+		"$inc":   bson.M{"version": 1},
+	}
+}
@@ -0,0 +1,134 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aldoramirezmartinez/fiber-api/config"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	maxTransactionAttempts = 5
+	transactionRetryDelay  = 20 * time.Millisecond
+)
+
+// ErrRetryTransaction can be returned by the function passed to
+// UnitOfWork.Do to abort the transaction and run it again from a fresh
+// snapshot, as if it had hit a transient error.
+var ErrRetryTransaction = errors.New("transaction must be retried")
+
+// UnitOfWork runs a group of reads and writes as one Mongo transaction.
+// Standalone servers do not support transactions, so there, or when
+// MONGO_TRANSACTIONS=off, the work runs without one.
+type UnitOfWork struct {
+	db *mongo.Database
+
+	mu            sync.Mutex
+	checked       bool
+	transactional bool
+}
+
+func NewUnitOfWork(db *mongo.Database) *UnitOfWork {
+	return &UnitOfWork{
+		db: db,
+	}
+}
+
+// Do runs fn in a transaction and commits it if fn returns nil. fn is run
+// again when the transaction fails with a transient error such as a write
+// conflict, so it must only touch the database through the context it is
+// given and must not write the response; return an error and handle it after
+// Do instead. Errors returned by fn are passed through unchanged.
+func (u *UnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if !u.Transactional(ctx) {
+		return fn(ctx)
+	}
+
+	session, err := u.db.Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(context.Background())
+
+	for attempt := 1; ; attempt++ {
+		err = mongo.WithSession(ctx, session, func(sc mongo.SessionContext) error {
+			if err := sc.StartTransaction(); err != nil {
+				return err
+			}
+			if err := fn(sc); err != nil {
+				// Abort with a fresh context so a cancelled request still releases its locks.
+				sc.AbortTransaction(context.Background())
+				return err
+			}
+			return commitTransaction(sc)
+		})
+		retry := HasErrorLabel(err, "TransientTransactionError") || errors.Is(err, ErrRetryTransaction)
+		if err == nil || !retry || attempt == maxTransactionAttempts {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt) * transactionRetryDelay):
+		}
+	}
+}
+
+// commitTransaction retries commits whose outcome the server could not report.
+func commitTransaction(sc mongo.SessionContext) error {
+	for attempt := 1; ; attempt++ {
+		err := sc.CommitTransaction(sc)
+		if err == nil || !HasErrorLabel(err, "UnknownTransactionCommitResult") || attempt == maxTransactionAttempts {
+			return err
+		}
+	}
+}
+
+// Transactional reports whether Do uses transactions. The server topology is
+// looked up on first use; failed lookups are retried on the next call.
+func (u *UnitOfWork) Transactional(ctx context.Context) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.checked {
+		return u.transactional
+	}
+
+	switch config.GetTransactionMode() {
+	case "off":
+		u.checked = true
+		return false
+	case "on":
+		u.checked = true
+		u.transactional = true
+		return true
+	}
+
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	err := u.db.RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello)
+	if err != nil {
+		fmt.Println("Failed to detect MongoDB topology:", err)
+		return false
+	}
+
+	u.checked = true
+	u.transactional = hello.SetName != "" || hello.Msg == "isdbgrid"
+	if !u.transactional {
+		fmt.Println("MongoDB is standalone, running without transactions")
+	}
+	return u.transactional
+}
+
+func HasErrorLabel(err error, label string) bool {
+	var labeled mongo.LabeledError
+	return errors.As(err, &labeled) && labeled.HasErrorLabel(label)
+}
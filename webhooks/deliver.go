@@ -0,0 +1,172 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aldoramirezmartinez/fiber-api/models"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+
+	maxDeliveryAttempts = 8
+	baseRetryDelay      = 30 * time.Second
+	// deliveryLease keeps other dispatchers off a delivery while it is sent.
+	deliveryLease = 2 * time.Minute
+)
+
+type Dispatcher struct {
+	db     *mongo.Database
+	client *http.Client
+}
+
+func NewDispatcher(db *mongo.Database, timeout time.Duration) *Dispatcher {
+	return &Dispatcher{
+		db:     db,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// DeliverDue sends every pending delivery whose next attempt is due and
+// returns how many were attempted. Failed attempts are retried with
+// exponential backoff until maxDeliveryAttempts, after which the delivery is
+// marked failed and only a manual redelivery sends it again.
+func (d *Dispatcher) DeliverDue(ctx context.Context) (int, error) {
+	attempted := 0
+	for {
+		delivery, err := d.claim(ctx)
+		if err == mongo.ErrNoDocuments {
+			return attempted, nil
+		}
+		if err != nil {
+			return attempted, err
+		}
+
+		if err := d.attempt(ctx, delivery); err != nil {
+			return attempted, err
+		}
+		attempted++
+	}
+}
+
+func (d *Dispatcher) claim(ctx context.Context) (models.WebhookDelivery, error) {
+	now := time.Now()
+	filter := bson.M{
+		"status":          models.DeliveryPending,
+		"next_attempt_at": bson.M{"$lte": now},
+		"$or": bson.A{
+			bson.M{"locked_until": nil},
+			bson.M{"locked_until": bson.M{"$lte": now}},
+		},
+	}
+	update := bson.M{"$set": bson.M{"locked_until": now.Add(deliveryLease)}}
+	findOptions := options.FindOneAndUpdate().
+		SetSort(bson.M{"next_attempt_at": 1}).
+		SetReturnDocument(options.After)
+
+	var delivery models.WebhookDelivery
+	err := d.db.Collection(DeliveryCollectionName).FindOneAndUpdate(ctx, filter, update, findOptions).Decode(&delivery)
+	return delivery, err
+}
+
+func (d *Dispatcher) attempt(ctx context.Context, delivery models.WebhookDelivery) error {
+	started := time.Now()
+	attempt := models.DeliveryAttempt{At: started}
+
+	var subscription models.WebhookSubscription
+	err := d.db.Collection(SubscriptionCollectionName).FindOne(ctx, bson.M{"_id": delivery.SubscriptionID}).Decode(&subscription)
+	switch {
+	case err == mongo.ErrNoDocuments:
+		attempt.Error = "subscription no longer exists"
+	case err != nil:
+		return err
+	default:
+		attempt.StatusCode, err = d.send(ctx, subscription, delivery)
+		if err != nil {
+			attempt.Error = err.Error()
+		} else if attempt.StatusCode < 200 || attempt.StatusCode >= 300 {
+			attempt.Error = http.StatusText(attempt.StatusCode)
+		}
+	}
+	attempt.DurationMS = time.Since(started).Milliseconds()
+
+	attempts := delivery.Attempts + 1
+	set := bson.M{"attempts": attempts}
+	switch {
+	case attempt.Error == "":
+		set["status"] = models.DeliverySucceeded
+	case subscription.ID.IsZero() || attempts >= maxDeliveryAttempts:
+		set["status"] = models.DeliveryFailed
+	default:
+		set["next_attempt_at"] = time.Now().Add(retryDelay(attempts))
+	}
+
+	_, err = d.db.Collection(DeliveryCollectionName).UpdateOne(ctx, bson.M{"_id": delivery.ID}, bson.M{
+		"$set":   set,
+		"$push":  bson.M{"log": attempt},
+		"$unset": bson.M{"locked_until": ""},
+	})
+	return err
+}
+
+func (d *Dispatcher) send(ctx context.Context, subscription models.WebhookSubscription, delivery models.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	request.Header.Set(HeaderEvent, delivery.Event)
+	request.Header.Set(HeaderDelivery, delivery.ID.Hex())
+	request.Header.Set(HeaderTimestamp, timestamp)
+	request.Header.Set(HeaderSignature, "sha256="+Sign(subscription.Secret, timestamp, body))
+
+	response, err := d.client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	return response.StatusCode, nil
+}
+
+// retryDelay doubles from baseRetryDelay after every failed attempt.
+func retryDelay(attempts int) time.Duration {
+	return baseRetryDelay << (attempts - 1)
+}
+
+// Sign returns the hex HMAC-SHA256 of "<timestamp>.<body>" keyed by secret,
+// sent as "sha256=<signature>" in the X-Webhook-Signature header.
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%s.", timestamp)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature checks an X-Webhook-Signature header value in constant time.
+func VerifySignature(secret string, timestamp string, body []byte, header string) bool {
+	signature, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(Sign(secret, timestamp, body)))
+}
@@ -0,0 +1,122 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/aldoramirezmartinez/fiber-api/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	SubscriptionCollectionName = "webhook_subscriptions"
+	DeliveryCollectionName     = "webhook_deliveries"
+)
+
+// Publish queues event eventID for every active subscription to eventType.
+// It is called by the outbox relay, which may publish an event more than
+// once: deliveries already queued for eventID are left as they are.
+func Publish(ctx context.Context, db *mongo.Database, eventID primitive.ObjectID, eventType string, data interface{}) error {
+	cursor, err := db.Collection(SubscriptionCollectionName).Find(ctx, bson.M{"active": true, "events": eventType})
+	if err != nil {
+		return err
+	}
+
+	var subscriptions []models.WebhookSubscription
+	if err := cursor.All(ctx, &subscriptions); err != nil {
+		return err
+	}
+	if len(subscriptions) == 0 {
+		return nil
+	}
+
+	event := newEvent(eventType, data)
+	event.ID = eventID
+	_, err = enqueue(ctx, db, event, subscriptions)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	return err
+}
+
+// EnsureIndexes creates the unique index that keeps an event from being
+// queued twice for the same subscription.
+func EnsureIndexes(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection(DeliveryCollectionName).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "event_id", Value: 1}, {Key: "subscription_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// Ping queues a ping event for subscription whatever events it asked for.
+func Ping(ctx context.Context, db *mongo.Database, subscription models.WebhookSubscription) (models.WebhookDelivery, error) {
+	deliveries, err := enqueue(ctx, db, newEvent(models.EventPing, map[string]interface{}{"subscription_id": subscription.ID}), []models.WebhookSubscription{subscription})
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	return deliveries[0], nil
+}
+
+// Redeliver puts a delivery back in the queue to be sent right away with a
+// fresh set of attempts. It returns mongo.ErrNoDocuments for unknown IDs.
+func Redeliver(ctx context.Context, db *mongo.Database, deliveryID primitive.ObjectID) error {
+	result, err := db.Collection(DeliveryCollectionName).UpdateOne(ctx, bson.M{"_id": deliveryID}, bson.M{
+		"$set": bson.M{
+			"status":          models.DeliveryPending,
+			"attempts":        0,
+			"next_attempt_at": time.Now(),
+		},
+		"$unset": bson.M{"locked_until": ""},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func newEvent(eventType string, data interface{}) models.WebhookEvent {
+	return models.WebhookEvent{
+		ID:        primitive.NewObjectID(),
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	}
+}
+
+func enqueue(ctx context.Context, db *mongo.Database, event models.WebhookEvent, subscriptions []models.WebhookSubscription) ([]models.WebhookDelivery, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	deliveries := make([]models.WebhookDelivery, len(subscriptions))
+	documents := make([]interface{}, len(subscriptions))
+	for i, subscription := range subscriptions {
+		deliveries[i] = models.WebhookDelivery{
+			ID:             primitive.NewObjectID(),
+			SubscriptionID: subscription.ID,
+			EventID:        event.ID,
+			Event:          event.Type,
+			Payload:        string(payload),
+			Status:         models.DeliveryPending,
+			NextAttemptAt:  now,
+			CreatedAt:      now,
+			Log:            []models.DeliveryAttempt{},
+		}
+		documents[i] = deliveries[i]
+	}
+
+	// Unordered so a subscription that already has the event does not stop
+	// the others from getting it.
+	_, err = db.Collection(DeliveryCollectionName).InsertMany(ctx, documents, options.InsertMany().SetOrdered(false))
+	return deliveries, err
+}